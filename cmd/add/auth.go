@@ -28,9 +28,10 @@ import (
 // authCmd represents the auth command
 var (
 	authCmd = &cobra.Command{
-		Use:   "auth",
-		Short: "Add authentication to a resource or function group",
-		Args:  cobra.ExactArgs(1),
+		Use:         "auth",
+		Short:       "Add authentication to a resource or function group",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			rName := args[0]
 			mc := models.ReadMUGConfig()
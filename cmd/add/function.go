@@ -21,6 +21,7 @@
 package add
 
 import (
+	"bytes"
 	"log"
 	"os"
 	"path/filepath"
@@ -36,9 +37,10 @@ import (
 // functionCmd represents the function command
 var (
 	functionCmd = &cobra.Command{
-		Use:   "function functionName",
-		Short: "Adds a function to a resource",
-		Args:  cobra.ExactArgs(1),
+		Use:         "function functionName",
+		Short:       "Adds a function to a resource",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			fName := args[0]
 
@@ -83,7 +85,7 @@ func renderFunction(config models.MUGConfig, rName, fName string) {
 	// create the function folder
 	folder := filepath.Join(config.ProjectPath, "functions", rName)
 	funcFolder := filepath.Join(folder, fName)
-	os.MkdirAll(funcFolder, 0755)
+	models.MkdirAll(funcFolder, 0755)
 
 	// determine function templates and file names for resource or function group function
 	funcNames := map[string]string{
@@ -103,36 +105,30 @@ func renderFunction(config models.MUGConfig, rName, fName string) {
 		"Config":       config,
 	}
 	for tmpl, fn := range funcNames {
-		// create file
-		f, err := os.Create(filepath.Join(funcFolder, fn))
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-
 		t := models.LoadTemplateFromBox(models.FunctionBox, tmpl)
 
 		// execute template and save to file
-		err = t.Execute(f, data)
-		if err != nil {
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			log.Fatal(err)
+		}
+		if err := models.WriteFile(filepath.Join(funcFolder, fn), buf.Bytes(), 0644); err != nil {
 			log.Fatal(err)
 		}
 	}
 
 	if resourceFunc {
 		// also add function to resource file
-		f, err := os.OpenFile(filepath.Join(folder, rName+".go"), os.O_APPEND|os.O_WRONLY, 0600)
-		if err != nil {
-			log.Fatal(err)
-		}
-		defer f.Close()
-
 		data := map[string]interface{}{
 			"Function": fIdent,
 		}
 		t := models.LoadTemplateFromBox(models.FunctionBox, "resourceFunction.tmpl")
-		err = t.Execute(f, data)
-		if err != nil {
+
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			log.Fatal(err)
+		}
+		if err := models.AppendFile(filepath.Join(folder, rName+".go"), buf.Bytes()); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -33,9 +33,10 @@ import (
 // functionGroupCmd represents the functionGroup command
 var (
 	functionGroupCmd = &cobra.Command{
-		Use:   "functionGroup name [flags]",
-		Short: "Adds a new function group, you can then add functions to with 'mug add function -r name [flags]'",
-		Args:  cobra.ExactArgs(1),
+		Use:         "functionGroup name [flags]",
+		Short:       "Adds a new function group, you can then add functions to with 'mug add function -r name [flags]'",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			// instantiate new functionGroup
 			groupName := args[0]
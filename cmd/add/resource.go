@@ -33,9 +33,10 @@ import (
 // resourceCmd represents the resource command
 var (
 	resourceCmd = &cobra.Command{
-		Use:   "resource name [flags]",
-		Short: "Adds CRUDL functions for the defined resource",
-		Args:  cobra.ExactArgs(1),
+		Use:         "resource name [flags]",
+		Short:       "Adds CRUDL functions for the defined resource",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			// instantiate new resource model and parse given attributes
 			modelName := args[0]
@@ -65,12 +66,15 @@ var (
 			}
 
 			// render templates with data
-			renderTemplates(mc, m)
+			m.RenderTemplates(mc)
 
 			// write modelName.json, mug.config.json and serverless.yml for resource
 			m.Write(mc.ProjectPath)
 			mc.Write()
 			sc.Write(mc.ProjectPath, modelName)
+
+			// write the API Gateway events used by the generated tests
+			mc.WriteEvents(sc, modelName, "apigw")
 		},
 	}
 
@@ -90,59 +94,3 @@ func init() {
 	resourceCmd.Flags().Int64VarP(&readUnits, "readUnits", "r", 1, "Set the ReadCapacityUnits if billingMode is set to ProvisionedThroughput")
 	resourceCmd.Flags().Int64VarP(&writeUnits, "writeUnits", "w", 1, "Set the WriteCapacityUnits if billingMode is set to ProvisionedThroughput")
 }
-
-func renderTemplates(config models.MUGConfig, m models.Model) {
-	temps := []string{
-		"create",
-		"read",
-		"update",
-		"delete",
-		"list",
-		"model",
-		"modelMocks",
-	}
-
-	data := map[string]interface{}{
-		"Model":  m,
-		"Config": config,
-	}
-
-	mName := m.Ident.Camelize().String()
-
-	// iterate over resource templates and execute
-	for _, t := range temps {
-		// create the function folder for function templete (except model)
-		folder := filepath.Join(config.ProjectPath, "functions", mName)
-		if t == "model" {
-			os.MkdirAll(folder, 0755)
-			createFile(mName+".go", "model.tmpl", folder, data)
-		} else if t == "modelMocks" {
-			mockString := mName + "Mocks"
-			folder = filepath.Join(config.ProjectPath, "mocks", mockString)
-			os.MkdirAll(folder, 0755)
-			createFile(mockString+".go", "modelMocks.tmpl", folder, data)
-		} else {
-			folder = filepath.Join(folder, t)
-			os.MkdirAll(folder, 0755)
-			for _, tf := range []string{"main", "main_test"} {
-				createFile(tf+".go", filepath.Join(t, tf+".tmpl"), folder, data)
-			}
-		}
-	}
-}
-
-func createFile(fName, tPath, folder string, data map[string]interface{}) {
-	f, err := os.Create(filepath.Join(folder, fName))
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer f.Close()
-
-	// load template
-	tmpl := models.LoadTemplateFromBox(models.ResourceBox, tPath)
-
-	err = tmpl.Execute(f, data)
-	if err != nil {
-		log.Fatal(err)
-	}
-}
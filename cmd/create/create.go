@@ -21,11 +21,10 @@
 package create
 
 import (
-	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
 
 	"github.com/crolly/mug/cmd/models"
@@ -37,18 +36,24 @@ var (
 	CreateCmd = &cobra.Command{
 		Use:   "create",
 		Short: "Creates the boilerplate for your AWS Lambda for golang project.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Creates the boilerplate for your AWS Lambda for golang project.
+The project is created in the current directory and initialized as go module. The project name
+is used as module path (e.g. github.com/crolly/mug-example), unless --module is given.
+
+With --kit the project is created from a starter kit in a local directory or git repository
+(append #ref to select a branch or tag). The kit's files/ directory is copied into the project,
+files ending with .tmpl are rendered with the project config (.Config) and the kit variables (.Vars).
+Variables and predefined resources are described in the kit.yml of the kit. Variables are passed
+with --set key=value or prompted for.`,
+		Args: cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			createProjectStructure(args[0], force)
 		},
 	}
 
-	region string
-	force  bool
-
-	gopkg = `[[constraint]]
-	name = "github.com/aws/aws-lambda-go"
-	version = "^1.0.1"`
+	region, kit, module, runtime, arch string
+	force                              bool
+	set                                []string
 )
 
 func init() {
@@ -58,82 +63,83 @@ func init() {
 	})
 	CreateCmd.Flags().StringVarP(&region, "region", "r", "eu-central-1", "Region the project will be deployed to (e.g. us-east-1 or eu-central-1)")
 	CreateCmd.Flags().BoolVarP(&force, "force", "f", false, "Force overwrite of the directory in case it exists already")
+	CreateCmd.Flags().StringVar(&runtime, "runtime", models.ProvidedAL2023Runtime, "Lambda runtime of the functions (provided.al2023, provided.al2 or the deprecated go1.x)")
+	CreateCmd.Flags().StringVar(&arch, "arch", models.X86Architecture, "Lambda architecture of the functions (x86_64 or arm64)")
+	CreateCmd.Flags().StringVarP(&module, "module", "m", "", "Module path of the project (defaults to the project name e.g. github.com/crolly/mug-example)")
+	CreateCmd.Flags().StringVarP(&kit, "kit", "k", "", "Starter kit the project is created from (local directory or git repository)")
+	CreateCmd.Flags().StringArrayVar(&set, "set", nil, "Set a variable of the starter kit (key=value)")
 }
 
-// createsProjectStructure creates the project structure with serverless.yml and mug.config.json
+// createsProjectStructure creates the project structure with go.mod and mug.config.json
 func createProjectStructure(projectName string, force bool) {
 	// create new config from project name
 	config := newConfig(projectName)
 
 	if force {
-		os.RemoveAll(config.ProjectPath)
+		models.RemoveAll(config.ProjectPath)
 	} else if _, err := os.Stat(config.ProjectPath); !os.IsNotExist(err) {
 		// projectPath exists already
 		log.Fatal("folder already exists")
 	}
-	os.MkdirAll(config.ProjectPath, 0755)
+	models.MkdirAll(config.ProjectPath, 0755)
 
-	// write Gopkg.toml
-	if err := ioutil.WriteFile(filepath.Join(config.ProjectPath, "Gopkg.toml"), []byte(gopkg), 0644); err != nil {
+	// write go.mod
+	if err := models.WriteFile(filepath.Join(config.ProjectPath, "go.mod"), models.GoMod(config.ImportPath, nil), 0644); err != nil {
 		log.Fatal(err)
 	}
 
+	// apply starter kit
+	if len(kit) > 0 {
+		k := models.LoadKit(kit)
+		defer k.Close()
+		config = k.Apply(config, k.ResolveVariables(parseVariables(set), os.Stdin))
+	}
+
 	// persist config
 	config.Write()
 }
 
+// parseVariables parses the key=value pairs of the starter kit variables
+func parseVariables(set []string) map[string]string {
+	vars := map[string]string{}
+	for _, s := range set {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 || len(kv[0]) == 0 {
+			log.Fatalf("Invalid variable %s, use --set key=value", s)
+		}
+		vars[kv[0]] = kv[1]
+	}
+
+	return vars
+}
+
 func newConfig(projectName string) models.MUGConfig {
 	pName, pPath, iPath := getPaths(projectName)
 
+	if err := models.ValidateRuntime(runtime, arch); err != nil {
+		log.Fatal(err)
+	}
+
 	config := models.MUGConfig{
-		ProjectName: pName,
-		ProjectPath: pPath,
-		ImportPath:  iPath,
-		Region:      region,
+		ProjectName:  pName,
+		ProjectPath:  pPath,
+		ImportPath:   iPath,
+		Region:       region,
+		Runtime:      runtime,
+		Architecture: arch,
 	}
 
 	return config
 }
 
 func getPaths(projectName string) (string, string, string) {
-	projectPath, importPath := "", ""
-
-	// environments GOPATH
-	goPath := os.Getenv("GOPATH")
-	if len(goPath) == 0 {
-		log.Fatal("$GOPATH is not set")
-	}
-	srcPath := filepath.Join(goPath, "src")
-
-	if strings.Contains(projectName, "/") {
-		// project is created with full path to GOPATH src e.g. github.com/crolly/mug-example
-		projectPath = filepath.Join(srcPath, projectName)
-		importPath = projectName
-
-		i := strings.LastIndex(projectName, "/")
-		projectName = projectName[i+1 : len(projectName)]
-	} else {
-		// project is created with project name only
-		wd := models.GetWorkingDir()
-		if filepathHasPrefix(wd, srcPath) {
-			projectPath = filepath.Join(wd, projectName)
-			importPath = strings.TrimPrefix(strings.Replace(projectPath, srcPath, "", 1), "/")
-		} else {
-			log.Fatal("You must either create the project inside of $GOPATH or provide the full path (e.g. github.com/crolly/mug-example")
-		}
+	// the project name may be given as module path e.g. github.com/crolly/mug-example
+	importPath := projectName
+	if len(module) > 0 {
+		importPath = module
 	}
+	projectName = path.Base(projectName)
+	projectPath := filepath.Join(models.GetWorkingDir(), projectName)
 
 	return projectName, projectPath, importPath
 }
-
-func filepathHasPrefix(path string, prefix string) bool {
-	if len(path) <= len(prefix) {
-		return false
-	}
-	if runtime.GOOS == "windows" {
-		// Paths in windows are case-insensitive.
-		return strings.EqualFold(path[0:len(prefix)], prefix)
-	}
-	return path[0:len(prefix)] == prefix
-
-}
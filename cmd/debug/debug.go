@@ -22,6 +22,8 @@ package debug
 
 import (
 	"log"
+	"strings"
+	"time"
 
 	"github.com/crolly/mug/cmd/models"
 
@@ -31,9 +33,10 @@ import (
 var (
 	// DebugCmd represents the debug command
 	DebugCmd = &cobra.Command{
-		Use:   "debug",
-		Short: "Start Local API for debugging",
-		Long:  `This command generates a template.yml for aws-sam-cli and starts a local api to test or debug against`,
+		Use:         "debug",
+		Short:       "Start Local API for debugging",
+		Long:        `This command generates a template.yml for aws-sam-cli and starts a local api to test or debug against`,
+		Annotations: map[string]string{models.LockAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			// get the config
 			mc := models.ReadMUGConfig()
@@ -41,49 +44,90 @@ var (
 			list := models.GetList(mc.ProjectPath, debugList)
 
 			// create lambda-local network if it doesn't exist already
-			models.CreateLambdaNetwork()
+			mc.CreateLambdaNetwork()
 			// start dynamodb-local
-			models.StartLocalDynamoDB()
+			mc.StartLocalDynamoDB()
 			// create tables for resources
 			mc.CreateResourceTables(list, "debug", force)
 
 			// render template.yml
-			t := models.NewTemplate()
-			for _, r := range list {
-				sc := mc.ReadServerlessConfig(r)
-				t.AddFunctionsFromServerlessConfig(sc, r)
-			}
-			t.Write(mc.ProjectPath)
+			mc.WriteLocalTemplate(list, stage, "debug")
 
 			// make debug binaries overwriting previous
-			mc.MakeDebug(list)
+			mc.BuildDebug(list)
+
+			if watch && !models.DryRun {
+				watchLocalAPI(mc)
+				return
+			}
+
+			// sam reads the template.yml from disk, release the project lock while the local API is running
+			models.Commit()
+			models.Unlock()
 
 			// start aws-sam-cli local api
-			startLocalAPI()
+			models.RunCmd("sam", localAPIArgs(mc)...)
 		},
 	}
 
-	remoteDebugger, force        bool
+	remoteDebugger, force, watch bool
 	debugPort, gwPort, debugList string
+	stage                        string
 )
 
 func init() {
 	DebugCmd.Flags().BoolVarP(&remoteDebugger, "remoteDebugger", "r", false, "indicates whether you want to run a remote debugger (e.g. step through your code with VSCode)")
-	DebugCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "force overwrite existing tables (might be necessary if you changed you table definition - e.g. new index)")
+	DebugCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "recreate existing tables empty instead of migrating them to the changed table definition")
 	DebugCmd.Flags().StringVarP(&debugPort, "debugPort", "d", "5986", "defines the remote port if remoteDebugger is true")
 	DebugCmd.Flags().StringVarP(&gwPort, "gwPort", "g", "3000", "defines the port of local API Gateway")
 	DebugCmd.Flags().StringVarP(&debugList, "list", "l", "all", "comma separated list of resources/ function groups to debug")
+	DebugCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the serverless variables and secrets are resolved for")
+	DebugCmd.Flags().BoolVarP(&watch, "watch", "w", false, "rebuild changed functions and reload the local API while it keeps running")
 }
 
-func startLocalAPI() {
-	args := []string{"local", "start-api", "-p", gwPort, "--docker-network", "lambda-local"}
+// localAPIArgs returns the arguments of sam starting the local API in the network of the local DynamoDB
+func localAPIArgs(mc models.MUGConfig) []string {
+	args := []string{"local", "start-api", "-p", gwPort, "--docker-network", mc.DynamoDB().Network}
 	if remoteDebugger {
 		ensureDebugger()
 		args = append(args, "--debugger-path", "./dlv", "-d", debugPort, "--debug-args", "-delveAPI=2")
 		log.Printf("Starting local API at port %s with debugger at %s...\n", gwPort, debugPort)
 	}
 
-	models.RunCmd("sam", args...)
+	return args
+}
+
+// watchLocalAPI runs the local API and rebuilds the functions affected by changes of the project.
+// The template.yml is regenerated and the local API restarted if a serverless.yml changes.
+func watchLocalAPI(mc models.MUGConfig) {
+	// release the project lock, so functions can be added while watching
+	models.Commit()
+	models.Unlock()
+
+	api := models.StartCmd("sam", localAPIArgs(mc)...)
+	log.Println("Watching for changes, stop with Ctrl+C")
+
+	models.NewWatcher(mc.ProjectPath, 500*time.Millisecond).Watch(func(changed []string) {
+		log.Printf("Changed %s", strings.Join(changed, ", "))
+
+		// resources/ function groups may have been added or removed
+		mc := models.ReadMUGConfig()
+		list := models.GetList(mc.ProjectPath, debugList)
+
+		if models.ConfigChanged(changed) {
+			mc.WriteLocalTemplate(list, stage, "debug")
+			models.Commit()
+			log.Println("Restarting local API with the regenerated template.yml")
+			models.StopCmd(api)
+			api = models.StartCmd("sam", localAPIArgs(mc)...)
+		}
+
+		if err := mc.RebuildDebug(list); err != nil {
+			log.Printf("%s, waiting for changes", err)
+		}
+	})
+
+	models.StopCmd(api)
 }
 
 func ensureDebugger() {
@@ -21,8 +21,6 @@
 package deploy
 
 import (
-	"path/filepath"
-
 	"github.com/crolly/mug/cmd/models"
 	"github.com/spf13/cobra"
 )
@@ -32,43 +30,59 @@ var (
 	DeployCmd = &cobra.Command{
 		Use:   "deploy",
 		Short: "Deploys the stack to AWS using serverless framework",
+		Long: `Deploys the resources/ function groups changed since their last deployment to the stage using serverless framework.
+Unchanged services are skipped and if only the code of a single function changed, only that function is updated.
+Independent services are deployed in parallel, services with dependencies in mug.config.json after them.`,
+		Annotations: map[string]string{models.LockAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
 			mc := models.ReadMUGConfig()
 
 			list := models.GetList(mc.ProjectPath, buildList)
+			if len(name) > 0 {
+				// only build and deploy the resource/ function group of the function
+				list = []string{mc.FunctionService(list, name)}
+			}
 
 			if !noTest {
 				// create lambda-local network if it doesn't exist already
-				models.CreateLambdaNetwork()
+				mc.CreateLambdaNetwork()
 				// start dynamodb-local
-				models.StartLocalDynamoDB()
+				mc.StartLocalDynamoDB()
 				// create tables for resources
 				mc.CreateResourceTables(list, "test", force)
 			}
 
 			// build binaries
-			mc.MakeBuild(list, !noTest)
-			// deploy to AWS
-			sls := "sls deploy --stage " + stage
-			if len(profile) > 0 {
-				sls = sls + " --aws-profile " + profile
-			}
-			for _, r := range list {
-				models.RunCmd("/bin/sh", "-c", "cd "+filepath.Join(mc.ProjectPath, "functions", r)+";"+sls)
+			mc.Build(list, !noTest)
+			// package the binaries
+			mc.Package(list)
+			// compare with the last deployment to the stage
+			plan := mc.DeployPlan(list, stage, profile, name, all)
+			models.PrintDeployPlan(plan)
+			if planOnly {
+				return
 			}
+			// serverless deploys the generated serverless.yml and the packages from disk
+			models.Commit()
+			// deploy to AWS
+			mc.Deploy(plan, stage, profile, concurrency)
 		},
 	}
 
-	name, buildList, stage, profile string
-	noUpdate, noTest, force         bool
+	name, buildList, stage, profile        string
+	noUpdate, noTest, force, all, planOnly bool
+	concurrency                            int
 )
 
 func init() {
 	DeployCmd.Flags().BoolVarP(&noUpdate, "ignoreYMLUpdate", "i", false, "Ignore update of serverless.yml during execution")
 	DeployCmd.Flags().BoolVarP(&noTest, "do not test", "t", false, "Don't run tests before deploying")
-	DeployCmd.Flags().StringVarP(&name, "name", "n", "", "Name of the resource of function to deploy")
+	DeployCmd.Flags().StringVarP(&name, "name", "n", "", "Name of a function to deploy on its own, e.g. create_user")
 	DeployCmd.Flags().StringVarP(&buildList, "list", "l", "all", "comma separated list of resources/ function groups to debug")
 	DeployCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "define deployment stage")
 	DeployCmd.Flags().StringVarP(&profile, "profile", "p", "", "define deployment profile")
-	DeployCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "force overwrite existing tables (might be necessary if you changed you table definition - e.g. new index)")
+	DeployCmd.Flags().BoolVar(&all, "all", false, "deploy all services of the list, even the unchanged ones")
+	DeployCmd.Flags().IntVarP(&concurrency, "concurrency", "c", 0, "maximum number of services deployed at the same time (default Deployment.Concurrency of mug.config.json or 4)")
+	DeployCmd.Flags().BoolVar(&planOnly, "plan", false, "only show which services would be deployed")
+	DeployCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "recreate existing tables empty instead of migrating them to the changed table definition")
 }
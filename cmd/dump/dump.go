@@ -0,0 +1,54 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package dump
+
+import (
+	"github.com/crolly/mug/cmd/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// DumpCmd represents the dump command
+	DumpCmd = &cobra.Command{
+		Use:         "dump",
+		Short:       "Export the local DynamoDB tables to fixtures",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Exports the items of the local DynamoDB tables of the resources to functions/<resource>/fixtures/<resource>.json
+(or .yaml), replacing the existing fixtures of the resource. Commit the fixtures to share a reproducible local
+dataset, 'mug seed' loads them into the tables again.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// get the config
+			mc := models.ReadMUGConfig()
+
+			list := models.GetList(mc.ProjectPath, dumpList)
+
+			mc.Dump(list, mode, format)
+		},
+	}
+
+	dumpList, mode, format string
+)
+
+func init() {
+	DumpCmd.Flags().StringVarP(&dumpList, "list", "l", "all", "comma separated list of resources to dump")
+	DumpCmd.Flags().StringVarP(&mode, "mode", "m", "debug", "Choose between the 'debug' and 'test' tables")
+	DumpCmd.Flags().StringVar(&format, "format", "json", "Choose between 'json' and 'yaml' fixtures")
+}
@@ -0,0 +1,101 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package event
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	generateCmd = &cobra.Command{
+		Use:   "generate TYPE",
+		Short: "Generates a sample event for the functions of a resource or function group",
+		Long: `Generates a realistic sample event of the given type (` + strings.Join(models.EventTypes, ", ") + `).
+API Gateway events use the route of the function with the path parameters filled from the model of a
+resource, which also fills the bodies of the events. Queues, topics, buckets, rules and streams are
+taken from the events configured in the serverless.yml.
+
+The events are saved to functions/<group>/<function>/events/<type>.json, where they are picked up by
+the generated tests, or printed with --stdout to be piped into 'mug invoke --stdin'.`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: models.EventTypes,
+		Run: func(cmd *cobra.Command, args []string) {
+			kind := args[0]
+			if !models.Contains(models.EventTypes, kind) {
+				log.Fatalf("Unknown event type %s, choose between %s", kind, strings.Join(models.EventTypes, ", "))
+			}
+
+			mc := models.ReadMUGConfig()
+			if len(models.GetList(mc.ProjectPath, assigned)) == 0 {
+				log.Fatalf("Resource or function group %s doesn't exist", assigned)
+			}
+			sc := mc.ReadServerlessConfig(assigned)
+
+			var names []string
+			if len(function) > 0 {
+				name, ok := sc.FunctionName(assigned, function)
+				if !ok {
+					log.Fatalf("Function %s doesn't exist in %s", function, assigned)
+				}
+				names = append(names, name)
+			} else {
+				for name := range sc.Functions {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+			}
+
+			if stdout {
+				if len(names) != 1 {
+					log.Fatal("Please choose the function with --name to print its event")
+				}
+				data, err := mc.GenerateEvent(sc, assigned, names[0], kind)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Println(string(data))
+				return
+			}
+
+			for _, name := range names {
+				path := mc.WriteEvent(sc, assigned, name, kind, force || len(function) > 0)
+				log.Printf("Event for %s saved to %s", name, path)
+			}
+		},
+	}
+
+	assigned, function string
+	stdout, force      bool
+)
+
+func init() {
+	EventCmd.AddCommand(generateCmd)
+
+	generateCmd.Flags().StringVarP(&assigned, "assign", "a", "generic", "Name of the resource or function group of the functions")
+	generateCmd.Flags().StringVarP(&function, "name", "n", "", "Name of the function, all functions of the resource or function group if omitted")
+	generateCmd.Flags().BoolVar(&stdout, "stdout", false, "Print the event instead of saving it")
+	generateCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite existing events of all functions")
+}
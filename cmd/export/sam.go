@@ -0,0 +1,69 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package export
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	samCmd = &cobra.Command{
+		Use:   "sam",
+		Short: "Exports a deployable AWS SAM or CloudFormation template of your project",
+		Long: `This command generates one template for all resources and function groups of your project
+containing the functions with all their events, the DynamoDB tables, IAM roles, authorizers and environments.
+
+The sam target references the binaries in functions/<resource>/bin and can be deployed with 'sam deploy'.
+The cloudformation target expects the function zip files at <resource>/<handler>.zip in the ArtifactBucket parameter.
+Secrets referenced from SSM become NoEcho parameters of the template.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+
+			list := models.GetList(mc.ProjectPath, exportList)
+
+			if len(output) == 0 {
+				output = target + "-template.yml"
+			}
+			if !filepath.IsAbs(output) {
+				output = filepath.Join(mc.ProjectPath, output)
+			}
+
+			t := mc.ExportTemplate(list, target)
+			t.Write(output)
+
+			log.Printf("%s template written to %s", target, output)
+		},
+	}
+
+	target, output, exportList string
+)
+
+func init() {
+	ExportCmd.AddCommand(samCmd)
+
+	samCmd.Flags().StringVarP(&target, "target", "t", models.SAMTarget, "Choose between 'sam' for an AWS SAM template or 'cloudformation' for a plain CloudFormation template")
+	samCmd.Flags().StringVarP(&output, "output", "o", "", "file the template is written to (default \"<target>-template.yml\")")
+	samCmd.Flags().StringVarP(&exportList, "list", "l", "all", "comma separated list of resources/ function groups to export")
+}
@@ -0,0 +1,67 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package export
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	terraformCmd = &cobra.Command{
+		Use:   "terraform",
+		Short: "Exports a Terraform configuration of your project",
+		Long: `This command generates the HCL for the Lambda functions, API Gateway routes, DynamoDB tables
+and IAM roles of your resources and function groups derived from their serverless.yml and resource models.
+
+The functions are packaged from the binaries in functions/<resource>/bin, so build them before running 'terraform apply'.
+The stage and region become variables, secrets referenced from SSM become sensitive variables.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+
+			list := models.GetList(mc.ProjectPath, tfList)
+
+			if !filepath.IsAbs(tfOutput) {
+				tfOutput = filepath.Join(mc.ProjectPath, tfOutput)
+			}
+			root, err := filepath.Rel(filepath.Dir(tfOutput), mc.ProjectPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			models.WriteTerraform(tfOutput, mc.ExportTerraform(list, root))
+
+			log.Printf("Terraform configuration written to %s", tfOutput)
+		},
+	}
+
+	tfOutput, tfList string
+)
+
+func init() {
+	ExportCmd.AddCommand(terraformCmd)
+
+	terraformCmd.Flags().StringVarP(&tfOutput, "output", "o", filepath.Join("terraform", "main.tf"), "file the configuration is written to")
+	terraformCmd.Flags().StringVarP(&tfList, "list", "l", "all", "comma separated list of resources/ function groups to export")
+}
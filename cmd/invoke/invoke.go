@@ -0,0 +1,136 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package invoke
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// InvokeCmd represents the invoke command
+	InvokeCmd = &cobra.Command{
+		Use:   "invoke FUNCTION",
+		Short: "Invokes a single function locally with an event",
+		Long: `Builds the function for the local machine and invokes it once with the event read from the file
+given with --event or from stdin with --stdin (an empty JSON object otherwise). Any event shape can
+be passed, e.g. API Gateway, SQS, SNS, schedule or stream events generated with 'mug event generate'.
+
+The environment is resolved like for 'mug debug'. The response is printed to stdout, the logs of the
+function to stderr.`,
+		Annotations:  map[string]string{models.LockAnnotation: "true"},
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if assigned == "all" || strings.Contains(assigned, ",") {
+				return fmt.Errorf("--assign takes the name of a single resource or function group, not %s", assigned)
+			}
+			payload := readEvent()
+
+			mc := models.ReadMUGConfig()
+			list := models.GetList(mc.ProjectPath, assigned)
+			if len(list) != 1 {
+				return fmt.Errorf("resource or function group %s doesn't exist", assigned)
+			}
+
+			// build binaries for the local machine
+			mc.BuildNative(list)
+			if models.DryRun {
+				return nil
+			}
+
+			fn, err := mc.LocalFunction(assigned, args[0], stage)
+			if err != nil {
+				return err
+			}
+			defer fn.Stop()
+
+			start := time.Now()
+			out, err := fn.Invoke(payload)
+			log.Printf("REPORT %s/%s Duration: %s", fn.Resource, fn.Name, time.Since(start).Round(time.Millisecond))
+			if err != nil {
+				if fe, ok := err.(*models.FunctionError); ok {
+					data, _ := json.MarshalIndent(fe, "", "  ")
+					fmt.Println(string(data))
+					return fmt.Errorf("function %s/%s failed", fn.Resource, fn.Name)
+				}
+				return err
+			}
+
+			printResponse(out)
+			return nil
+		},
+	}
+
+	assigned, event, stage string
+	stdin                  bool
+)
+
+func init() {
+	InvokeCmd.Flags().StringVarP(&assigned, "assign", "a", "generic", "Name of the resource or function group the function is assigned to")
+	InvokeCmd.Flags().StringVarP(&event, "event", "e", "", "JSON file with the event passed to the function")
+	InvokeCmd.Flags().BoolVar(&stdin, "stdin", false, "read the event from stdin")
+	InvokeCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the serverless variables and secrets are resolved for")
+}
+
+// readEvent returns the event from the file or stdin
+func readEvent() []byte {
+	if stdin && len(event) > 0 {
+		log.Fatal("Either pass the event with --event or --stdin")
+	}
+
+	payload := []byte("{}")
+	var err error
+	switch {
+	case stdin:
+		payload, err = ioutil.ReadAll(os.Stdin)
+	case len(event) > 0:
+		payload, err = ioutil.ReadFile(event)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !json.Valid(payload) {
+		log.Fatal("Event is no valid JSON")
+	}
+
+	return payload
+}
+
+// printResponse prints the response indented if it is JSON
+func printResponse(out []byte) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, out, "", "  "); err != nil {
+		os.Stdout.Write(out)
+		fmt.Println()
+		return
+	}
+	fmt.Println(buf.String())
+}
@@ -0,0 +1,51 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package local
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var (
+	// LocalCmd represents the local command
+	LocalCmd = &cobra.Command{
+		Use:   "local",
+		Short: "Manage the local DynamoDB of your project",
+		Long: `Manages the local DynamoDB container used by 'mug debug', 'mug test' and 'mug seed'.
+It is configured with LocalDynamoDB in the mug.config.json, e.g.:
+
+  "LocalDynamoDB": {
+    "Container": "myproject-dynamodb",
+    "Port": 8001,
+    "Persistent": true
+  }
+
+Container (default dynamodb), Image (amazon/dynamodb-local), Tag (latest), Port (8000),
+Persistent (false, the data is kept in memory), DataDir (.mug/dynamodb) and Network (lambda-local)
+can be set. Give each project its own container and port to run them side by side.`,
+	}
+)
+
+func init() {
+	LocalCmd.SetHelpCommand(&cobra.Command{
+		Use:    "no-help",
+		Hidden: true,
+	})
+}
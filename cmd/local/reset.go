@@ -0,0 +1,50 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package local
+
+import (
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	resetCmd = &cobra.Command{
+		Use:         "reset",
+		Short:       "Recreates the local DynamoDB container with empty tables",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Removes the local DynamoDB container including its persistent data, starts it again with the current
+configuration and creates empty tables for the resources. Restore data afterwards with 'mug seed'.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+
+			mc.CreateLambdaNetwork()
+			mc.ResetLocalDynamoDB()
+			mc.CreateResourceTables(models.GetList(mc.ProjectPath, "all"), resetMode, false)
+		},
+	}
+
+	resetMode string
+)
+
+func init() {
+	LocalCmd.AddCommand(resetCmd)
+
+	resetCmd.Flags().StringVarP(&resetMode, "mode", "m", "debug", "Choose between creating the 'debug' and 'test' tables")
+}
@@ -0,0 +1,53 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package migrate
+
+import (
+	"log"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// MigrateModulesCmd represents the migrate-modules command
+	MigrateModulesCmd = &cobra.Command{
+		Use:         "migrate-modules",
+		Short:       "Migrates the project from dep to go modules",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Migrates the project from dep to go modules.
+A go.mod with the ImportPath of mug.config.json as module path is written. Dependencies locked
+to a version in Gopkg.lock are kept, the remaining ones are resolved by 'go mod tidy' with the
+next build. Gopkg.toml, Gopkg.lock and the vendor directory are removed.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+			mc.MigrateModules()
+			log.Printf("Migrated %s to go module %s, run 'go mod tidy' to resolve the remaining dependencies", mc.ProjectName, mc.ImportPath)
+		},
+	}
+)
+
+func init() {
+	MigrateModulesCmd.SetHelpCommand(&cobra.Command{
+		Use:    "no-help",
+		Hidden: true,
+	})
+}
@@ -0,0 +1,452 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// buildCache is the file storing the input hashes of the built binaries
+const buildCache = ".mug/build.json"
+
+// buildTarget is a single function binary to be built
+type buildTarget struct {
+	Resource string
+	Function string
+	Package  string
+	Output   string
+	Flags    []string
+	Env      []string
+
+	hash     string
+	cached   bool
+	duration time.Duration
+	size     int64
+	output   []byte
+	err      error
+}
+
+// goPackage is the part of the 'go list -json' output used to hash the inputs of a binary
+type goPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	GoFiles    []string
+	CgoFiles   []string
+	EmbedFiles []string
+	Deps       []string
+	Module     *struct {
+		Path    string
+		Version string
+		Main    bool
+	}
+}
+
+// buildOptions configures how and where the binaries are built
+type buildOptions struct {
+	// folder is the folder of the resource/ function group the binaries are built into
+	folder string
+	flags  []string
+	// packaged keeps the zips of 'mug package' in the folder
+	packaged bool
+	// native builds for the local OS and architecture instead of Lambda
+	native bool
+	test   bool
+}
+
+// BuildDebug builds the debug binaries of the given resources/ function groups
+func (m MUGConfig) BuildDebug(list []string) {
+	m.build(list, buildOptions{folder: "debug", flags: []string{"-gcflags", "all=-N -l"}})
+}
+
+// BuildNative builds the binaries of the given resources/ function groups for the local machine
+func (m MUGConfig) BuildNative(list []string) {
+	m.build(list, buildOptions{folder: "local", flags: []string{"-gcflags", "all=-N -l"}, native: true})
+}
+
+// Build builds the binaries of the given resources/ function groups running the tests first if requested
+func (m MUGConfig) Build(list []string, test bool) {
+	m.build(list, buildOptions{folder: "bin", flags: []string{"-ldflags", "-s -w"}, packaged: true, test: test})
+}
+
+// RebuildDebug rebuilds the debug binaries of the given resources/ function groups affected by changes
+// without resolving the dependencies. A failed build is returned instead of terminating mug.
+func (m MUGConfig) RebuildDebug(list []string) error {
+	return m.buildBinaries(list, buildOptions{folder: "debug", flags: []string{"-gcflags", "all=-N -l"}})
+}
+
+func (m MUGConfig) build(list []string, opts buildOptions) {
+	m.ensureDependencies(list)
+
+	if opts.test {
+		env := append([]string{"MODE=test", "DYNAMODB_ENDPOINT=" + m.DynamoDB().Endpoint()}, m.LocalTableEnvironment(list, "test")...)
+		for _, r := range list {
+			log.Printf("Run tests of %s", r)
+			RunCmdWithEnv(env, "go", "test", "./functions/"+r+"/...", "-cover")
+		}
+	}
+
+	if err := m.buildBinaries(list, opts); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// buildBinaries builds the binaries whose inputs changed since the last build
+func (m MUGConfig) buildBinaries(list []string, opts buildOptions) error {
+	targets := m.buildTargets(list, opts)
+	if DryRun {
+		for _, t := range targets {
+			Record("%s go build %s -o %s %s", strings.Join(t.Env, " "), strings.Join(t.Flags, " "), t.Output, t.Package)
+		}
+		return nil
+	}
+	cache := m.readBuildCache()
+	if err := m.hashTargets(targets); err != nil {
+		return fmt.Errorf("error resolving packages: %s", err)
+	}
+	for _, t := range targets {
+		if _, err := os.Stat(filepath.Join(m.ProjectPath, t.Output)); err == nil && cache[t.Output] == t.hash {
+			t.cached = true
+		}
+	}
+
+	runBuilds(m.ProjectPath, targets)
+
+	failed := false
+	for _, t := range targets {
+		if t.err != nil {
+			failed = true
+			log.Printf("Building %s/%s failed with %s\n%s", t.Resource, t.Function, t.err, t.output)
+			continue
+		}
+		cache[t.Output] = t.hash
+	}
+	m.writeBuildCache(cache)
+	printBuildReport(targets)
+
+	if failed {
+		return fmt.Errorf("build failed")
+	}
+	return nil
+}
+
+// ensureDependencies resolves the dependencies of the project with go modules or dep. It only runs if the packages
+// import dependencies missing in go.mod/ go.sum, e.g. after adding a resource, so builds neither need the network
+// nor rewrite go.mod otherwise.
+func (m MUGConfig) ensureDependencies(list []string) {
+	if m.dependenciesResolved(list) {
+		return
+	}
+
+	if m.UsesModules() {
+		RunCmd("go", "mod", "tidy")
+	} else {
+		RunCmd("dep", "ensure")
+	}
+}
+
+// dependenciesResolved checks whether all packages of the resources/ function groups including their tests
+// can be resolved with the current dependencies of the project
+func (m MUGConfig) dependenciesResolved(list []string) bool {
+	args := []string{"list", "-e", "-deps", "-test", "-f", "{{if or .Error .DepsErrors}}{{.ImportPath}}{{end}}"}
+	for _, r := range list {
+		args = append(args, "./functions/"+r+"/...")
+	}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = m.ProjectPath
+	out, err := cmd.Output()
+
+	return err == nil && len(bytes.TrimSpace(out)) == 0
+}
+
+// buildTargets returns the targets for all functions of the resources/ function groups and removes stale binaries.
+// The OS-only runtimes require a bootstrap binary, which is placed in a folder per function.
+func (m MUGConfig) buildTargets(list []string, opts buildOptions) []*buildTarget {
+	var targets []*buildTarget
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+
+		// outputs maps the names of the outputs to whether they are folders
+		outputs := map[string]bool{}
+		for _, fn := range sortedKeys(sc.Functions) {
+			name := strings.TrimPrefix(sc.Functions[fn].Handler, "bin/")
+			fnRuntime, arch := sc.FunctionRuntime(fn)
+			t := &buildTarget{
+				Resource: r,
+				Function: name,
+				Package:  "./" + filepath.ToSlash(filepath.Join("functions", r, name)),
+				Output:   filepath.Join("functions", r, opts.folder, name),
+				Flags:    opts.flags,
+				Env:      []string{"GOOS=linux", "GOARCH=" + goArch(arch), "CGO_ENABLED=0"},
+			}
+			if opts.native {
+				t.Env = []string{"GOOS=" + runtime.GOOS, "GOARCH=" + runtime.GOARCH, "CGO_ENABLED=0"}
+			}
+			outputs[name] = false
+			if opts.packaged {
+				outputs[name+".zip"] = false
+			}
+			if IsProvidedRuntime(fnRuntime) {
+				outputs[name] = true
+				t.Output = filepath.Join(t.Output, bootstrap)
+				t.Flags = append([]string{"-tags", "lambda.norpc"}, opts.flags...)
+			}
+			targets = append(targets, t)
+		}
+
+		// remove binaries of functions which don't exist anymore
+		info, err := ioutil.ReadDir(filepath.Join(m.ProjectPath, "functions", r, opts.folder))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		for _, f := range info {
+			// binaries of a different runtime are replaced, go1.x uses a file and the OS-only runtimes a folder.
+			// Like the binaries go build writes, they are removed right away instead of being staged.
+			if isDir, ok := outputs[f.Name()]; !ok || isDir != f.IsDir() {
+				stale := filepath.Join(m.ProjectPath, "functions", r, opts.folder, f.Name())
+				if DryRun {
+					Record("rm -rf %s", stale)
+				} else if err := os.RemoveAll(stale); err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+	}
+
+	return targets
+}
+
+// hashTargets hashes the inputs of all targets: the sources of the project packages they depend on,
+// the versions of the dependencies, go.mod and go.sum, the build flags and the go version
+func (m MUGConfig) hashTargets(targets []*buildTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+
+	common := sha256.New()
+	fmt.Fprintln(common, runtime.Version())
+	for _, f := range []string{"go.mod", "go.sum", "Gopkg.lock"} {
+		if err := hashFile(common, filepath.Join(m.ProjectPath, f)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	// build constraints select different files per GOOS/ GOARCH, so the packages are listed per platform
+	var platforms []string
+	byPlatform := map[string][]*buildTarget{}
+	for _, t := range targets {
+		platform := strings.Join(t.Env, " ")
+		if _, ok := byPlatform[platform]; !ok {
+			platforms = append(platforms, platform)
+		}
+		byPlatform[platform] = append(byPlatform[platform], t)
+	}
+
+	for _, platform := range platforms {
+		targets := byPlatform[platform]
+		pkgs, dirs, err := m.listPackages(targets)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range targets {
+			root, ok := pkgs[path.Join(m.ImportPath, strings.TrimPrefix(t.Package, "./"))]
+			if !ok {
+				root, ok = dirs[filepath.Join(m.ProjectPath, filepath.FromSlash(t.Package))]
+			}
+			if !ok {
+				return fmt.Errorf("package %s not found", t.Package)
+			}
+
+			h := sha256.New()
+			h.Write(common.Sum(nil))
+			fmt.Fprintln(h, strings.Join(t.Flags, " "), strings.Join(t.Env, " "))
+			for _, dep := range append([]string{root.ImportPath}, root.Deps...) {
+				p := pkgs[dep]
+				if p == nil || p.Standard {
+					continue
+				}
+				if p.Module != nil && !p.Module.Main {
+					// dependencies are identified by their version
+					fmt.Fprintln(h, p.ImportPath, p.Module.Path, p.Module.Version)
+					continue
+				}
+				fmt.Fprintln(h, p.ImportPath)
+				for _, files := range [][]string{p.GoFiles, p.CgoFiles, p.EmbedFiles} {
+					for _, f := range files {
+						if err := hashFile(h, filepath.Join(p.Dir, f)); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			t.hash = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+
+	return nil
+}
+
+// listPackages lists the packages of the targets, which share their environment, including their dependencies
+// by import path and by directory
+func (m MUGConfig) listPackages(targets []*buildTarget) (map[string]*goPackage, map[string]*goPackage, error) {
+	args := []string{"list", "-e", "-json", "-deps"}
+	for _, t := range targets {
+		args = append(args, t.Package)
+	}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = m.ProjectPath
+	cmd.Env = append(os.Environ(), targets[0].Env...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkgs := map[string]*goPackage{}
+	dirs := map[string]*goPackage{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		p := &goPackage{}
+		if err := dec.Decode(p); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, err
+		}
+		pkgs[p.ImportPath] = p
+		dirs[p.Dir] = p
+	}
+
+	return pkgs, dirs, nil
+}
+
+// hashFile writes the name and content of a file to the hash
+func hashFile(w io.Writer, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(w, filepath.Base(file))
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// runBuilds builds the targets, which are not cached, in parallel
+func runBuilds(projectPath string, targets []*buildTarget) {
+	jobs := make(chan *buildTarget)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range jobs {
+				t.build(projectPath)
+			}
+		}()
+	}
+
+	for _, t := range targets {
+		if !t.cached {
+			jobs <- t
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// build cross-compiles the binary of the target
+func (t *buildTarget) build(projectPath string) {
+	start := time.Now()
+
+	args := append([]string{"build"}, t.Flags...)
+	args = append(args, "-o", t.Output, t.Package)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = projectPath
+	cmd.Env = append(os.Environ(), t.Env...)
+	t.output, t.err = cmd.CombinedOutput()
+	t.duration = time.Since(start)
+
+	if t.err == nil {
+		if info, err := os.Stat(filepath.Join(projectPath, t.Output)); err == nil {
+			t.size = info.Size()
+		}
+	}
+}
+
+// printBuildReport prints the duration and binary size of every target to stderr keeping stdout for the output of the command
+func printBuildReport(targets []*buildTarget) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tSTATUS\tTIME\tSIZE")
+	for _, t := range targets {
+		status, duration, size := "built", t.duration.Round(time.Millisecond).String(), formatSize(t.size)
+		switch {
+		case t.err != nil:
+			status, size = "failed", "-"
+		case t.cached:
+			status, duration, size = "cached", "-", "-"
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\n", t.Resource, t.Function, status, duration, size)
+	}
+	w.Flush()
+}
+
+// formatSize returns the size in bytes human readable
+func formatSize(size int64) string {
+	if size < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
+
+// readBuildCache returns the input hashes of the previous builds
+func (m MUGConfig) readBuildCache() map[string]string {
+	cache := map[string]string{}
+	data, err := ReadFile(filepath.Join(m.ProjectPath, buildCache))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cache
+		}
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		log.Printf("Ignoring invalid build cache: %s", err)
+		return map[string]string{}
+	}
+
+	return cache
+}
+
+// writeBuildCache writes the input hashes of the built binaries
+func (m MUGConfig) writeBuildCache(cache map[string]string) {
+	// drop hashes of binaries which don't exist anymore
+	for o := range cache {
+		p := filepath.Join(m.ProjectPath, o)
+		if _, err := os.Stat(p); os.IsNotExist(err) || vfs.isRemoved(p) {
+			delete(cache, o)
+		}
+	}
+
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(m.ProjectPath, buildCache), data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
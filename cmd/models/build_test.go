@@ -0,0 +1,200 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeProject writes the files of a test project into a temporary folder
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "mug-build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestHashTargetsPerPlatform(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"go.mod":                             "module example.com/project\n\ngo 1.16\n",
+		"functions/user/create/main.go":      "package main\n\nfunc main() { run() }\n",
+		"functions/user/create/run_amd64.go": "package main\n\nfunc run() {}\n",
+		"functions/user/create/run_arm64.go": "package main\n\nfunc run() {}\n",
+		"functions/user/create/run_other.go": "//go:build !amd64 && !arm64\n\npackage main\n\nfunc run() {}\n",
+		"functions/user/create/main_test.go": "package main\n",
+	})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectPath: dir, ImportPath: "example.com/project"}
+
+	targets := func() (*buildTarget, *buildTarget) {
+		amd := &buildTarget{Package: "./functions/user/create", Env: []string{"GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0"}}
+		arm := &buildTarget{Package: "./functions/user/create", Env: []string{"GOOS=linux", "GOARCH=arm64", "CGO_ENABLED=0"}}
+		if err := m.hashTargets([]*buildTarget{amd, arm}); err != nil {
+			t.Fatal(err)
+		}
+		return amd, arm
+	}
+
+	amd, arm := targets()
+	if amd.hash == arm.hash {
+		t.Fatal("targets of different architectures have the same hash")
+	}
+
+	tests := []struct {
+		name       string
+		file       string
+		amdChanged bool
+		armChanged bool
+	}{
+		{"shared file", "main.go", true, true},
+		{"amd64 file", "run_amd64.go", true, false},
+		{"arm64 file", "run_arm64.go", false, true},
+		{"file of other platforms", "run_other.go", false, false},
+		{"test file", "main_test.go", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := os.OpenFile(filepath.Join(dir, "functions", "user", "create", tt.file), os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.WriteString("\n// changed\n")
+			f.Close()
+
+			newAmd, newArm := targets()
+			if changed := newAmd.hash != amd.hash; changed != tt.amdChanged {
+				t.Errorf("hash of amd64 changed = %v, want %v", changed, tt.amdChanged)
+			}
+			if changed := newArm.hash != arm.hash; changed != tt.armChanged {
+				t.Errorf("hash of arm64 changed = %v, want %v", changed, tt.armChanged)
+			}
+			amd, arm = newAmd, newArm
+		})
+	}
+}
+
+func TestDependenciesResolved(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  bool
+	}{
+		{
+			name: "standard library only",
+			files: map[string]string{
+				"functions/user/create/main.go": "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n",
+			},
+			want: true,
+		},
+		{
+			name: "missing dependency",
+			files: map[string]string{
+				"functions/user/create/main.go": "package main\n\nimport _ \"example.com/missing/pkg\"\n\nfunc main() {}\n",
+			},
+			want: false,
+		},
+		{
+			name: "missing test dependency",
+			files: map[string]string{
+				"functions/user/create/main.go":      "package main\n\nfunc main() {}\n",
+				"functions/user/create/main_test.go": "package main\n\nimport _ \"example.com/missing/assert\"\n",
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.files["go.mod"] = "module example.com/project\n\ngo 1.16\n"
+			dir := writeProject(t, tt.files)
+			defer os.RemoveAll(dir)
+
+			m := MUGConfig{ProjectPath: dir, ImportPath: "example.com/project"}
+			if got := m.dependenciesResolved([]string{"user"}); got != tt.want {
+				t.Errorf("dependenciesResolved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildBinariesCache(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+	vfs = newVirtualFS()
+	dir := writeProject(t, map[string]string{
+		"go.mod":                        "module example.com/project\n\ngo 1.16\n",
+		"functions/user/serverless.yml": "service:\n  name: user\nprovider:\n  name: aws\n  runtime: provided.al2023\nfunctions:\n  create:\n    handler: bin/create\n",
+		"functions/user/create/main.go": "package main\n\nfunc main() {}\n",
+		"functions/user/debug/removed":  "stale binary",
+	})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectPath: dir, ImportPath: "example.com/project"}
+	binary := filepath.Join(dir, "functions", "user", "debug", "create", bootstrap)
+
+	// build returns the modification time of the binary after building it, the binary is backdated before
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	build := func() time.Time {
+		if err := m.RebuildDebug([]string{"user"}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := os.Stat(binary)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modTime := info.ModTime()
+		if err := os.Chtimes(binary, old, old); err != nil {
+			t.Fatal(err)
+		}
+		return modTime
+	}
+
+	build()
+	if _, err := os.Stat(filepath.Join(dir, "functions", "user", "debug", "removed")); !os.IsNotExist(err) {
+		t.Errorf("stale binary wasn't removed: %v", err)
+	}
+	if cache := m.readBuildCache(); len(cache[filepath.Join("functions", "user", "debug", "create", bootstrap)]) == 0 {
+		t.Errorf("readBuildCache() = %v, want the hash of the binary", cache)
+	}
+
+	if got := build(); !got.Equal(old) {
+		t.Errorf("unchanged binary was rebuilt at %s", got)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions", "user", "create", "main.go"), []byte("package main\n\nfunc main() { println() }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := build(); got.Equal(old) {
+		t.Error("changed binary wasn't rebuilt")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		size int64
+		want string
+	}{
+		{0, "0.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+	}
+
+	for _, tt := range tests {
+		if got := formatSize(tt.size); got != tt.want {
+			t.Errorf("formatSize(%d) = %q, want %q", tt.size, got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,13 @@ package models
 
 import (
 	"encoding/json"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"text/template"
 
 	"gopkg.in/yaml.v2"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/gobuffalo/flect"
 )
@@ -22,7 +19,14 @@ type MUGConfig struct {
 	ProjectPath string
 	ImportPath  string
 	Region      string
-	Resources   map[string]*NewResource
+	// Runtime and Architecture are the defaults for new resources/ function groups
+	Runtime      string
+	Architecture string
+	Resources    map[string]*NewResource
+	// LocalDynamoDB configures the local DynamoDB container, see DynamoDB for the defaults
+	LocalDynamoDB *LocalDynamoDB `json:",omitempty"`
+	// Deployment configures the order and concurrency of 'mug deploy', see DeploySettings for the defaults
+	Deployment *DeployConfig `json:",omitempty"`
 }
 
 // NewResource ...
@@ -70,7 +74,7 @@ func (m MUGConfig) Write() {
 	f := filepath.Join(m.ProjectPath, "mug.config.json")
 
 	json, _ := json.MarshalIndent(m, "", "  ")
-	err := ioutil.WriteFile(f, json, 0644)
+	err := WriteFile(f, json, 0644)
 
 	if err != nil {
 		log.Fatal(err)
@@ -84,6 +88,9 @@ func (m MUGConfig) NewServerlessConfig(resource string) ServerlessConfig {
 	s.ProjectPath = m.ProjectPath
 	s.Service = Service{Name: m.ProjectName + "-" + resource}
 	s.Provider.Region = m.Region
+	if len(m.Runtime) > 0 {
+		s.SetRuntime(m.Runtime, m.Architecture)
+	}
 
 	return s
 }
@@ -112,71 +119,22 @@ func (m *MUGConfig) RemoveResource(rN string) {
 	delete(m.Resources, rN)
 }
 
-func (m MUGConfig) clearFolder(path string) {
-	if err := os.RemoveAll(filepath.Join(m.ProjectPath, "functions", path)); err != nil {
-		log.Fatal(err)
-	}
-}
-
-func (m MUGConfig) renderMakefile(t *template.Template, r string) {
-	// open file and execute template
-	f, err := os.Create(filepath.Join(m.ProjectPath, "Makefile"))
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer f.Close()
-
-	sc := m.ReadServerlessConfig(r)
-	// execute template and save to file
-	data := map[string]interface{}{
-		"Functions": sc.Functions,
-		"Resource":  r,
-	}
-
-	err = t.Execute(f, data)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Makefile generated.")
-}
-
-func (m MUGConfig) make(list []string, path, target string, test bool) {
-	// load Makefile template
-	t := LoadTemplateFromBox(MakeBox, "Makefile.tmpl")
-
-	for _, r := range list {
-		// clear the debug binaries
-		m.clearFolder(filepath.Join(r, path))
-		// render for each resource/ function group
-		m.renderMakefile(t, r)
-		// run test if flag indicates so
-		if test {
-			log.Println("Run tests")
-			RunCmd("make", "test")
+// CreateResourceTables creates the tables in the local DynamoDB named by the given mode
+func (m MUGConfig) CreateResourceTables(list []string, mode string, overwrite bool) {
+	if DryRun {
+		for n, r := range m.Resources {
+			if Contains(list, n) {
+				sc := m.ReadServerlessConfig(n)
+				if res := sc.Resources.Resources[r.Ident.Pascalize().String()+"DynamoDbTable"]; res != nil {
+					Record("create local DynamoDB table %s", m.LocalTableName(sc, n, res.Properties, mode))
+				}
+			}
 		}
-		// and run the build
-		RunCmd("make", target)
+		return
 	}
-}
 
-// MakeDebug renders the Makefile and builds the debug binaries
-func (m MUGConfig) MakeDebug(list []string) {
-	m.make(list, "debug", "debug", false)
-}
-
-// MakeBuild renders the Makefile and builds the binaries
-func (m MUGConfig) MakeBuild(list []string, test bool) {
-	m.make(list, "bin", "build", test)
-}
-
-// CreateResourceTables creates the tables in the local DynamoDB named by the given mode
-func (m MUGConfig) CreateResourceTables(list []string, mode string, overwrite bool) {
 	// create service to dynamodb
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint: aws.String("http://localhost:8000"),
-		Region:   aws.String(m.Region),
-	}))
-	svc := dynamodb.New(sess)
+	svc := m.localDynamoDB()
 
 	// get list of tables
 	result, err := svc.ListTables(&dynamodb.ListTablesInput{})
@@ -199,14 +157,14 @@ func (m MUGConfig) CreateResourceTables(list []string, mode string, overwrite bo
 				log.Fatalf("Resourse %s not valid. Please check your serverless.yml or your command.", rName)
 			}
 			props := res.Properties
-			tableName := m.ProjectName + "-" + r.Ident.Pluralize().Camelize().String() + "-" + mode
+			tableName := m.LocalTableName(sc, n, props, mode)
 
 			if tables[tableName] {
 				if overwrite {
 					deleteTable(svc, tableName)
 					createTableForResource(svc, tableName, props)
 				} else {
-					log.Printf("Table %s already exists, skipping creation...", tableName)
+					migrateTable(svc, tableName, props)
 				}
 			} else {
 				createTableForResource(svc, tableName, props)
@@ -216,6 +174,18 @@ func (m MUGConfig) CreateResourceTables(list []string, mode string, overwrite bo
 }
 
 func createTableForResource(svc *dynamodb.DynamoDB, tableName string, props Properties) {
+	input := tableInput(tableName, props)
+
+	out, err := svc.CreateTable(input)
+	if err != nil {
+		log.Fatalf("Error creating table %s: %s", tableName, err)
+	}
+
+	log.Printf("Table %s created: %s", tableName, out)
+}
+
+// tableInput returns the input creating the table for the properties of a resource
+func tableInput(tableName string, props Properties) *dynamodb.CreateTableInput {
 	// create the table input for the resource
 	input := &dynamodb.CreateTableInput{
 		TableName: aws.String(tableName),
@@ -322,12 +292,7 @@ func createTableForResource(svc *dynamodb.DynamoDB, tableName string, props Prop
 		input.ProvisionedThroughput = throughput
 	}
 
-	out, err := svc.CreateTable(input)
-	if err != nil {
-		log.Fatalf("Error creating table %s: %s", tableName, err)
-	}
-
-	log.Printf("Table %s created: %s", tableName, out)
+	return input
 }
 
 func deleteTable(svc *dynamodb.DynamoDB, tableName string) {
@@ -0,0 +1,565 @@
+package models
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// deployStateFolder keeps a state file per stage with the hashes of the deployed services
+	deployStateFolder = ".mug/deploy"
+	// deployConcurrency is the default number of services deployed at the same time
+	deployConcurrency = 4
+
+	// DeploySkip skips an unchanged service
+	DeploySkip = "skip"
+	// DeployService deploys the whole stack of a service with 'sls deploy'
+	DeployService = "service"
+	// DeployFunction only updates the code of a single function with 'sls deploy function'
+	DeployFunction = "function"
+)
+
+// fileReference matches the files referenced in serverless.yml, e.g. ${file(./config.yml):key}
+var fileReference = regexp.MustCompile(`\$\{file\(([^)]+)\)`)
+
+// DeployConfig configures the deployment of a project in mug.config.json, missing values use the defaults
+type DeployConfig struct {
+	// Concurrency is the maximum number of services deployed at the same time
+	Concurrency int `json:",omitempty"`
+	// DependsOn maps resources/ function groups to the ones deployed before them,
+	// e.g. the service owning the API or an SNS topic the others refer to
+	DependsOn map[string][]string `json:",omitempty"`
+}
+
+// DeploySettings returns the configuration of the deployment with the defaults for missing values
+func (m MUGConfig) DeploySettings() DeployConfig {
+	d := DeployConfig{}
+	if m.Deployment != nil {
+		d = *m.Deployment
+	}
+
+	if d.Concurrency < 1 {
+		d.Concurrency = deployConcurrency
+	}
+	if d.DependsOn == nil {
+		d.DependsOn = map[string][]string{}
+	}
+
+	return d
+}
+
+// serviceState is the content of a service at its last deployment to a stage
+type serviceState struct {
+	// Config is the hash of serverless.yml and the files it references
+	Config string
+	// Functions maps the functions to the hash of their sources and deployment package
+	Functions map[string]string
+	Profile   string `json:",omitempty"`
+	Deployed  time.Time
+}
+
+// DeployStep is the planned deployment of a service
+type DeployStep struct {
+	Service  string
+	Action   string
+	Function string
+	Reason   string
+	// DependsOn are the services of the plan deployed before this one
+	DependsOn []string
+
+	state    serviceState
+	done     bool
+	blocked  string
+	err      error
+	duration time.Duration
+}
+
+// DeployPlan compares the services in the list with their state at the last deployment to the stage and plans to
+// skip unchanged services, to update the code of a single changed function and to deploy all others completely.
+// With a function name only that function is deployed, all skips deploying every service of the list.
+// The plan is ordered so that each service follows the services it depends on.
+func (m MUGConfig) DeployPlan(list []string, stage, profile, function string, all bool) []*DeployStep {
+	deployed := m.readDeployState(stage)
+	cache := m.readBuildCache()
+
+	var plan []*DeployStep
+	sort.Strings(list)
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		step := &DeployStep{Service: r, state: m.serviceState(r, sc, cache, profile)}
+		plan = append(plan, step)
+
+		if len(function) > 0 {
+			if _, ok := sc.Functions[function]; !ok {
+				step.Action, step.Reason = DeploySkip, "no function "+function
+				continue
+			}
+			step.Action, step.Function, step.Reason = DeployFunction, function, "--name"
+			if last, ok := deployed[r]; !ok {
+				log.Printf("%s wasn't deployed to %s by mug yet, 'sls deploy function' requires the deployed service", r, stage)
+			} else if last.Config != step.state.Config {
+				log.Printf("The configuration of %s changed since its last deployment to %s, it isn't updated when deploying a single function", r, stage)
+			}
+			continue
+		}
+
+		last, ok := deployed[r]
+		step.Action = DeployService
+		switch {
+		case all:
+			step.Reason = "--all"
+		case !ok:
+			step.Reason = "not deployed yet"
+		case last.Profile != step.state.Profile:
+			step.Reason = "profile changed"
+		case last.Config != step.state.Config:
+			step.Reason = "serverless.yml changed"
+		default:
+			var changed []string
+			for _, fn := range sortedKeys(sc.Functions) {
+				if last.Functions[fn] != step.state.Functions[fn] {
+					changed = append(changed, fn)
+				}
+			}
+			switch len(changed) {
+			case 0:
+				step.Action, step.Reason = DeploySkip, "unchanged"
+			case 1:
+				step.Action, step.Function, step.Reason = DeployFunction, changed[0], "code of "+changed[0]+" changed"
+			default:
+				step.Reason = "code of " + strings.Join(changed, ", ") + " changed"
+			}
+		}
+	}
+
+	return m.orderDeployPlan(plan)
+}
+
+// orderDeployPlan sets the dependencies of the steps within the plan and orders them by their dependencies.
+// Dependencies on services outside of the plan are followed to the services of the plan they depend on.
+func (m MUGConfig) orderDeployPlan(plan []*DeployStep) []*DeployStep {
+	dependsOn := m.DeploySettings().DependsOn
+	services := make([]string, 0, len(dependsOn))
+	for s := range dependsOn {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+
+	available := GetList(m.ProjectPath, "all")
+	for _, s := range services {
+		for _, d := range append([]string{s}, dependsOn[s]...) {
+			if !Contains(available, d) {
+				log.Fatalf("Deployment.DependsOn in mug.config.json refers to %s, which is no resource/ function group", d)
+			}
+		}
+	}
+	if cycle := dependencyCycle(services, dependsOn); cycle != nil {
+		log.Fatalf("Deployment.DependsOn in mug.config.json has a cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	steps := map[string]*DeployStep{}
+	for _, s := range plan {
+		steps[s.Service] = s
+	}
+	for _, s := range plan {
+		visited := map[string]bool{}
+		var follow func(string)
+		follow = func(service string) {
+			for _, d := range dependsOn[service] {
+				if visited[d] {
+					continue
+				}
+				visited[d] = true
+				if _, ok := steps[d]; ok {
+					s.DependsOn = append(s.DependsOn, d)
+					continue
+				}
+				follow(d)
+			}
+		}
+		follow(s.Service)
+		sort.Strings(s.DependsOn)
+	}
+
+	var ordered []*DeployStep
+	added := map[string]bool{}
+	var add func(*DeployStep)
+	add = func(s *DeployStep) {
+		if added[s.Service] {
+			return
+		}
+		added[s.Service] = true
+		for _, d := range s.DependsOn {
+			add(steps[d])
+		}
+		ordered = append(ordered, s)
+	}
+	for _, s := range plan {
+		add(s)
+	}
+
+	return ordered
+}
+
+// dependencyCycle returns the services of a cycle in the dependencies, e.g. [api user api], or nil
+func dependencyCycle(services []string, dependsOn map[string][]string) []string {
+	// visiting are the services on the current path, done the ones without a cycle
+	visiting, done := map[string]bool{}, map[string]bool{}
+	var path []string
+	var visit func(string) []string
+	visit = func(s string) []string {
+		if visiting[s] {
+			for i, p := range path {
+				if p == s {
+					return append(append([]string{}, path[i:]...), s)
+				}
+			}
+		}
+		if done[s] {
+			return nil
+		}
+
+		visiting[s] = true
+		path = append(path, s)
+		for _, d := range dependsOn[s] {
+			if cycle := visit(d); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		visiting[s], done[s] = false, true
+
+		return nil
+	}
+
+	for _, s := range services {
+		if cycle := visit(s); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// PrintDeployPlan prints the planned deployment of each service in the order of the deployment
+func PrintDeployPlan(plan []*DeployStep) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tDEPLOY\tAFTER\tREASON")
+	for _, s := range plan {
+		action, after := s.Action, "-"
+		if s.Action == DeployFunction {
+			action += " " + s.Function
+		}
+		if len(s.DependsOn) > 0 {
+			after = strings.Join(s.DependsOn, ", ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Service, action, after, s.Reason)
+	}
+	w.Flush()
+}
+
+// Deploy runs the serverless framework for the planned services. Independent services are deployed in parallel up to
+// the concurrency, the others after the services they depend on. The output of each service is prefixed with its name
+// and its state is recorded right after it was deployed. Services depending on a failed one aren't deployed.
+func (m MUGConfig) Deploy(plan []*DeployStep, stage, profile string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = m.DeploySettings().Concurrency
+	}
+	if DryRun {
+		// the commands are recorded in the order of the deployment
+		concurrency = 1
+	}
+	width := 0
+	for _, s := range plan {
+		if len(s.Service) > width {
+			width = len(s.Service)
+		}
+	}
+
+	deployed := m.readDeployState(stage)
+	steps := map[string]*DeployStep{}
+	for _, s := range plan {
+		steps[s.Service] = s
+	}
+
+	var mu sync.Mutex
+	results := make(chan *DeployStep)
+	running := 0
+	pending := plan
+	for len(pending) > 0 || running > 0 {
+		// the plan is ordered by the dependencies, so failures propagate in a single pass
+		var waiting []*DeployStep
+		for _, s := range pending {
+			ready, failed := s.ready(steps)
+			switch {
+			case s.Action == DeploySkip:
+				s.done = true
+			case len(failed) > 0:
+				s.blocked = failed
+			case ready && running < concurrency:
+				running++
+				out := &prefixWriter{mu: &mu, out: os.Stdout, prefix: fmt.Sprintf("%-*s | ", width, s.Service)}
+				go func(s *DeployStep) {
+					s.run(filepath.Join(m.ProjectPath, "functions", s.Service), s.command(stage, profile), out)
+					results <- s
+				}(s)
+			default:
+				waiting = append(waiting, s)
+			}
+		}
+		pending = waiting
+		if running == 0 {
+			break
+		}
+
+		s := <-results
+		running--
+		s.done = true
+		if s.err != nil || DryRun {
+			continue
+		}
+
+		switch last, ok := deployed[s.Service]; {
+		case s.Action == DeployService:
+			s.state.Deployed = time.Now()
+			deployed[s.Service] = s.state
+		case ok:
+			// only the code of the function was updated, the configuration stays the one deployed last
+			last.Functions[s.Function] = s.state.Functions[s.Function]
+			last.Deployed = time.Now()
+			deployed[s.Service] = last
+		default:
+			continue
+		}
+		m.writeDeployState(stage, deployed)
+	}
+
+	printDeploySummary(plan)
+
+	var failed []string
+	for _, s := range plan {
+		if s.err != nil {
+			failed = append(failed, s.Service)
+		}
+	}
+	if len(failed) > 0 {
+		// keep the state of the deployed services, so the next deploy only retries the failed ones
+		Commit()
+		log.Fatalf("Deploying %s failed", strings.Join(failed, ", "))
+	}
+}
+
+// command returns the serverless command deploying the step
+func (s *DeployStep) command(stage, profile string) string {
+	sls := "sls deploy"
+	if s.Action == DeployFunction {
+		sls += " function -f " + s.Function
+	}
+	sls += " --stage " + stage
+	if len(profile) > 0 {
+		sls += " --aws-profile " + profile
+	}
+
+	return sls
+}
+
+// ready checks whether the services the step depends on are deployed or returns the first one which failed
+func (s *DeployStep) ready(steps map[string]*DeployStep) (bool, string) {
+	ready := true
+	for _, d := range s.DependsOn {
+		dep := steps[d]
+		if dep.err != nil || len(dep.blocked) > 0 {
+			return false, d
+		}
+		ready = ready && dep.done
+	}
+
+	return ready, ""
+}
+
+// run runs the serverless command in the folder of the service
+func (s *DeployStep) run(dir, sls string, out *prefixWriter) {
+	if DryRun {
+		Record("cd %s; %s", dir, sls)
+		return
+	}
+
+	start := time.Now()
+	cmd := exec.Command("/bin/sh", "-c", sls)
+	cmd.Dir = dir
+	// the same writer for both keeps the order of the lines
+	cmd.Stdout, cmd.Stderr = out, out
+	s.err = cmd.Run()
+	out.Flush()
+	s.duration = time.Since(start)
+}
+
+// printDeploySummary prints the result of the deployment of each service
+func printDeploySummary(plan []*DeployStep) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tRESULT\tDURATION")
+	for _, s := range plan {
+		result, duration := "deployed", s.duration.Round(100*time.Millisecond).String()
+		switch {
+		case s.Action == DeploySkip:
+			result, duration = "skipped, "+s.Reason, "-"
+		case len(s.blocked) > 0:
+			result, duration = "not deployed, "+s.blocked+" failed", "-"
+		case s.err != nil:
+			result = "failed, " + s.err.Error()
+		case s.Action == DeployFunction:
+			result = "deployed " + s.Function
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Service, result, duration)
+	}
+	w.Flush()
+}
+
+// prefixWriter writes the output of a service line by line prefixed with its name,
+// the lines of services deployed in parallel are written one at a time
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+// Write writes the complete lines and keeps the rest until the line is completed
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes an incomplete last line
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s%s\n", w.prefix, bytes.TrimRight(line, "\r"))
+}
+
+// FunctionService returns the resource/ function group of the list containing the function
+func (m MUGConfig) FunctionService(list []string, function string) string {
+	var services, functions []string
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		if _, ok := sc.Functions[function]; ok {
+			services = append(services, r)
+		}
+		functions = append(functions, sortedKeys(sc.Functions)...)
+	}
+
+	switch len(services) {
+	case 0:
+		sort.Strings(functions)
+		log.Fatalf("Function %s doesn't exist, available are: %s", function, strings.Join(functions, ", "))
+	case 1:
+		return services[0]
+	}
+	log.Fatalf("Function %s exists in %s, select one with -l", function, strings.Join(services, ", "))
+	return ""
+}
+
+// serviceState hashes serverless.yml with the referenced files and the sources and packages of the functions of a service
+func (m MUGConfig) serviceState(r string, sc ServerlessConfig, cache map[string]string, profile string) serviceState {
+	dir := filepath.Join(m.ProjectPath, "functions", r)
+	s := serviceState{Functions: map[string]string{}, Profile: profile}
+
+	config, err := ReadFile(filepath.Join(dir, "serverless.yml"))
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+	h := sha256.New()
+	h.Write(config)
+	for _, ref := range fileReference.FindAllSubmatch(config, -1) {
+		f := strings.TrimSpace(string(ref[1]))
+		data, err := ReadFile(filepath.Join(dir, f))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(h, f)
+		h.Write(data)
+	}
+	s.Config = hex.EncodeToString(h.Sum(nil))
+
+	for _, fn := range sortedKeys(sc.Functions) {
+		handler := strings.TrimPrefix(sc.Functions[fn].Handler, "bin/")
+		binary := filepath.Join("functions", r, "bin", handler)
+		if fnRuntime, _ := sc.FunctionRuntime(fn); IsProvidedRuntime(fnRuntime) {
+			binary = filepath.Join(binary, bootstrap)
+		}
+
+		// a missing package, e.g. during a dry run, is hashed as empty and deployed
+		artifact := sc.Functions[fn].Package.Artifact
+		if len(artifact) == 0 {
+			artifact = "bin/" + handler + ".zip"
+		}
+		zip, err := ReadFile(filepath.Join(dir, artifact))
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+
+		h := sha256.New()
+		fmt.Fprintln(h, cache[binary])
+		h.Write(zip)
+		s.Functions[fn] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	return s
+}
+
+// readDeployState returns the state of the services deployed to the stage
+func (m MUGConfig) readDeployState(stage string) map[string]serviceState {
+	deployed := map[string]serviceState{}
+	data, err := ReadFile(filepath.Join(m.ProjectPath, deployStateFolder, stage+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return deployed
+		}
+		log.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &deployed); err != nil {
+		log.Printf("Ignoring invalid deploy state of %s, all services are deployed: %s", stage, err)
+		return map[string]serviceState{}
+	}
+
+	return deployed
+}
+
+// writeDeployState writes the state of the services deployed to the stage
+func (m MUGConfig) writeDeployState(stage string, deployed map[string]serviceState) {
+	data, err := json.MarshalIndent(deployed, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(m.ProjectPath, deployStateFolder, stage+".json"), data, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDependencyCycle(t *testing.T) {
+	tests := []struct {
+		name      string
+		services  []string
+		dependsOn map[string][]string
+		want      []string
+	}{
+		{"no dependencies", []string{"user"}, map[string][]string{}, nil},
+		{"chain", []string{"note", "user"}, map[string][]string{"note": {"user"}, "user": {"api"}}, nil},
+		{"diamond", []string{"a"}, map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, nil},
+		{"self", []string{"api"}, map[string][]string{"api": {"api"}}, []string{"api", "api"}},
+		{"two services", []string{"api", "user"}, map[string][]string{"api": {"user"}, "user": {"api"}}, []string{"api", "user", "api"}},
+		{"behind a dependency", []string{"note"}, map[string][]string{"note": {"user"}, "user": {"api"}, "api": {"user"}}, []string{"user", "api", "user"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dependencyCycle(tt.services, tt.dependsOn); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("dependencyCycle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderDeployPlan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mug-deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, s := range []string{"api", "note", "topic", "user"} {
+		if err := os.MkdirAll(filepath.Join(dir, "functions", s), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		plan      []string
+		dependsOn map[string][]string
+		want      []string
+		after     map[string][]string
+	}{
+		{
+			name: "independent services keep their order",
+			plan: []string{"note", "user"},
+			want: []string{"note", "user"},
+		},
+		{
+			name:      "dependencies first",
+			plan:      []string{"note", "user", "api"},
+			dependsOn: map[string][]string{"note": {"user"}, "user": {"api"}},
+			want:      []string{"api", "user", "note"},
+			after:     map[string][]string{"note": {"user"}, "user": {"api"}},
+		},
+		{
+			name:      "dependencies outside of the plan are followed",
+			plan:      []string{"note", "api"},
+			dependsOn: map[string][]string{"note": {"user"}, "user": {"api"}},
+			want:      []string{"api", "note"},
+			after:     map[string][]string{"note": {"api"}},
+		},
+		{
+			name:      "several dependencies",
+			plan:      []string{"note", "user", "topic", "api"},
+			dependsOn: map[string][]string{"note": {"user", "topic"}, "topic": {"api"}},
+			want:      []string{"api", "topic", "user", "note"},
+			after:     map[string][]string{"note": {"topic", "user"}, "topic": {"api"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MUGConfig{ProjectPath: dir, Deployment: &DeployConfig{DependsOn: tt.dependsOn}}
+			var plan []*DeployStep
+			for _, s := range tt.plan {
+				plan = append(plan, &DeployStep{Service: s, Action: DeployService})
+			}
+
+			var got []string
+			after := map[string][]string{}
+			for _, s := range m.orderDeployPlan(plan) {
+				got = append(got, s.Service)
+				if len(s.DependsOn) > 0 {
+					after[s.Service] = s.DependsOn
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderDeployPlan() = %v, want %v", got, tt.want)
+			}
+			if tt.after == nil {
+				tt.after = map[string][]string{}
+			}
+			if !reflect.DeepEqual(after, tt.after) {
+				t.Errorf("orderDeployPlan() dependencies = %v, want %v", after, tt.after)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around changes
+const diffContext = 3
+
+// diffLine represents a line of the edit script with its operation (' ', '-' or '+')
+type diffLine struct {
+	op   byte
+	text string
+	a, b int
+}
+
+// UnifiedDiff returns the unified diff between the old and the new content of a file
+func UnifiedDiff(oldName, newName string, old, new []byte) string {
+	lines := editScript(splitLines(string(old)), splitLines(string(new)))
+
+	var changes []int
+	for i, l := range lines {
+		if l.op != ' ' {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	for i := 0; i < len(changes); {
+		// extend the hunk as long as the next change is within the context
+		start := changes[i] - diffContext
+		if start < 0 {
+			start = 0
+		}
+		j := i
+		for j+1 < len(changes) && changes[j+1]-changes[j] <= 2*diffContext {
+			j++
+		}
+		end := changes[j] + diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		writeHunk(&sb, lines[start:end])
+		i = j + 1
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes a hunk with its header
+func writeHunk(sb *strings.Builder, hunk []diffLine) {
+	aCount, bCount := 0, 0
+	for _, l := range hunk {
+		if l.op != '+' {
+			aCount++
+		}
+		if l.op != '-' {
+			bCount++
+		}
+	}
+
+	aStart, bStart := hunk[0].a+1, hunk[0].b+1
+	if aCount == 0 {
+		aStart--
+	}
+	if bCount == 0 {
+		bStart--
+	}
+
+	sb.WriteString(fmt.Sprintf("@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount))
+	for _, l := range hunk {
+		sb.WriteString(string(l.op) + l.text + "\n")
+	}
+}
+
+// editScript returns the lines of both versions with their operation based on the longest common subsequence
+func editScript(a, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	return lines
+}
+
+// splitLines splits the content into lines without trailing newline
+func splitLines(s string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
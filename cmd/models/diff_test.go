@@ -0,0 +1,80 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines 1 to n, replacing the given line numbers
+func numbered(n int, replace map[int]string) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		if r, ok := replace[i]; ok {
+			sb.WriteString(r + "\n")
+			continue
+		}
+		sb.WriteString(strconv.Itoa(i) + "\n")
+	}
+
+	return sb.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "unchanged",
+			old:  numbered(5, nil),
+			new:  numbered(5, nil),
+		},
+		{
+			name: "new file",
+			new:  "a\nb\n",
+			want: "--- a/f\n+++ b/f\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "emptied file",
+			old:  "a\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "changed line with context",
+			old:  numbered(10, nil),
+			new:  numbered(10, map[int]string{5: "five"}),
+			want: "--- a/f\n+++ b/f\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "distant changes in separate hunks",
+			old:  numbered(20, nil),
+			new:  numbered(20, map[int]string{2: "two", 18: "eighteen"}),
+			want: "--- a/f\n+++ b/f\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+		{
+			name: "close changes in one hunk",
+			old:  numbered(12, nil),
+			new:  numbered(12, map[int]string{3: "three", 9: "nine"}),
+			want: "--- a/f\n+++ b/f\n" +
+				"@@ -1,12 +1,12 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n 8\n-9\n+nine\n 10\n 11\n 12\n",
+		},
+		{
+			name: "inserted lines",
+			old:  "a\nc\n",
+			new:  "a\nb\nc\n",
+			want: "--- a/f\n+++ b/f\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a/f", "b/f", []byte(tt.old), []byte(tt.new)); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// dockerTimeout is how long mug waits for the Docker Engine API to answer a ping
+const dockerTimeout = 5 * time.Second
+
+// dockerClient talks to the Docker Engine API, which Podman provides through its compatible socket as well
+type dockerClient struct {
+	http *http.Client
+	host string
+	base string
+}
+
+// containerState is the state of a container as reported by the Engine API
+type containerState struct {
+	// Status is one of created, running, paused, restarting, removing, exited or dead
+	Status   string `json:"Status"`
+	Running  bool   `json:"Running"`
+	ExitCode int    `json:"ExitCode"`
+	Error    string `json:"Error"`
+	Health   *struct {
+		Status string `json:"Status"`
+	} `json:"Health"`
+}
+
+// String returns the readable state, e.g. "running" or "exited with code 1"
+func (s containerState) String() string {
+	switch {
+	case s.Health != nil && s.Running:
+		return s.Status + " (" + s.Health.Status + ")"
+	case s.Status == "exited" || s.Status == "dead":
+		if len(s.Error) > 0 {
+			return fmt.Sprintf("%s with code %d: %s", s.Status, s.ExitCode, s.Error)
+		}
+		return fmt.Sprintf("%s with code %d", s.Status, s.ExitCode)
+	}
+	return s.Status
+}
+
+// container is the inspected container
+type container struct {
+	ID     string         `json:"Id"`
+	Name   string         `json:"Name"`
+	State  containerState `json:"State"`
+	Config struct {
+		Image string `json:"Image"`
+	} `json:"Config"`
+}
+
+// containerConfig is the configuration of a container to create
+type containerConfig struct {
+	Image        string              `json:"Image"`
+	Cmd          []string            `json:"Cmd,omitempty"`
+	User         string              `json:"User,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   struct {
+		Binds        []string                       `json:"Binds,omitempty"`
+		PortBindings map[string][]map[string]string `json:"PortBindings,omitempty"`
+		NetworkMode  string                         `json:"NetworkMode,omitempty"`
+	} `json:"HostConfig"`
+}
+
+// dockerError is an error returned by the Engine API
+type dockerError struct {
+	status  int
+	Message string `json:"message"`
+}
+
+func (e *dockerError) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.status)
+}
+
+// newDockerClient returns a client of the Docker Engine API found at DOCKER_HOST, CONTAINER_HOST
+// or the default sockets of Docker and Podman
+func newDockerClient() (*dockerClient, error) {
+	var hosts []string
+	for _, env := range []string{"DOCKER_HOST", "CONTAINER_HOST"} {
+		if h := os.Getenv(env); len(h) > 0 {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = defaultDockerHosts()
+	}
+
+	var errs []string
+	for _, h := range hosts {
+		c, err := dialDocker(h)
+		if err == nil {
+			return c, nil
+		}
+		errs = append(errs, err.Error())
+	}
+
+	return nil, fmt.Errorf("Docker isn't available, start Docker or Podman or point DOCKER_HOST to its socket:\n  %s", strings.Join(errs, "\n  "))
+}
+
+// defaultDockerHosts returns the sockets of Docker, Docker Desktop and rootless and rootful Podman
+func defaultDockerHosts() []string {
+	hosts := []string{"unix:///var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		hosts = append(hosts, "unix://"+filepath.Join(home, ".docker", "run", "docker.sock"))
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); len(dir) > 0 {
+		hosts = append(hosts, "unix://"+filepath.Join(dir, "podman", "podman.sock"))
+	}
+	hosts = append(hosts, fmt.Sprintf("unix:///run/user/%d/podman/podman.sock", os.Getuid()), "unix:///run/podman/podman.sock")
+
+	return hosts
+}
+
+// dialDocker returns a client of the Engine API at the host, if it answers a ping
+func dialDocker(host string) (*dockerClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", host, err)
+	}
+
+	c := &dockerClient{host: host}
+	switch u.Scheme {
+	case "unix":
+		if _, err := os.Stat(u.Path); err != nil {
+			return nil, fmt.Errorf("%s: no socket", host)
+		}
+		c.base = "http://docker"
+		c.http = &http.Client{Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{Timeout: dockerTimeout}).DialContext(ctx, "unix", u.Path)
+			},
+		}}
+	case "tcp", "http":
+		if len(os.Getenv("DOCKER_TLS_VERIFY")) > 0 {
+			return nil, fmt.Errorf("%s: TLS isn't supported", host)
+		}
+		c.base = "http://" + u.Host
+		c.http = &http.Client{}
+	default:
+		return nil, fmt.Errorf("%s: unsupported scheme %s", host, u.Scheme)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dockerTimeout)
+	defer cancel()
+	req, _ := http.NewRequest(http.MethodGet, c.base+"/_ping", nil)
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", host, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: ping failed with status %d", host, resp.StatusCode)
+	}
+
+	return c, nil
+}
+
+// do sends the request to the Engine API and decodes the response into out, if given
+func (c *dockerClient) do(method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.request(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// request sends the request to the Engine API and returns the response, statuses of 400 and above are returned as dockerError
+func (c *dockerClient) request(method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+
+	u := c.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error talking to Docker at %s: %s", c.host, err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		e := &dockerError{status: resp.StatusCode}
+		data, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(data, e) != nil || len(e.Message) == 0 {
+			e.Message = strings.TrimSpace(string(data))
+		}
+		return nil, e
+	}
+
+	return resp, nil
+}
+
+// isNotFound checks whether the error is the Engine API's 404
+func isNotFound(err error) bool {
+	e, ok := err.(*dockerError)
+	return ok && e.status == http.StatusNotFound
+}
+
+// networkExists checks whether the network exists
+func (c *dockerClient) networkExists(name string) (bool, error) {
+	err := c.do(http.MethodGet, "/networks/"+url.PathEscape(name), nil, nil, nil)
+	if isNotFound(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// createNetwork creates a bridge network
+func (c *dockerClient) createNetwork(name string) error {
+	return c.do(http.MethodPost, "/networks/create", nil, map[string]interface{}{"Name": name, "CheckDuplicate": true}, nil)
+}
+
+// inspectContainer returns the container, it is nil if the container doesn't exist
+func (c *dockerClient) inspectContainer(name string) (*container, error) {
+	var ct container
+	err := c.do(http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil, &ct)
+	if isNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ct, nil
+}
+
+// ensureImage pulls the image if it doesn't exist locally
+func (c *dockerClient) ensureImage(image, tag string) error {
+	err := c.do(http.MethodGet, "/images/"+image+":"+tag+"/json", nil, nil, nil)
+	if !isNotFound(err) {
+		return err
+	}
+
+	log.Printf("Pulling %s:%s...", image, tag)
+	resp, err := c.request(http.MethodPost, "/images/create", url.Values{"fromImage": {image}, "tag": {tag}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the progress is streamed as JSON messages, failures are reported in them as well
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Status string `json:"status"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(msg.Error) > 0 {
+			return fmt.Errorf("Error pulling %s:%s: %s", image, tag, msg.Error)
+		}
+	}
+}
+
+// createContainer creates the container with the name
+func (c *dockerClient) createContainer(name string, config containerConfig) error {
+	return c.do(http.MethodPost, "/containers/create", url.Values{"name": {name}}, config, nil)
+}
+
+// startContainer starts the container, starting a running container is no error
+func (c *dockerClient) startContainer(name string) error {
+	return c.do(http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil, nil)
+}
+
+// removeContainer stops and removes the container
+func (c *dockerClient) removeContainer(name string) error {
+	return c.do(http.MethodDelete, "/containers/"+url.PathEscape(name), url.Values{"force": {"true"}}, nil, nil)
+}
+
+// containerLogs returns the last lines of the output of the container
+func (c *dockerClient) containerLogs(name string, lines int) (string, error) {
+	resp, err := c.request(http.MethodGet, "/containers/"+url.PathEscape(name)+"/logs", url.Values{
+		"stdout": {"true"},
+		"stderr": {"true"},
+		"tail":   {fmt.Sprint(lines)},
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return demuxLogs(data), nil
+}
+
+// demuxLogs strips the headers of the multiplexed stdout and stderr streams of containers without a TTY
+func demuxLogs(data []byte) string {
+	var out bytes.Buffer
+	r := bufio.NewReader(bytes.NewReader(data))
+	for {
+		header := make([]byte, 8)
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		// the output isn't multiplexed, e.g. if the container has a TTY
+		if header[0] > 2 || header[1] != 0 || header[2] != 0 || header[3] != 0 {
+			return string(data)
+		}
+		if _, err := io.CopyN(&out, r, int64(binary.BigEndian.Uint32(header[4:]))); err != nil {
+			break
+		}
+	}
+
+	return out.String()
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEngine is a minimal Docker Engine API knowing a single container and image
+func fakeEngine() http.Handler {
+	mux := http.NewServeMux()
+	notFound := func(w http.ResponseWriter, what string) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such ` + what + `"}`))
+	}
+
+	mux.HandleFunc("/_ping", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) })
+	mux.HandleFunc("/containers/dynamodb/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"abc","Name":"/dynamodb","State":{"Status":"exited","ExitCode":137},"Config":{"Image":"amazon/dynamodb-local:latest"}}`))
+	})
+	mux.HandleFunc("/containers/missing/json", func(w http.ResponseWriter, r *http.Request) { notFound(w, "container: missing") })
+	mux.HandleFunc("/images/amazon/dynamodb-local:latest/json", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{}`)) })
+	mux.HandleFunc("/images/", func(w http.ResponseWriter, r *http.Request) { notFound(w, "image") })
+	mux.HandleFunc("/images/create", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("tag") == "broken" {
+			w.Write([]byte(`{"status":"Pulling from amazon/dynamodb-local"}` + "\n" + `{"error":"manifest unknown"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"Pulling from amazon/dynamodb-local"}` + "\n" + `{"status":"Downloaded newer image"}`))
+	})
+	mux.HandleFunc("/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "dynamodb" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Conflict. The container name \"/dynamodb\" is already in use"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"def"}`))
+	})
+	mux.HandleFunc("/networks/lambda-local", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{}`)) })
+	mux.HandleFunc("/networks/", func(w http.ResponseWriter, r *http.Request) { notFound(w, "network") })
+
+	return mux
+}
+
+func TestDialDocker(t *testing.T) {
+	server := httptest.NewServer(fakeEngine())
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "mug-docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skip("unix sockets are not available:", err)
+	}
+	unixServer := httptest.NewUnstartedServer(fakeEngine())
+	unixServer.Listener.Close()
+	unixServer.Listener = l
+	unixServer.Start()
+	defer unixServer.Close()
+
+	tests := []struct {
+		host    string
+		wantErr string
+	}{
+		{host: "tcp://" + server.Listener.Addr().String()},
+		{host: "unix://" + socket},
+		{host: "unix://" + filepath.Join(dir, "missing.sock"), wantErr: "no socket"},
+		{host: "npipe:////./pipe/docker_engine", wantErr: "unsupported scheme npipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			c, err := dialDocker(tt.host)
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("dialDocker() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ct, err := c.inspectContainer("dynamodb")
+			if err != nil || ct.State.String() != "exited with code 137" {
+				t.Errorf("inspectContainer() = %+v, %v", ct, err)
+			}
+		})
+	}
+}
+
+func TestDockerClient(t *testing.T) {
+	server := httptest.NewServer(fakeEngine())
+	defer server.Close()
+	c, err := dialDocker("tcp://" + server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ct, err := c.inspectContainer("missing"); ct != nil || err != nil {
+		t.Errorf("inspectContainer() of a missing container = %v, %v", ct, err)
+	}
+	if ok, err := c.networkExists("lambda-local"); !ok || err != nil {
+		t.Errorf("networkExists(lambda-local) = %v, %v", ok, err)
+	}
+	if ok, err := c.networkExists("other"); ok || err != nil {
+		t.Errorf("networkExists(other) = %v, %v", ok, err)
+	}
+
+	tests := []struct {
+		tag     string
+		wantErr string
+	}{
+		{tag: "latest"},
+		{tag: "2.0.0"},
+		{tag: "broken", wantErr: "Error pulling amazon/dynamodb-local:broken: manifest unknown"},
+	}
+	for _, tt := range tests {
+		err := c.ensureImage("amazon/dynamodb-local", tt.tag)
+		if (err != nil || len(tt.wantErr) > 0) && (err == nil || err.Error() != tt.wantErr) {
+			t.Errorf("ensureImage(%s) error = %v, want %q", tt.tag, err, tt.wantErr)
+		}
+	}
+
+	if err := c.createContainer("blog-dynamodb", containerConfig{Image: "amazon/dynamodb-local:latest"}); err != nil {
+		t.Errorf("createContainer() error = %v", err)
+	}
+	err = c.createContainer("dynamodb", containerConfig{Image: "amazon/dynamodb-local:latest"})
+	if e, ok := err.(*dockerError); !ok || e.status != http.StatusConflict || !strings.Contains(e.Message, "already in use") {
+		t.Errorf("createContainer() of an existing container error = %v", err)
+	}
+}
+
+func TestContainerStateString(t *testing.T) {
+	healthy := containerState{Status: "running", Running: true}
+	healthy.Health = &struct {
+		Status string `json:"Status"`
+	}{Status: "healthy"}
+
+	tests := []struct {
+		state containerState
+		want  string
+	}{
+		{containerState{Status: "running", Running: true}, "running"},
+		{healthy, "running (healthy)"},
+		{containerState{Status: "exited", ExitCode: 1}, "exited with code 1"},
+		{containerState{Status: "dead", ExitCode: 128, Error: "port is already allocated"}, "dead with code 128: port is already allocated"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestDemuxLogs(t *testing.T) {
+	frame := func(stream byte, s string) []byte {
+		header := make([]byte, 8)
+		header[0] = stream
+		binary.BigEndian.PutUint32(header[4:], uint32(len(s)))
+		return append(header, s...)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"multiplexed", append(frame(1, "Initializing DynamoDB Local\n"), frame(2, "port in use\n")...), "Initializing DynamoDB Local\nport in use\n"},
+		{"tty", []byte("Initializing DynamoDB Local\n"), "Initializing DynamoDB Local\n"},
+		{"empty", nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := demuxLogs(tt.data); got != tt.want {
+			t.Errorf("%s: demuxLogs() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
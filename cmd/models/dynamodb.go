@@ -0,0 +1,325 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+const (
+	// dynamoDBPort is the port DynamoDB listens on inside of the container
+	dynamoDBPort = 8000
+	// dynamoDBTimeout is how long mug waits for a started local DynamoDB to accept requests
+	dynamoDBTimeout = 30 * time.Second
+)
+
+// LocalDynamoDB configures the local DynamoDB container of a project in mug.config.json, missing values use the defaults
+type LocalDynamoDB struct {
+	// Container is the name of the container, give each project its own to run them side by side
+	Container string `json:",omitempty"`
+	Image     string `json:",omitempty"`
+	Tag       string `json:",omitempty"`
+	// Port is the port on the host the container is published on
+	Port int `json:",omitempty"`
+	// Persistent keeps the data in DataDir, otherwise it's kept in memory and lost when the container stops
+	Persistent bool   `json:",omitempty"`
+	DataDir    string `json:",omitempty"`
+	// Network is the docker network shared with the aws-sam-cli containers
+	Network string `json:",omitempty"`
+}
+
+// DynamoDB returns the configuration of the local DynamoDB with the defaults for missing values
+func (m MUGConfig) DynamoDB() LocalDynamoDB {
+	d := LocalDynamoDB{}
+	if m.LocalDynamoDB != nil {
+		d = *m.LocalDynamoDB
+	}
+
+	if len(d.Container) == 0 {
+		d.Container = "dynamodb"
+	}
+	if len(d.Image) == 0 {
+		d.Image = "amazon/dynamodb-local"
+	}
+	if len(d.Tag) == 0 {
+		d.Tag = "latest"
+	}
+	if d.Port == 0 {
+		d.Port = dynamoDBPort
+	}
+	if len(d.DataDir) == 0 {
+		d.DataDir = filepath.Join(".mug", "dynamodb")
+	}
+	if !filepath.IsAbs(d.DataDir) {
+		d.DataDir = filepath.Join(m.ProjectPath, d.DataDir)
+	}
+	if len(d.Network) == 0 {
+		d.Network = "lambda-local"
+	}
+
+	return d
+}
+
+// Endpoint returns the endpoint of the local DynamoDB on the host
+func (d LocalDynamoDB) Endpoint() string {
+	return fmt.Sprintf("http://localhost:%d", d.Port)
+}
+
+// NetworkEndpoint returns the endpoint of the local DynamoDB inside of the docker network
+func (d LocalDynamoDB) NetworkEndpoint() string {
+	return fmt.Sprintf("http://%s:%d", d.Container, dynamoDBPort)
+}
+
+// Storage returns the description of where the data is kept
+func (d LocalDynamoDB) Storage() string {
+	if d.Persistent {
+		return "persistent in " + d.DataDir
+	}
+	return "in memory"
+}
+
+// containerConfig returns the configuration of the container
+func (d LocalDynamoDB) containerConfig() containerConfig {
+	port := fmt.Sprintf("%d/tcp", dynamoDBPort)
+	c := containerConfig{
+		Image: d.Image + ":" + d.Tag,
+		// share the database between the credentials and regions of mug and the functions
+		Cmd:          []string{"-jar", "DynamoDBLocal.jar", "-sharedDb"},
+		ExposedPorts: map[string]struct{}{port: {}},
+	}
+	c.HostConfig.PortBindings = map[string][]map[string]string{port: {{"HostPort": strconv.Itoa(d.Port)}}}
+	c.HostConfig.NetworkMode = d.Network
+
+	if !d.Persistent {
+		c.Cmd = append(c.Cmd, "-inMemory")
+		return c
+	}
+
+	c.Cmd = append(c.Cmd, "-dbPath", "./data")
+	c.HostConfig.Binds = []string{d.DataDir + ":/home/dynamodblocal/data"}
+	// write the data as the current user, so it can be removed without root
+	if runtime.GOOS != "windows" {
+		c.User = fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	}
+
+	return c
+}
+
+// CreateLambdaNetwork spins up a lambda network for dynamodb and AWS SAM to interact with one another
+func (m MUGConfig) CreateLambdaNetwork() {
+	network := m.DynamoDB().Network
+	if DryRun {
+		Record("docker network create %s (if missing)", network)
+		return
+	}
+
+	// check if network exists
+	c := docker()
+	exists, err := c.networkExists(network)
+	if err != nil {
+		log.Fatalf("Error inspecting docker network %s: %s", network, err)
+	}
+	// create network if it doesn't exist
+	if exists {
+		log.Printf("Docker network %s already exists, skipping creation...", network)
+		return
+	}
+	log.Printf("Creating %s docker network", network)
+	if err := c.createNetwork(network); err != nil {
+		log.Fatalf("Error creating docker network %s: %s", network, err)
+	}
+}
+
+// StartLocalDynamoDB spins up the local DynamoDB container and waits until it accepts requests
+func (m MUGConfig) StartLocalDynamoDB() {
+	d := m.DynamoDB()
+	if DryRun {
+		Record("docker run %s:%s as %s on port %d (if not running)", d.Image, d.Tag, d.Container, d.Port)
+		return
+	}
+
+	c := docker()
+	ct, err := c.inspectContainer(d.Container)
+	if err != nil {
+		log.Fatalf("Error inspecting container %s: %s", d.Container, err)
+	}
+
+	switch {
+	case ct == nil:
+		// create container if it doesn't exist already
+		log.Printf("Starting %s (%s) on port %d...", d.Container, d.Storage(), d.Port)
+		if d.Persistent {
+			// the data folder is mounted into the container and belongs to it rather than to the project files
+			if err := os.MkdirAll(d.DataDir, 0755); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := c.ensureImage(d.Image, d.Tag); err != nil {
+			log.Fatal(err)
+		}
+		if err := c.createContainer(d.Container, d.containerConfig()); err != nil {
+			log.Fatalf("Error creating container %s: %s", d.Container, err)
+		}
+		if err := c.startContainer(d.Container); err != nil {
+			log.Fatalf("Error starting container %s: %s", d.Container, err)
+		}
+	case !ct.State.Running:
+		log.Printf("Restarting %s container (%s)...", d.Container, ct.State)
+		if err := c.startContainer(d.Container); err != nil {
+			log.Fatalf("Error starting container %s: %s", d.Container, err)
+		}
+	}
+	if ct != nil && ct.Config.Image != d.Image+":"+d.Tag {
+		log.Printf("Container %s runs %s instead of %s:%s, recreate it with 'mug local reset'", d.Container, ct.Config.Image, d.Image, d.Tag)
+	}
+
+	m.waitForDynamoDB(c)
+	log.Printf("%s running at %s.", d.Container, d.Endpoint())
+}
+
+// waitForDynamoDB waits until the local DynamoDB answers requests and fails with the logs of the container if it stopped
+func (m MUGConfig) waitForDynamoDB(c *dockerClient) {
+	d := m.DynamoDB()
+	svc := m.localDynamoDB()
+	deadline := time.Now().Add(dynamoDBTimeout)
+	for {
+		// poll without the retries of the SDK to notice a stopped container early
+		_, err := svc.ListTablesWithContext(aws.BackgroundContext(), &dynamodb.ListTablesInput{}, func(r *request.Request) {
+			r.Retryer = client.DefaultRetryer{NumMaxRetries: 0}
+		})
+		if err == nil {
+			return
+		}
+
+		ct, cErr := c.inspectContainer(d.Container)
+		if cErr == nil && (ct == nil || !ct.State.Running) {
+			state := "was removed"
+			if ct != nil {
+				state = ct.State.String()
+			}
+			logs, _ := c.containerLogs(d.Container, 20)
+			log.Fatalf("Container %s %s:\n%s", d.Container, state, logs)
+		}
+		if time.Now().After(deadline) {
+			log.Fatalf("Local DynamoDB at %s isn't answering after %s: %s", d.Endpoint(), dynamoDBTimeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// StopLocalDynamoDB removes the local DynamoDB container, persistent data is kept in the data folder
+func (m MUGConfig) StopLocalDynamoDB() {
+	d := m.DynamoDB()
+	if DryRun {
+		Record("docker rm -f %s (if exists)", d.Container)
+		return
+	}
+
+	c := docker()
+	ct, err := c.inspectContainer(d.Container)
+	if err != nil {
+		log.Fatalf("Error inspecting container %s: %s", d.Container, err)
+	}
+	if ct == nil {
+		log.Printf("%s container doesn't exist, nothing to stop", d.Container)
+		return
+	}
+
+	if err := c.removeContainer(d.Container); err != nil {
+		log.Fatalf("Error removing container %s: %s", d.Container, err)
+	}
+	log.Printf("%s stopped and removed", d.Container)
+}
+
+// ResetLocalDynamoDB removes the local DynamoDB container including its persistent data and starts it again
+func (m MUGConfig) ResetLocalDynamoDB() {
+	d := m.DynamoDB()
+	m.StopLocalDynamoDB()
+	if _, err := os.Stat(d.DataDir); d.Persistent && err == nil {
+		// the data is removed right away as the container started next mounts the folder
+		if DryRun {
+			Record("rm -rf %s", d.DataDir)
+		} else if err := os.RemoveAll(d.DataDir); err != nil {
+			log.Fatalf("Error removing %s: %s", d.DataDir, err)
+		}
+		log.Printf("Data in %s removed", d.DataDir)
+	}
+	m.StartLocalDynamoDB()
+}
+
+// PrintLocalDynamoDBStatus prints the state of the local DynamoDB container and its tables
+func (m MUGConfig) PrintLocalDynamoDBStatus() {
+	d := m.DynamoDB()
+	ct, err := docker().inspectContainer(d.Container)
+	if err != nil {
+		log.Fatalf("Error inspecting container %s: %s", d.Container, err)
+	}
+
+	status, image := "not created", d.Image+":"+d.Tag
+	if ct != nil {
+		status = ct.State.String()
+		if ct.Config.Image != image {
+			image += " (container runs " + ct.Config.Image + ", recreate it with 'mug local reset')"
+		}
+	}
+
+	fmt.Printf("Container: %s\n", d.Container)
+	fmt.Printf("Image:     %s\n", image)
+	fmt.Printf("Status:    %s\n", status)
+	fmt.Printf("Endpoint:  %s (%s in network %s)\n", d.Endpoint(), d.NetworkEndpoint(), d.Network)
+	fmt.Printf("Storage:   %s\n", d.Storage())
+	if ct == nil || !ct.State.Running {
+		return
+	}
+
+	svc := m.localDynamoDB()
+	out, err := svc.ListTables(&dynamodb.ListTablesInput{})
+	if err != nil {
+		fmt.Printf("Tables:    not reachable (%s)\n", err)
+		return
+	}
+	if len(out.TableNames) == 0 {
+		fmt.Println("Tables:    none")
+		return
+	}
+
+	fmt.Println("Tables:")
+	for _, t := range out.TableNames {
+		desc, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: t})
+		if err != nil {
+			fmt.Printf("  %s\n", *t)
+			continue
+		}
+		fmt.Printf("  %s (%d items)\n", *t, aws.Int64Value(desc.Table.ItemCount))
+	}
+}
+
+// localDynamoDB returns the client of the local DynamoDB
+func (m MUGConfig) localDynamoDB() *dynamodb.DynamoDB {
+	sess := session.Must(session.NewSession(&aws.Config{
+		Endpoint: aws.String(m.DynamoDB().Endpoint()),
+		Region:   aws.String(m.Region),
+	}))
+
+	return dynamodb.New(sess)
+}
+
+// docker returns the client of the Docker Engine API and exits if Docker isn't available
+func docker() *dockerClient {
+	c, err := newDockerClient()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return c
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestDynamoDB(t *testing.T) {
+	tests := []struct {
+		name  string
+		local *LocalDynamoDB
+		want  LocalDynamoDB
+	}{
+		{
+			name: "defaults",
+			want: LocalDynamoDB{
+				Container: "dynamodb",
+				Image:     "amazon/dynamodb-local",
+				Tag:       "latest",
+				Port:      8000,
+				DataDir:   filepath.Join("/projects/blog", ".mug", "dynamodb"),
+				Network:   "lambda-local",
+			},
+		},
+		{
+			name:  "configured",
+			local: &LocalDynamoDB{Container: "blog-dynamodb", Tag: "2.0.0", Port: 8001, Persistent: true, DataDir: "data"},
+			want: LocalDynamoDB{
+				Container:  "blog-dynamodb",
+				Image:      "amazon/dynamodb-local",
+				Tag:        "2.0.0",
+				Port:       8001,
+				Persistent: true,
+				DataDir:    filepath.Join("/projects/blog", "data"),
+				Network:    "lambda-local",
+			},
+		},
+		{
+			name:  "absolute data directory",
+			local: &LocalDynamoDB{DataDir: "/var/lib/dynamodb"},
+			want: LocalDynamoDB{
+				Container: "dynamodb",
+				Image:     "amazon/dynamodb-local",
+				Tag:       "latest",
+				Port:      8000,
+				DataDir:   "/var/lib/dynamodb",
+				Network:   "lambda-local",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MUGConfig{ProjectPath: "/projects/blog", LocalDynamoDB: tt.local}
+			if got := m.DynamoDB(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DynamoDB() = %+v, want %+v", got, tt.want)
+			}
+			if got, want := m.DynamoDB().Endpoint(), fmt.Sprintf("http://localhost:%d", tt.want.Port); got != want {
+				t.Errorf("Endpoint() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestLocalDynamoDBStorage(t *testing.T) {
+	inMemory := MUGConfig{ProjectPath: "/projects/blog"}.DynamoDB()
+	persistent := MUGConfig{ProjectPath: "/projects/blog", LocalDynamoDB: &LocalDynamoDB{Persistent: true}}.DynamoDB()
+
+	if got := inMemory.Storage(); got != "in memory" {
+		t.Errorf("Storage() = %s, want in memory", got)
+	}
+	if got, want := persistent.Storage(), "persistent in "+filepath.Join("/projects/blog", ".mug", "dynamodb"); got != want {
+		t.Errorf("Storage() = %s, want %s", got, want)
+	}
+	if got := inMemory.NetworkEndpoint(); got != "http://dynamodb:8000" {
+		t.Errorf("NetworkEndpoint() = %s, want http://dynamodb:8000", got)
+	}
+}
+
+func TestContainerConfig(t *testing.T) {
+	inMemory := MUGConfig{ProjectPath: "/projects/blog", LocalDynamoDB: &LocalDynamoDB{Port: 8001}}.DynamoDB()
+	c := inMemory.containerConfig()
+	if want := []string{"-jar", "DynamoDBLocal.jar", "-sharedDb", "-inMemory"}; !reflect.DeepEqual(c.Cmd, want) {
+		t.Errorf("Cmd = %v, want %v", c.Cmd, want)
+	}
+	if want := map[string][]map[string]string{"8000/tcp": {{"HostPort": "8001"}}}; !reflect.DeepEqual(c.HostConfig.PortBindings, want) {
+		t.Errorf("PortBindings = %v, want %v", c.HostConfig.PortBindings, want)
+	}
+	if c.Image != "amazon/dynamodb-local:latest" || c.HostConfig.NetworkMode != "lambda-local" || len(c.HostConfig.Binds) > 0 {
+		t.Errorf("containerConfig() = %+v", c)
+	}
+
+	persistent := MUGConfig{ProjectPath: "/projects/blog", LocalDynamoDB: &LocalDynamoDB{Persistent: true}}.DynamoDB()
+	c = persistent.containerConfig()
+	if want := []string{"-jar", "DynamoDBLocal.jar", "-sharedDb", "-dbPath", "./data"}; !reflect.DeepEqual(c.Cmd, want) {
+		t.Errorf("Cmd = %v, want %v", c.Cmd, want)
+	}
+	if want := []string{persistent.DataDir + ":/home/dynamodblocal/data"}; !reflect.DeepEqual(c.HostConfig.Binds, want) {
+		t.Errorf("Binds = %v, want %v", c.HostConfig.Binds, want)
+	}
+	if want := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()); runtime.GOOS != "windows" && c.User != want {
+		t.Errorf("User = %s, want %s", c.User, want)
+	}
+}
@@ -0,0 +1,281 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/flect"
+)
+
+const (
+	// E2EServe runs the end-to-end tests against the built-in local API of 'mug serve'
+	E2EServe = "serve"
+	// E2ESAM runs the end-to-end tests against the local API of aws-sam-cli
+	E2ESAM = "sam"
+
+	// e2eFolder is the folder of the generated end-to-end tests in the project
+	e2eFolder = "e2e"
+	// e2eHeader starts the generated test files, which are replaced on every run
+	e2eHeader = "// Code generated by mug test --e2e"
+	// apiTimeout is how long mug waits for a started local API to accept connections
+	apiTimeout = time.Minute
+)
+
+// e2eOperations are the handlers of the generated functions of a resource the tests are generated for
+var e2eOperations = []string{"create", "read", "update", "delete", "list"}
+
+// E2ESuite is the data of the end-to-end tests of a resource
+type E2ESuite struct {
+	Resource string
+	Ident    flect.Ident
+	Routes   []E2ERoute
+	Keys     []E2EKey
+	// Sample is the JSON of the created item, Updated the item after the update
+	// and Missing an item with a key which doesn't exist
+	Sample  string
+	Updated string
+	Missing string
+}
+
+// E2ERoute is the http event of the function of an operation
+type E2ERoute struct {
+	Operation string
+	Method    string
+	Path      string
+}
+
+// E2EKey maps a path parameter to the JSON attribute of the item filling it
+type E2EKey struct {
+	Param     string
+	Attribute string
+}
+
+// WriteE2ETests generates the end-to-end tests of the routes of the resources in the list into the e2e folder of the project
+// replacing the tests generated before, and returns the resources with tests. Function groups don't have a model to test.
+func (m MUGConfig) WriteE2ETests(list []string) []string {
+	dir := filepath.Join(m.ProjectPath, e2eFolder)
+
+	// the resources and routes may have changed since the last run, own tests are kept
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range files {
+		if data, err := ReadFile(f); err == nil && bytes.HasPrefix(data, []byte(e2eHeader)) {
+			if err := RemoveAll(f); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	var tested []string
+	sort.Strings(list)
+	for _, r := range list {
+		s, ok := m.e2eSuite(r)
+		if !ok {
+			continue
+		}
+		m.renderE2EFile(r+"_test.go", "resource_test.tmpl", map[string]interface{}{"Suite": s, "Config": m})
+		tested = append(tested, r)
+	}
+	if len(tested) > 0 {
+		m.renderE2EFile("main_test.go", "main_test.tmpl", map[string]interface{}{"Config": m})
+	}
+
+	return tested
+}
+
+// renderE2EFile renders the template of the e2e box to the file in the e2e folder
+func (m MUGConfig) renderE2EFile(fName, tPath string, data map[string]interface{}) {
+	tmpl := LoadProjectTemplate(m.ProjectPath, E2EBox, tPath)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(m.ProjectPath, e2eFolder, fName), buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// e2eSuite returns the end-to-end tests of a resource derived from its model and the http events of its functions
+func (m MUGConfig) e2eSuite(r string) (E2ESuite, bool) {
+	s := E2ESuite{Resource: r}
+	res, ok := m.Resources[r]
+	if !ok {
+		return s, false
+	}
+	model, err := ReadModel(m.ProjectPath, r)
+	if err != nil {
+		log.Printf("Skipping end-to-end tests of %s, its model can't be read: %s", r, err)
+		return s, false
+	}
+	s.Ident = res.Ident
+
+	sc := m.ReadServerlessConfig(r)
+	params := map[string]bool{}
+	for _, op := range e2eOperations {
+		for _, name := range sortedKeys(sc.Functions) {
+			fn := sc.Functions[name]
+			if strings.TrimPrefix(fn.Handler, "bin/") != op {
+				continue
+			}
+			for _, ev := range fn.Events {
+				if ev.HTTP == nil {
+					continue
+				}
+				path := "/" + strings.Trim(ev.HTTP.Path, "/")
+				s.Routes = append(s.Routes, E2ERoute{Operation: op, Method: strings.ToUpper(ev.HTTP.Method), Path: path})
+				for _, segment := range strings.Split(path, "/") {
+					if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+						params[strings.Trim(segment, "{}")] = true
+					}
+				}
+				break
+			}
+			break
+		}
+	}
+	if len(s.Routes) == 0 {
+		return s, false
+	}
+
+	// the path parameters are named like the key attributes of the model
+	keys := map[string]bool{}
+	for _, k := range model.KeySchema {
+		keys[flect.New(k).Underscore().String()] = true
+	}
+	var names []string
+	for p := range params {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for _, p := range names {
+		attribute := flect.New(p).Underscore().String()
+		if a, ok := model.Attributes[p]; ok {
+			attribute = a.Ident.Underscore().String()
+		}
+		s.Keys = append(s.Keys, E2EKey{Param: p, Attribute: attribute})
+		keys[attribute] = true
+	}
+
+	sample := sampleModel(model)
+	updated, missing := map[string]interface{}{}, map[string]interface{}{}
+	for k, v := range sample {
+		updated[k], missing[k] = v, v
+		if keys[k] {
+			missing[k] = missingValue(model.Name, k, v)
+			continue
+		}
+		updated[k] = updatedValue(v)
+	}
+
+	s.Sample, s.Updated, s.Missing = marshalString(sample), marshalString(updated), marshalString(missing)
+
+	return s, true
+}
+
+// updatedValue returns a changed sample value, IDs and timestamps stay the same
+func updatedValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case string:
+		if _, err := time.Parse(time.RFC3339, t); err == nil || len(t) == 36 && strings.Count(t, "-") == 4 {
+			return t
+		}
+		return "updated " + t
+	case int:
+		return t + 1
+	case float64:
+		return t + 1
+	case bool:
+		return !t
+	}
+
+	return v
+}
+
+// missingValue returns a value of a key attribute which doesn't exist
+func missingValue(model, attribute string, v interface{}) interface{} {
+	switch v.(type) {
+	case int, float64:
+		return 404404
+	}
+
+	return exampleUUID(model, attribute, "missing")
+}
+
+// marshalString returns the JSON of the value as a string
+func marshalString(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return string(data)
+}
+
+// Start serves the local API on a free port of localhost in the background and returns its URL and a function stopping it
+func (a *LocalAPI) Start() (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	go http.Serve(l, a)
+
+	return "http://" + l.Addr().String(), func() {
+		l.Close()
+		a.Stop()
+	}
+}
+
+// FreePort returns a free port of localhost
+func FreePort() string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer l.Close()
+
+	return fmt.Sprint(l.Addr().(*net.TCPAddr).Port)
+}
+
+// WaitForAPI waits until the local API at the address accepts connections
+func WaitForAPI(addr string) error {
+	deadline := time.Now().Add(apiTimeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Local API isn't accepting connections at %s after %s: %s", addr, apiTimeout, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// RunE2ETests runs the generated end-to-end tests and the own tests in the e2e folder against the API at the URL
+func (m MUGConfig) RunE2ETests(url string, env []string) error {
+	args := []string{"test", "-count=1", "./" + e2eFolder + "/..."}
+	env = append(env, "API_URL="+url)
+	if DryRun {
+		Record("%s go %s", strings.Join(env, " "), strings.Join(args, " "))
+		return nil
+	}
+	cmd := exec.Command("go", args...)
+	cmd.Dir = m.ProjectPath
+	cmd.Env = append(os.Environ(), env...)
+
+	return execCmd(cmd)
+}
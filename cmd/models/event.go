@@ -0,0 +1,525 @@
+package models
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EventTypes are the trigger types sample events can be generated for
+var EventTypes = []string{"apigw", "sqs", "sns", "s3", "schedule", "stream"}
+
+const (
+	// eventTime is the fixed time of the sample events, so generating them again doesn't change the fixtures
+	eventTime = "2019-01-01T12:00:00.000Z"
+	// eventEpoch is eventTime in seconds
+	eventEpoch = 1546344000
+	// eventAccount is the account id used in the ARNs of the sample events
+	eventAccount = "000000000000"
+)
+
+// FunctionName returns the name of a function of the resource/ function group given by its name,
+// its name without the resource suffix or its handler
+func (s ServerlessConfig) FunctionName(r, name string) (string, bool) {
+	for _, n := range []string{name, GetFuncName(r, name)} {
+		if _, ok := s.Functions[n]; ok {
+			return n, true
+		}
+	}
+	for _, n := range sortedKeys(s.Functions) {
+		if strings.TrimPrefix(s.Functions[n].Handler, "bin/") == name {
+			return n, true
+		}
+	}
+
+	return "", false
+}
+
+// EventPath returns the path of the event fixture of the type for a function
+func (m MUGConfig) EventPath(sc ServerlessConfig, r, name, kind string) string {
+	handler := strings.TrimPrefix(sc.Functions[name].Handler, "bin/")
+	return filepath.Join(m.ProjectPath, "functions", r, handler, "events", kind+".json")
+}
+
+// GenerateEvent returns a sample event of the type for a function of a resource/ function group.
+// The triggers of the function configure the sources of the event, the model of a resource fills the bodies.
+func (m MUGConfig) GenerateEvent(sc ServerlessConfig, r, name, kind string) ([]byte, error) {
+	fn, ok := sc.Functions[name]
+	if !ok {
+		return nil, fmt.Errorf("function %s doesn't exist in %s", name, r)
+	}
+
+	var model *Model
+	if _, ok := m.Resources[r]; ok {
+		if md, err := ReadModel(m.ProjectPath, r); err == nil {
+			model = &md
+		}
+	}
+
+	var event interface{}
+	switch kind {
+	case "apigw":
+		event = m.apigwEvent(fn, model)
+	case "sqs":
+		event = m.sqsEvent(sc, fn, model)
+	case "sns":
+		event = m.snsEvent(sc, fn, model)
+	case "s3":
+		event = m.s3Event(sc, fn)
+	case "schedule":
+		event = m.scheduleEvent(name, fn)
+	case "stream":
+		event = m.streamEvent(r, fn, model)
+	default:
+		return nil, fmt.Errorf("unknown event type %s, choose between %s", kind, strings.Join(EventTypes, ", "))
+	}
+
+	return json.MarshalIndent(event, "", "  ")
+}
+
+// WriteEvents writes the event fixtures of the type for all functions of the resource/ function group.
+// Existing fixtures are kept as they may have been customized.
+func (m MUGConfig) WriteEvents(sc ServerlessConfig, r, kind string) {
+	for _, name := range sortedKeys(sc.Functions) {
+		m.WriteEvent(sc, r, name, kind, false)
+	}
+}
+
+// WriteEvent writes the event fixture of the type for a function to its events folder
+// and returns the path, an existing fixture is only replaced if overwrite is set
+func (m MUGConfig) WriteEvent(sc ServerlessConfig, r, name, kind string, overwrite bool) string {
+	path := m.EventPath(sc, r, name, kind)
+	if _, err := ReadFile(path); err == nil && !overwrite {
+		return path
+	}
+
+	data, err := m.GenerateEvent(sc, r, name, kind)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(path, append(data, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	return path
+}
+
+// apigwEvent returns an API Gateway proxy request for the http event of the function
+func (m MUGConfig) apigwEvent(fn *ServerlessFunction, model *Model) proxyRequest {
+	method, path := "GET", strings.TrimPrefix(fn.Handler, "bin/")
+	for _, ev := range fn.Events {
+		if ev.HTTP != nil {
+			method, path = strings.ToUpper(ev.HTTP.Method), strings.Trim(ev.HTTP.Path, "/")
+			break
+		}
+	}
+	if method == "ANY" {
+		method = "GET"
+	}
+
+	sample := map[string]interface{}{}
+	if model != nil {
+		sample = sampleModel(*model)
+	}
+
+	// fill the path parameters with the sample values of the key attributes
+	var params map[string]string
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if !strings.HasPrefix(s, "{") || !strings.HasSuffix(s, "}") {
+			continue
+		}
+		param := strings.TrimSuffix(strings.Trim(s, "{}"), "+")
+		value := "example-" + param
+		if model != nil {
+			if a, ok := model.Attributes[param]; ok {
+				value = fmt.Sprint(sample[a.Ident.Underscore().String()])
+			}
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[param] = value
+		segments[i] = value
+	}
+
+	headers := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+		"Host":         "localhost",
+	}
+	multiHeaders := map[string][]string{}
+	for k, v := range headers {
+		multiHeaders[k] = []string{v}
+	}
+
+	req := proxyRequest{
+		Resource:          "/" + path,
+		Path:              "/" + strings.Join(segments, "/"),
+		HTTPMethod:        method,
+		Headers:           headers,
+		MultiValueHeaders: multiHeaders,
+		PathParameters:    params,
+		RequestContext: proxyRequestContext{
+			AccountID:    eventAccount,
+			ResourceID:   "example",
+			Stage:        "dev",
+			RequestID:    exampleUUID("request", path),
+			ResourcePath: "/" + path,
+			HTTPMethod:   method,
+			APIID:        "example",
+			Identity: map[string]string{
+				"sourceIp":  "127.0.0.1",
+				"userAgent": "mug",
+			},
+			RequestTimeEpoch: eventEpoch * 1000,
+		},
+	}
+	if method == "POST" || method == "PUT" || method == "PATCH" {
+		req.Body = eventBody(sample, map[string]string{"message": "Hello from API Gateway"})
+	}
+
+	return req
+}
+
+// sqsEvent returns an SQS event with a message of the queue the function is subscribed to
+func (m MUGConfig) sqsEvent(sc ServerlessConfig, fn *ServerlessFunction, model *Model) map[string]interface{} {
+	arn := fmt.Sprintf("arn:aws:sqs:%s:%s:%s-queue", m.Region, eventAccount, sc.Service.Name)
+	for _, ev := range fn.Events {
+		if ev.SQS != nil && strings.HasPrefix(ev.SQS.ARN, "arn:") {
+			arn = ev.SQS.ARN
+		}
+	}
+
+	body := eventBody(sampleOf(model), map[string]string{"message": "Hello from SQS"})
+	sum := md5.Sum([]byte(body))
+
+	return records(map[string]interface{}{
+		"messageId":     exampleUUID("sqs", arn),
+		"receiptHandle": "AQEBexample",
+		"body":          body,
+		"attributes": map[string]string{
+			"ApproximateReceiveCount":          "1",
+			"SentTimestamp":                    fmt.Sprint(eventEpoch * 1000),
+			"SenderId":                         eventAccount,
+			"ApproximateFirstReceiveTimestamp": fmt.Sprint(eventEpoch * 1000),
+		},
+		"messageAttributes": map[string]interface{}{},
+		"md5OfBody":         hex.EncodeToString(sum[:]),
+		"eventSource":       "aws:sqs",
+		"eventSourceARN":    arn,
+		"awsRegion":         m.Region,
+	})
+}
+
+// snsEvent returns an SNS notification of the topic the function is subscribed to
+func (m MUGConfig) snsEvent(sc ServerlessConfig, fn *ServerlessFunction, model *Model) map[string]interface{} {
+	topic := sc.Service.Name + "-topic"
+	for _, ev := range fn.Events {
+		if ev.SNS != nil && len(ev.SNS.TopicName) > 0 {
+			topic = ev.SNS.TopicName
+		}
+	}
+	arn := fmt.Sprintf("arn:aws:sns:%s:%s:%s", m.Region, eventAccount, topic)
+
+	return records(map[string]interface{}{
+		"EventVersion":         "1.0",
+		"EventSubscriptionArn": arn + ":" + exampleUUID("subscription", arn),
+		"EventSource":          "aws:sns",
+		"Sns": map[string]interface{}{
+			"SignatureVersion":  "1",
+			"Timestamp":         eventTime,
+			"Signature":         "EXAMPLE",
+			"SigningCertUrl":    "EXAMPLE",
+			"MessageId":         exampleUUID("sns", arn),
+			"Message":           eventBody(sampleOf(model), map[string]string{"message": "Hello from SNS"}),
+			"MessageAttributes": map[string]interface{}{},
+			"Type":              "Notification",
+			"UnsubscribeUrl":    "EXAMPLE",
+			"TopicArn":          arn,
+			"Subject":           "example",
+		},
+	})
+}
+
+// s3Event returns an S3 notification of the bucket and event the function is subscribed to
+func (m MUGConfig) s3Event(sc ServerlessConfig, fn *ServerlessFunction) map[string]interface{} {
+	bucket, name := sc.Service.Name+"-bucket", "ObjectCreated:Put"
+	for _, ev := range fn.Events {
+		if ev.S3 == nil {
+			continue
+		}
+		if len(ev.S3.Bucket) > 0 {
+			bucket = ev.S3.Bucket
+		}
+		if len(ev.S3.Event) > 0 {
+			name = strings.TrimPrefix(ev.S3.Event, "s3:")
+			if strings.HasPrefix(name, "ObjectRemoved") {
+				name = strings.Replace(name, "*", "Delete", 1)
+			}
+			name = strings.Replace(name, "*", "Put", 1)
+		}
+	}
+
+	return records(map[string]interface{}{
+		"eventVersion":      "2.1",
+		"eventSource":       "aws:s3",
+		"awsRegion":         m.Region,
+		"eventTime":         eventTime,
+		"eventName":         name,
+		"userIdentity":      map[string]string{"principalId": "EXAMPLE"},
+		"requestParameters": map[string]string{"sourceIPAddress": "127.0.0.1"},
+		"responseElements": map[string]string{
+			"x-amz-request-id": "EXAMPLE123456789",
+			"x-amz-id-2":       "EXAMPLE123/5678abcdefghijklambdaisawesome/mnopqrstuvwxyzABCDEFGH",
+		},
+		"s3": map[string]interface{}{
+			"s3SchemaVersion": "1.0",
+			"configurationId": "example",
+			"bucket": map[string]interface{}{
+				"name":          bucket,
+				"ownerIdentity": map[string]string{"principalId": "EXAMPLE"},
+				"arn":           "arn:aws:s3:::" + bucket,
+			},
+			"object": map[string]interface{}{
+				"key":       "example/object.json",
+				"size":      1024,
+				"eTag":      "0123456789abcdef0123456789abcdef",
+				"sequencer": "0A1B2C3D4E5F678901",
+			},
+		},
+	})
+}
+
+// scheduleEvent returns a scheduled CloudWatch event of the rule of the function
+func (m MUGConfig) scheduleEvent(name string, fn *ServerlessFunction) map[string]interface{} {
+	rule := name + "-schedule"
+	for _, ev := range fn.Events {
+		if ev.Schedule != nil && len(ev.Schedule.Name) > 0 {
+			rule = ev.Schedule.Name
+		}
+	}
+
+	return map[string]interface{}{
+		"version":     "0",
+		"id":          exampleUUID("schedule", rule),
+		"detail-type": "Scheduled Event",
+		"source":      "aws.events",
+		"account":     eventAccount,
+		"time":        strings.Replace(eventTime, ".000", "", 1),
+		"region":      m.Region,
+		"resources":   []string{fmt.Sprintf("arn:aws:events:%s:%s:rule/%s", m.Region, eventAccount, rule)},
+		"detail":      map[string]interface{}{},
+	}
+}
+
+// streamEvent returns a DynamoDB stream record inserting a sample of the model or a Kinesis record
+// if the function is subscribed to a Kinesis stream
+func (m MUGConfig) streamEvent(r string, fn *ServerlessFunction, model *Model) map[string]interface{} {
+	arn := fmt.Sprintf("arn:aws:dynamodb:%s:%s:table/%s-%s/stream/2019-01-01T00:00:00.000", m.Region, eventAccount, m.ProjectName, r)
+	for _, ev := range fn.Events {
+		if ev.Stream != nil && strings.HasPrefix(ev.Stream.ARN, "arn:") {
+			arn = ev.Stream.ARN
+		}
+	}
+	sample := sampleOf(model)
+
+	if strings.Contains(arn, ":kinesis:") {
+		data := eventBody(sample, map[string]string{"message": "Hello from Kinesis"})
+		return records(map[string]interface{}{
+			"kinesis": map[string]interface{}{
+				"kinesisSchemaVersion":        "1.0",
+				"partitionKey":                "1",
+				"sequenceNumber":              "49590338271490256608559692538361571095921575989136588898",
+				"data":                        base64.StdEncoding.EncodeToString([]byte(data)),
+				"approximateArrivalTimestamp": eventEpoch,
+			},
+			"eventSource":       "aws:kinesis",
+			"eventVersion":      "1.0",
+			"eventID":           "shardId-000000000006:49590338271490256608559692538361571095921575989136588898",
+			"eventName":         "aws:kinesis:record",
+			"invokeIdentityArn": fmt.Sprintf("arn:aws:iam::%s:role/lambda-role", eventAccount),
+			"awsRegion":         m.Region,
+			"eventSourceARN":    arn,
+		})
+	}
+
+	if len(sample) == 0 {
+		sample = map[string]interface{}{"id": exampleUUID("id", r)}
+	}
+	keys := map[string]interface{}{}
+	if model != nil {
+		for _, k := range model.KeySchema {
+			if a, ok := model.Attributes[k]; ok {
+				key := a.Ident.Underscore().String()
+				keys[key] = dynamoValue(sample[key])
+			}
+		}
+	}
+	image := map[string]interface{}{}
+	for k, v := range sample {
+		image[k] = dynamoValue(v)
+	}
+
+	return records(map[string]interface{}{
+		"eventID":      "c4ca4238a0b923820dcc509a6f75849b",
+		"eventName":    "INSERT",
+		"eventVersion": "1.1",
+		"eventSource":  "aws:dynamodb",
+		"awsRegion":    m.Region,
+		"dynamodb": map[string]interface{}{
+			"ApproximateCreationDateTime": eventEpoch,
+			"Keys":                        keys,
+			"NewImage":                    image,
+			"SequenceNumber":              "4421584500000000017450439091",
+			"SizeBytes":                   26,
+			"StreamViewType":              "NEW_AND_OLD_IMAGES",
+		},
+		"eventSourceARN": arn,
+	})
+}
+
+// records wraps the record in the Records list of the event
+func records(record map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"Records": []interface{}{record}}
+}
+
+// sampleOf returns the sample data of the model, if there is one
+func sampleOf(model *Model) map[string]interface{} {
+	if model == nil {
+		return map[string]interface{}{}
+	}
+	return sampleModel(*model)
+}
+
+// eventBody returns the JSON of the sample or the fallback if the sample is empty
+func eventBody(sample map[string]interface{}, fallback map[string]string) string {
+	var data []byte
+	if len(sample) > 0 {
+		data, _ = json.Marshal(sample)
+	} else {
+		data, _ = json.Marshal(fallback)
+	}
+	return string(data)
+}
+
+// sampleModel returns sample data of the model keyed like its JSON representation
+func sampleModel(m Model) map[string]interface{} {
+	sample := map[string]interface{}{}
+	for _, a := range m.Attributes {
+		sample[a.Ident.Underscore().String()] = sampleValue(m.Name, a)
+	}
+	for _, n := range m.Nested {
+		if strings.HasPrefix(n.Type, "[]") {
+			sample[n.Ident.Underscore().String()] = []interface{}{sampleModel(n)}
+		} else {
+			sample[n.Ident.Underscore().String()] = sampleModel(n)
+		}
+	}
+
+	return sample
+}
+
+// sampleValue returns a deterministic sample value of an attribute based on its type and name
+func sampleValue(model string, a Attribute) interface{} {
+	name := strings.ToLower(a.Name)
+	switch a.GoType {
+	case "string":
+		switch {
+		case name == "id" || strings.HasSuffix(name, "id"):
+			return exampleUUID(model, a.Name)
+		case strings.Contains(name, "email"):
+			return "jane.doe@example.com"
+		case strings.Contains(name, "name"):
+			return "Jane Doe"
+		case strings.Contains(name, "url"):
+			return "https://example.com"
+		}
+		return "example " + a.Ident.Underscore().String()
+	case "uuid.UUID":
+		return exampleUUID(model, a.Name)
+	case "time.Time", "*time.Time":
+		return strings.Replace(eventTime, ".000", "", 1)
+	case "bool":
+		return true
+	case "[]string":
+		return []interface{}{"example", "sample"}
+	case "[]byte":
+		return base64.StdEncoding.EncodeToString([]byte("example"))
+	case "map[string]string", "map[string]int", "map[string]interface{}":
+		return map[string]interface{}{"key": "value"}
+	}
+
+	switch awsType(a.GoType) {
+	case "N":
+		if strings.HasPrefix(a.GoType, "float") {
+			return 4.2
+		}
+		return 42
+	case "NS":
+		return []interface{}{1, 2}
+	}
+
+	return nil
+}
+
+// dynamoValue returns the DynamoDB attribute value of a sample value
+func dynamoValue(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case string:
+		return map[string]interface{}{"S": t}
+	case bool:
+		return map[string]interface{}{"BOOL": t}
+	case int, float64:
+		return map[string]interface{}{"N": fmt.Sprint(t)}
+	case []interface{}:
+		if len(t) > 0 {
+			switch t[0].(type) {
+			case string:
+				var ss []string
+				for _, s := range t {
+					ss = append(ss, s.(string))
+				}
+				return map[string]interface{}{"SS": ss}
+			case int, float64:
+				var ns []string
+				for _, n := range t {
+					ns = append(ns, fmt.Sprint(n))
+				}
+				return map[string]interface{}{"NS": ns}
+			}
+		}
+		var l []interface{}
+		for _, e := range t {
+			l = append(l, dynamoValue(e))
+		}
+		return map[string]interface{}{"L": l}
+	case map[string]interface{}:
+		var keys []string
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		m := map[string]interface{}{}
+		for _, k := range keys {
+			m[k] = dynamoValue(t[k])
+		}
+		return map[string]interface{}{"M": m}
+	}
+
+	return map[string]interface{}{"NULL": true}
+}
+
+// exampleUUID returns a UUID derived from the seeds, so sample events are stable
+func exampleUUID(seeds ...string) string {
+	sum := sha1.Sum([]byte(strings.Join(seeds, "/")))
+	s := hex.EncodeToString(sum[:16])
+	return s[0:8] + "-" + s[8:12] + "-5" + s[13:16] + "-" + s[16:20] + "-" + s[20:32]
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// jsonValue returns the value of the dot separated path in the decoded JSON, list elements are addressed by index
+func jsonValue(v interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+func TestGenerateEvent(t *testing.T) {
+	dir := writeProject(t, nil)
+	defer os.RemoveAll(dir)
+
+	model := New("note", false, "title,views:int", KitResource{GenerateID: true}.options())
+	data, err := json.Marshal(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "functions", "note"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions", "note", "note.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1", Resources: map[string]*NewResource{"note": {}}}
+	id := exampleUUID("note", "id")
+
+	sc := ServerlessConfig{
+		Service: Service{Name: "svc-note"},
+		Functions: map[string]*ServerlessFunction{
+			"create_note": {Handler: "bin/create", Events: []Events{{HTTP: &HTTPEvent{Path: "notes", Method: "post"}}}},
+			"read_note":   {Handler: "bin/read", Events: []Events{{HTTP: &HTTPEvent{Path: "notes/{id}", Method: "get"}}}},
+			"queue":       {Handler: "bin/queue", Events: []Events{{SQS: &SQSEvent{ARN: "arn:aws:sqs:eu-central-1:123456789012:notes"}}}},
+			"topic":       {Handler: "bin/topic", Events: []Events{{SNS: &SNSEvent{TopicName: "note-created"}}}},
+			"upload":      {Handler: "bin/upload", Events: []Events{{S3: &S3Event{Bucket: "attachments", Event: "s3:ObjectRemoved:*"}}}},
+			"cleanup":     {Handler: "bin/cleanup", Events: []Events{{Schedule: &ScheduleEvent{Name: "nightly", Rate: "rate(1 day)"}}}},
+			"kinesis":     {Handler: "bin/kinesis", Events: []Events{{Stream: &StreamEvent{ARN: "arn:aws:kinesis:eu-central-1:123456789012:stream/notes"}}}},
+		},
+	}
+
+	tests := []struct {
+		function string
+		kind     string
+		want     map[string]interface{}
+		wantErr  bool
+	}{
+		{function: "create_note", kind: "apigw", want: map[string]interface{}{
+			"httpMethod": "POST",
+			"path":       "/notes",
+			"body":       `{"id":"` + id + `","title":"example title","views":42}`,
+		}},
+		{function: "read_note", kind: "apigw", want: map[string]interface{}{
+			"httpMethod":        "GET",
+			"resource":          "/notes/{id}",
+			"path":              "/notes/" + id,
+			"pathParameters.id": id,
+			"body":              "",
+		}},
+		{function: "queue", kind: "sqs", want: map[string]interface{}{
+			"Records.0.eventSource":    "aws:sqs",
+			"Records.0.eventSourceARN": "arn:aws:sqs:eu-central-1:123456789012:notes",
+			"Records.0.awsRegion":      "eu-central-1",
+		}},
+		{function: "topic", kind: "sns", want: map[string]interface{}{
+			"Records.0.EventSource":  "aws:sns",
+			"Records.0.Sns.TopicArn": "arn:aws:sns:eu-central-1:000000000000:note-created",
+		}},
+		{function: "upload", kind: "s3", want: map[string]interface{}{
+			"Records.0.eventName":                         "ObjectRemoved:Delete",
+			"Records.0.s3.bucket.name":                    "attachments",
+			"Records.0.s3.object.key":                     "example/object.json",
+			"Records.0.s3.bucket.arn":                     "arn:aws:s3:::attachments",
+			"Records.0.requestParameters.sourceIPAddress": "127.0.0.1",
+		}},
+		{function: "cleanup", kind: "schedule", want: map[string]interface{}{
+			"source":      "aws.events",
+			"detail-type": "Scheduled Event",
+			"resources.0": "arn:aws:events:eu-central-1:000000000000:rule/nightly",
+		}},
+		{function: "read_note", kind: "stream", want: map[string]interface{}{
+			"Records.0.eventName":                 "INSERT",
+			"Records.0.dynamodb.Keys.id.S":        id,
+			"Records.0.dynamodb.NewImage.views.N": "42",
+			"Records.0.dynamodb.NewImage.title.S": "example title",
+		}},
+		{function: "kinesis", kind: "stream", want: map[string]interface{}{
+			"Records.0.eventSource":    "aws:kinesis",
+			"Records.0.eventSourceARN": "arn:aws:kinesis:eu-central-1:123456789012:stream/notes",
+		}},
+		{function: "read_note", kind: "alb", wantErr: true},
+		{function: "delete_note", kind: "apigw", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.function+"/"+tt.kind, func(t *testing.T) {
+			data, err := m.GenerateEvent(sc, "note", tt.function, tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// the events are stable, so fixtures don't change when they are generated again
+			again, _ := m.GenerateEvent(sc, "note", tt.function, tt.kind)
+			if string(again) != string(data) {
+				t.Error("GenerateEvent() isn't deterministic")
+			}
+
+			var event interface{}
+			if err := json.Unmarshal(data, &event); err != nil {
+				t.Fatal(err)
+			}
+			for path, want := range tt.want {
+				if got := jsonValue(event, path); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteEvents(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+	vfs = newVirtualFS()
+
+	dir := writeProject(t, map[string]string{"functions/misc/read/events/apigw.json": "{\"customized\": true}\n"})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1"}
+	sc := ServerlessConfig{Functions: map[string]*ServerlessFunction{
+		"hello": {Handler: "bin/hello"},
+		"read":  {Handler: "bin/read"},
+	}}
+
+	m.WriteEvents(sc, "misc", "apigw")
+
+	for path, want := range map[string]string{
+		"functions/misc/hello/events/apigw.json": `"path": "/hello"`,
+		"functions/misc/read/events/apigw.json":  `"customized": true`,
+	} {
+		data, err := ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil || !strings.Contains(string(data), want) {
+			t.Errorf("%s = %s, %v, want it to contain %s", path, data, err, want)
+		}
+	}
+
+	// overwrite replaces the customized fixture
+	m.WriteEvent(sc, "misc", "read", "apigw", true)
+	if data, _ := ReadFile(filepath.Join(dir, "functions", "misc", "read", "events", "apigw.json")); strings.Contains(string(data), "customized") {
+		t.Error("WriteEvent() kept the customized fixture with overwrite")
+	}
+}
@@ -0,0 +1,857 @@
+package models
+
+import (
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// SAMTarget exports an AWS SAM template
+	SAMTarget = "sam"
+	// CloudFormationTarget exports a plain AWS CloudFormation template
+	CloudFormationTarget = "cloudformation"
+
+	// restAPIID is the logical ID of the REST API, matching the one the serverless framework uses
+	restAPIID = "ApiGatewayRestApi"
+)
+
+// CFTemplate represents a deployable AWS CloudFormation or SAM template
+type CFTemplate struct {
+	FormatVersion string                 `yaml:"AWSTemplateFormatVersion"`
+	Transform     string                 `yaml:"Transform,omitempty"`
+	Description   string                 `yaml:"Description,omitempty"`
+	Parameters    map[string]CFParameter `yaml:"Parameters,omitempty"`
+	Resources     map[string]*CFResource `yaml:"Resources"`
+	Outputs       map[string]CFOutput    `yaml:"Outputs,omitempty"`
+}
+
+// CFParameter represents a template parameter
+type CFParameter struct {
+	Type        string `yaml:"Type"`
+	Default     string `yaml:"Default,omitempty"`
+	Description string `yaml:"Description,omitempty"`
+	NoEcho      bool   `yaml:"NoEcho,omitempty"`
+}
+
+// CFResource represents a template resource
+type CFResource struct {
+	Type           string                 `yaml:"Type"`
+	DependsOn      []string               `yaml:"DependsOn,omitempty"`
+	DeletionPolicy string                 `yaml:"DeletionPolicy,omitempty"`
+	Properties     map[string]interface{} `yaml:"Properties,omitempty"`
+}
+
+// CFOutput represents a template output
+type CFOutput struct {
+	Description string      `yaml:"Description,omitempty"`
+	Value       interface{} `yaml:"Value"`
+}
+
+// exporter collects the resources of all resources/ function groups into one template
+type exporter struct {
+	mc     MUGConfig
+	target string
+	t      *CFTemplate
+
+	// REST API state
+	cors        bool
+	authorizers map[string]string
+	paths       map[string]string
+	methods     []string
+}
+
+// ExportTemplate returns the template for the given resources/ function groups and target (sam or cloudformation)
+func (m MUGConfig) ExportTemplate(list []string, target string) *CFTemplate {
+	e := &exporter{
+		mc:     m,
+		target: target,
+		t: &CFTemplate{
+			FormatVersion: "2010-09-09",
+			Description:   m.ProjectName + " exported by mug",
+			Parameters: map[string]CFParameter{
+				"Stage": {Type: "String", Default: "dev", Description: "Deployment stage"},
+			},
+			Resources: map[string]*CFResource{},
+		},
+		authorizers: map[string]string{},
+		paths:       map[string]string{},
+	}
+
+	switch target {
+	case SAMTarget:
+		e.t.Transform = "AWS::Serverless-2016-10-31"
+	case CloudFormationTarget:
+		e.t.Parameters["ArtifactBucket"] = CFParameter{Type: "String", Description: "S3 bucket containing the function zip files (<resource>/<handler>.zip)"}
+	default:
+		log.Fatalf("Unknown export target %s, choose between '%s' and '%s'", target, SAMTarget, CloudFormationTarget)
+	}
+
+	for _, r := range list {
+		e.addService(r)
+	}
+	e.addAPI()
+
+	return e.t
+}
+
+// Write writes the template to the given file
+func (t *CFTemplate) Write(path string) {
+	if err := MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	yml, err := yaml.Marshal(t)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := WriteFile(path, yml, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// addService adds tables, role and functions of a resource/ function group
+func (e *exporter) addService(r string) {
+	sc := e.mc.ReadServerlessConfig(r)
+	res, err := e.mc.NewVariableResolver(sc, r, map[string]string{
+		"stage":  "${Stage}",
+		"region": "${AWS::Region}",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	res.Secrets = e.secretParameter
+
+	for _, name := range sortedKeys(sc.Resources.Resources) {
+		e.addResource(name, sc.Resources.Resources[name], res)
+	}
+
+	role := logicalID(r) + "Role"
+	e.addRole(role, sc, res)
+
+	env := map[string]string{}
+	for k, v := range sc.Provider.Environments {
+		env[k] = resolveExport(res, v)
+	}
+
+	for _, name := range sortedKeys(sc.Functions) {
+		e.addFunction(r, name, sc, env, role, res)
+	}
+}
+
+// addResource adds a resource definition of the serverless.yml
+func (e *exporter) addResource(name string, rd *ResourceDefinition, res *VariableResolver) {
+	cr := &CFResource{
+		Type:           rd.Type,
+		DependsOn:      rd.DependsOn,
+		DeletionPolicy: rd.DeletionPolicy,
+	}
+
+	if rd.Type == "AWS::DynamoDB::Table" {
+		cr.Properties = tableProperties(rd.Properties, res)
+	} else {
+		// pass through any other resource
+		yml, err := yaml.Marshal(rd.Properties)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var props map[string]interface{}
+		if err := yaml.Unmarshal(yml, &props); err != nil {
+			log.Fatal(err)
+		}
+		for k, v := range props {
+			props[k] = resolveExportValue(res, v)
+		}
+		cr.Properties = props
+	}
+
+	e.t.Resources[name] = cr
+}
+
+// tableProperties returns the properties of a DynamoDB table with the attribute names used by the generated code
+func tableProperties(p Properties, res *VariableResolver) map[string]interface{} {
+	keySchema := func(ks []KeySchema) []map[string]string {
+		var out []map[string]string
+		for _, k := range ks {
+			out = append(out, map[string]string{
+				"AttributeName": flect.New(k.AttributeName).Underscore().String(),
+				"KeyType":       k.KeyType,
+			})
+		}
+		return out
+	}
+	throughput := func(t *ProvisionedThroughput) map[string]int64 {
+		if t == nil {
+			return nil
+		}
+		return map[string]int64{
+			"ReadCapacityUnits":  t.ReadCapacityUnits,
+			"WriteCapacityUnits": t.WriteCapacityUnits,
+		}
+	}
+	projection := func(p Projection) map[string]interface{} {
+		out := map[string]interface{}{"ProjectionType": p.ProjectionType}
+		if len(p.NonKeyAttributes) > 0 {
+			out["NonKeyAttributes"] = p.NonKeyAttributes
+		}
+		return out
+	}
+
+	props := map[string]interface{}{
+		"TableName": exportValue(resolveExport(res, p.TableName)),
+		"KeySchema": keySchema(p.KeySchema),
+	}
+
+	var attributes []map[string]string
+	for _, a := range p.AttributeDefinitions {
+		attributes = append(attributes, map[string]string{
+			"AttributeName": flect.New(a.AttributeName).Underscore().String(),
+			"AttributeType": a.AttributeType,
+		})
+	}
+	props["AttributeDefinitions"] = attributes
+
+	if len(p.BillingMode) > 0 {
+		props["BillingMode"] = p.BillingMode
+	}
+	if t := throughput(p.ProvisionedThroughput); t != nil {
+		props["ProvisionedThroughput"] = t
+	}
+
+	var lsi []map[string]interface{}
+	for _, i := range p.LocalSecondaryIndexes {
+		lsi = append(lsi, map[string]interface{}{
+			"IndexName":  i.IndexName,
+			"KeySchema":  keySchema(i.KeySchema),
+			"Projection": projection(i.Projection),
+		})
+	}
+	if len(lsi) > 0 {
+		props["LocalSecondaryIndexes"] = lsi
+	}
+
+	var gsi []map[string]interface{}
+	for _, i := range p.GlobalSecondaryIndexes {
+		idx := map[string]interface{}{
+			"IndexName":  i.IndexName,
+			"KeySchema":  keySchema(i.KeySchema),
+			"Projection": projection(i.Projection),
+		}
+		if t := throughput(i.ProvisionedThroughput); t != nil {
+			idx["ProvisionedThroughput"] = t
+		}
+		gsi = append(gsi, idx)
+	}
+	if len(gsi) > 0 {
+		props["GlobalSecondaryIndexes"] = gsi
+	}
+
+	if len(p.TTLSpecification.AttributeName) > 0 {
+		props["TimeToLiveSpecification"] = map[string]interface{}{
+			"AttributeName": flect.New(p.TTLSpecification.AttributeName).Underscore().String(),
+			"Enabled":       p.TTLSpecification.Enabled,
+		}
+	}
+
+	return props
+}
+
+// addRole adds the IAM role of a resource/ function group with the provider's role statements
+func (e *exporter) addRole(name string, sc ServerlessConfig, res *VariableResolver) {
+	var statements []map[string]interface{}
+	for _, s := range sc.Provider.RoleStatements {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   s.Effect,
+			"Action":   s.Actions,
+			"Resource": resolveExport(res, s.Resource),
+		})
+	}
+
+	props := map[string]interface{}{
+		"AssumeRolePolicyDocument": map[string]interface{}{
+			"Version": "2012-10-17",
+			"Statement": []map[string]interface{}{
+				{
+					"Effect":    "Allow",
+					"Principal": map[string][]string{"Service": {"lambda.amazonaws.com"}},
+					"Action":    []string{"sts:AssumeRole"},
+				},
+			},
+		},
+		"ManagedPolicyArns": []string{"arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole"},
+	}
+	if len(statements) > 0 {
+		props["Policies"] = []map[string]interface{}{
+			{
+				"PolicyName": name + "Policy",
+				"PolicyDocument": map[string]interface{}{
+					"Version":   "2012-10-17",
+					"Statement": statements,
+				},
+			},
+		}
+	}
+
+	e.t.Resources[name] = &CFResource{Type: "AWS::IAM::Role", Properties: props}
+}
+
+// addFunction adds a function with all its events
+func (e *exporter) addFunction(r, name string, sc ServerlessConfig, env map[string]string, role string, res *VariableResolver) {
+	fn := sc.Functions[name]
+	id := logicalID(name) + "Function"
+	handler := strings.TrimPrefix(fn.Handler, "bin/")
+
+	variables := map[string]interface{}{}
+	for k, v := range env {
+		variables[k] = exportValue(v)
+	}
+	for k, v := range fn.Environments {
+		variables[k] = exportValue(resolveExport(res, v))
+	}
+
+	// the functions are deployed with the zips of 'mug package'
+	runtime, arch := sc.FunctionRuntime(name)
+	codeURI := filepath.Join("functions", r, "bin", handler+".zip")
+	s3Key := r + "/" + handler + ".zip"
+	handler = fn.Handler
+	if IsProvidedRuntime(runtime) {
+		handler = bootstrap
+	}
+
+	props := map[string]interface{}{
+		"FunctionName":  exportValue(resolveExport(res, sc.Service.Name) + "-${Stage}-" + name),
+		"Handler":       handler,
+		"Runtime":       runtime,
+		"Architectures": []string{arch},
+		"Role":          getAtt(role, "Arn"),
+	}
+	if len(variables) > 0 {
+		props["Environment"] = map[string]interface{}{"Variables": variables}
+	}
+	if fn.MemorySize > 0 {
+		props["MemorySize"] = fn.MemorySize
+	}
+	if fn.Timeout > 0 {
+		props["Timeout"] = fn.Timeout
+	} else if sc.Provider.Timeout > 0 {
+		props["Timeout"] = sc.Provider.Timeout
+	}
+
+	if e.target == SAMTarget {
+		props["CodeUri"] = codeURI
+		events := map[string]interface{}{}
+		for i, ev := range fn.Events {
+			if t, p := e.samEvent(id, ev, res); len(t) > 0 {
+				events[eventID(t, i)] = map[string]interface{}{"Type": t, "Properties": p}
+			}
+		}
+		if len(events) > 0 {
+			props["Events"] = events
+		}
+		e.t.Resources[id] = &CFResource{Type: "AWS::Serverless::Function", Properties: props}
+		return
+	}
+
+	props["Code"] = map[string]interface{}{
+		"S3Bucket": ref("ArtifactBucket"),
+		"S3Key":    s3Key,
+	}
+	e.t.Resources[id] = &CFResource{Type: "AWS::Lambda::Function", Properties: props}
+	for i, ev := range fn.Events {
+		e.cfEvent(id, i, ev, res)
+	}
+}
+
+// samEvent returns type and properties of the SAM event source
+func (e *exporter) samEvent(fnID string, ev Events, res *VariableResolver) (string, map[string]interface{}) {
+	switch {
+	case ev.HTTP != nil:
+		props := map[string]interface{}{
+			"RestApiId": ref(restAPIID),
+			"Path":      "/" + strings.TrimPrefix(ev.HTTP.Path, "/"),
+			"Method":    ev.HTTP.Method,
+		}
+		if a := e.authorizer(ev.HTTP.Authorizer, res); len(a) > 0 {
+			props["Auth"] = map[string]string{"Authorizer": a}
+		}
+		e.cors = e.cors || ev.HTTP.CORS
+		return "Api", props
+	case ev.Schedule != nil:
+		return "Schedule", map[string]interface{}{"Schedule": ev.Schedule.Rate}
+	case ev.SNS != nil:
+		return "SNS", map[string]interface{}{"Topic": e.topic(ev.SNS, res)}
+	case ev.SQS != nil:
+		return "SQS", batchProps(map[string]interface{}{"Queue": exportValue(resolveExport(res, ev.SQS.ARN))}, ev.SQS.BatchSize)
+	case ev.Stream != nil:
+		t, props := streamProps(ev.Stream, res)
+		return t, props
+	case ev.S3 != nil:
+		return "S3", map[string]interface{}{
+			"Bucket": ref(e.bucket(ev.S3, res)),
+			"Events": ev.S3.Event,
+		}
+	case ev.AlexaSkill != nil:
+		return "AlexaSkill", map[string]interface{}{"SkillId": ev.AlexaSkill.AppID}
+	case ev.IOT != nil:
+		props := map[string]interface{}{"Sql": ev.IOT.SQL}
+		if len(ev.IOT.SQLVersion) > 0 {
+			props["AwsIotSqlVersion"] = ev.IOT.SQLVersion
+		}
+		return "IoTRule", props
+	case ev.CognitoUserPool != nil && !ev.CognitoUserPool.Existing:
+		return "Cognito", map[string]interface{}{
+			"UserPool": ref(e.userPool(ev.CognitoUserPool)),
+			"Trigger":  ev.CognitoUserPool.Trigger,
+		}
+	}
+
+	log.Printf("Skipping unsupported event of %s for SAM export", fnID)
+	return "", nil
+}
+
+// cfEvent adds the CloudFormation resources connecting an event source to a function
+func (e *exporter) cfEvent(fnID string, i int, ev Events, res *VariableResolver) {
+	arn := getAtt(fnID, "Arn")
+	permission := func(principal string, source interface{}) {
+		props := map[string]interface{}{
+			"Action":       "lambda:InvokeFunction",
+			"FunctionName": arn,
+			"Principal":    principal,
+		}
+		if source != nil {
+			props["SourceArn"] = source
+		}
+		e.t.Resources[fnID+eventID("Permission", i)] = &CFResource{Type: "AWS::Lambda::Permission", Properties: props}
+	}
+
+	switch {
+	case ev.HTTP != nil:
+		e.cfMethod(fnID, ev.HTTP, res)
+		e.cors = e.cors || ev.HTTP.CORS
+		permission("apigateway.amazonaws.com", sub("arn:aws:execute-api:${AWS::Region}:${AWS::AccountId}:${"+restAPIID+"}/*"))
+	case ev.Schedule != nil:
+		id := fnID + eventID("Schedule", i)
+		e.t.Resources[id] = &CFResource{Type: "AWS::Events::Rule", Properties: map[string]interface{}{
+			"ScheduleExpression": ev.Schedule.Rate,
+			"State":              "ENABLED",
+			"Targets":            []map[string]interface{}{{"Id": id, "Arn": arn}},
+		}}
+		permission("events.amazonaws.com", getAtt(id, "Arn"))
+	case ev.SNS != nil:
+		topic := e.topic(ev.SNS, res)
+		e.t.Resources[fnID+eventID("Subscription", i)] = &CFResource{Type: "AWS::SNS::Subscription", Properties: map[string]interface{}{
+			"Protocol": "lambda",
+			"Endpoint": arn,
+			"TopicArn": topic,
+		}}
+		permission("sns.amazonaws.com", topic)
+	case ev.SQS != nil:
+		e.t.Resources[fnID+eventID("EventSourceMapping", i)] = &CFResource{Type: "AWS::Lambda::EventSourceMapping", Properties: batchProps(map[string]interface{}{
+			"FunctionName":   arn,
+			"EventSourceArn": exportValue(resolveExport(res, ev.SQS.ARN)),
+		}, ev.SQS.BatchSize)}
+	case ev.Stream != nil:
+		_, props := streamProps(ev.Stream, res)
+		props["FunctionName"] = arn
+		props["EventSourceArn"] = props["Stream"]
+		delete(props, "Stream")
+		e.t.Resources[fnID+eventID("EventSourceMapping", i)] = &CFResource{Type: "AWS::Lambda::EventSourceMapping", Properties: props}
+	case ev.S3 != nil:
+		bucket := e.bucket(ev.S3, res)
+		permission("s3.amazonaws.com", sub("arn:aws:s3:::${"+bucket+"}"))
+		b := e.t.Resources[bucket]
+		b.DependsOn = append(b.DependsOn, fnID+eventID("Permission", i))
+		var configs []map[string]interface{}
+		if nc, ok := b.Properties["NotificationConfiguration"].(map[string]interface{}); ok {
+			configs = nc["LambdaConfigurations"].([]map[string]interface{})
+		}
+		b.Properties["NotificationConfiguration"] = map[string]interface{}{
+			"LambdaConfigurations": append(configs, map[string]interface{}{"Event": ev.S3.Event, "Function": arn}),
+		}
+	case ev.AlexaSkill != nil:
+		permission("alexa-appkit.amazon.com", nil)
+		e.t.Resources[fnID+eventID("Permission", i)].Properties["EventSourceToken"] = ev.AlexaSkill.AppID
+	case ev.IOT != nil:
+		id := fnID + eventID("TopicRule", i)
+		rule := map[string]interface{}{
+			"Sql":          ev.IOT.SQL,
+			"RuleDisabled": false,
+			"Actions":      []map[string]interface{}{{"Lambda": map[string]interface{}{"FunctionArn": arn}}},
+		}
+		if len(ev.IOT.SQLVersion) > 0 {
+			rule["AwsIotSqlVersion"] = ev.IOT.SQLVersion
+		}
+		e.t.Resources[id] = &CFResource{Type: "AWS::IoT::TopicRule", Properties: map[string]interface{}{"TopicRulePayload": rule}}
+		permission("iot.amazonaws.com", getAtt(id, "Arn"))
+	default:
+		log.Printf("Skipping unsupported event of %s for CloudFormation export", fnID)
+	}
+}
+
+// cfMethod adds the API Gateway resources along the path and the method integrating the function
+func (e *exporter) cfMethod(fnID string, ev *HTTPEvent, res *VariableResolver) {
+	parent := e.pathResource(ev.Path)
+	method := strings.ToUpper(ev.Method)
+	id := "ApiGatewayMethod" + logicalID(ev.Path) + logicalID(strings.ToLower(method))
+
+	props := map[string]interface{}{
+		"RestApiId":         ref(restAPIID),
+		"ResourceId":        parent,
+		"HttpMethod":        method,
+		"AuthorizationType": "NONE",
+		"Integration": map[string]interface{}{
+			"Type":                  "AWS_PROXY",
+			"IntegrationHttpMethod": "POST",
+			"Uri":                   sub("arn:aws:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/${" + fnID + ".Arn}/invocations"),
+		},
+	}
+	if a := e.authorizer(ev.Authorizer, res); len(a) > 0 {
+		props["AuthorizerId"] = ref(a)
+		props["AuthorizationType"] = "COGNITO_USER_POOLS"
+		if strings.Contains(e.authorizers[a], ":lambda:") {
+			props["AuthorizationType"] = "CUSTOM"
+		}
+	}
+
+	e.t.Resources[id] = &CFResource{Type: "AWS::ApiGateway::Method", Properties: props}
+	e.methods = append(e.methods, id)
+
+	if ev.CORS {
+		e.corsMethod(ev.Path, parent)
+	}
+}
+
+// pathResource adds the API Gateway resources for every segment of the path and returns the ID of the last one
+func (e *exporter) pathResource(path string) interface{} {
+	parent := interface{}(getAtt(restAPIID, "RootResourceId"))
+	current := ""
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if len(part) == 0 {
+			continue
+		}
+		current += "/" + part
+		id, ok := e.paths[current]
+		if !ok {
+			id = "ApiGatewayResource" + logicalID(current)
+			e.paths[current] = id
+			e.t.Resources[id] = &CFResource{Type: "AWS::ApiGateway::Resource", Properties: map[string]interface{}{
+				"RestApiId": ref(restAPIID),
+				"ParentId":  parent,
+				"PathPart":  part,
+			}}
+		}
+		parent = ref(id)
+	}
+
+	return parent
+}
+
+// corsMethod adds the OPTIONS method answering CORS preflight requests for a path
+func (e *exporter) corsMethod(path string, resource interface{}) {
+	id := "ApiGatewayMethod" + logicalID(path) + "Options"
+	if _, ok := e.t.Resources[id]; ok {
+		return
+	}
+
+	headers := map[string]string{
+		"method.response.header.Access-Control-Allow-Origin":  "'*'",
+		"method.response.header.Access-Control-Allow-Headers": "'Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token'",
+		"method.response.header.Access-Control-Allow-Methods": "'GET,PUT,POST,DELETE,PATCH,OPTIONS'",
+	}
+	allowed := map[string]bool{}
+	for h := range headers {
+		allowed[h] = true
+	}
+
+	e.t.Resources[id] = &CFResource{Type: "AWS::ApiGateway::Method", Properties: map[string]interface{}{
+		"RestApiId":         ref(restAPIID),
+		"ResourceId":        resource,
+		"HttpMethod":        "OPTIONS",
+		"AuthorizationType": "NONE",
+		"Integration": map[string]interface{}{
+			"Type":                 "MOCK",
+			"RequestTemplates":     map[string]string{"application/json": "{statusCode:200}"},
+			"IntegrationResponses": []map[string]interface{}{{"StatusCode": "200", "ResponseParameters": headers}},
+		},
+		"MethodResponses": []map[string]interface{}{{"StatusCode": "200", "ResponseParameters": allowed}},
+	}}
+	e.methods = append(e.methods, id)
+}
+
+// addAPI adds the REST API, its authorizers and deployment if any function has an http event
+func (e *exporter) addAPI() {
+	if e.target == SAMTarget {
+		hasAPI := false
+		for _, r := range e.t.Resources {
+			if events, ok := r.Properties["Events"].(map[string]interface{}); ok {
+				for _, ev := range events {
+					if ev.(map[string]interface{})["Type"] == "Api" {
+						hasAPI = true
+					}
+				}
+			}
+		}
+		if !hasAPI {
+			return
+		}
+
+		props := map[string]interface{}{
+			"Name":      sub(e.mc.ProjectName + "-${Stage}"),
+			"StageName": ref("Stage"),
+		}
+		if e.cors {
+			props["Cors"] = map[string]string{"AllowOrigin": "'*'", "AllowMethods": "'GET,PUT,POST,DELETE,PATCH,OPTIONS'"}
+		}
+		if len(e.authorizers) > 0 {
+			authorizers := map[string]interface{}{}
+			for id, arn := range e.authorizers {
+				if strings.Contains(arn, ":lambda:") {
+					authorizers[id] = map[string]interface{}{"FunctionArn": exportValue(arn)}
+				} else {
+					authorizers[id] = map[string]interface{}{"UserPoolArn": exportValue(arn)}
+				}
+			}
+			props["Auth"] = map[string]interface{}{"Authorizers": authorizers}
+		}
+		e.t.Resources[restAPIID] = &CFResource{Type: "AWS::Serverless::Api", Properties: props}
+	} else {
+		if len(e.methods) == 0 {
+			return
+		}
+
+		e.t.Resources[restAPIID] = &CFResource{Type: "AWS::ApiGateway::RestApi", Properties: map[string]interface{}{
+			"Name": sub(e.mc.ProjectName + "-${Stage}"),
+		}}
+		for id, arn := range e.authorizers {
+			props := map[string]interface{}{
+				"Name":           id,
+				"RestApiId":      ref(restAPIID),
+				"IdentitySource": "method.request.header.Authorization",
+			}
+			if strings.Contains(arn, ":lambda:") {
+				props["Type"] = "TOKEN"
+				props["AuthorizerUri"] = sub("arn:aws:apigateway:${AWS::Region}:lambda:path/2015-03-31/functions/" + arn + "/invocations")
+			} else {
+				props["Type"] = "COGNITO_USER_POOLS"
+				props["ProviderARNs"] = []interface{}{exportValue(arn)}
+			}
+			e.t.Resources[id] = &CFResource{Type: "AWS::ApiGateway::Authorizer", Properties: props}
+		}
+		sort.Strings(e.methods)
+		e.t.Resources["ApiGatewayDeployment"] = &CFResource{
+			Type:      "AWS::ApiGateway::Deployment",
+			DependsOn: e.methods,
+			Properties: map[string]interface{}{
+				"RestApiId": ref(restAPIID),
+				"StageName": ref("Stage"),
+			},
+		}
+	}
+
+	e.t.Outputs = map[string]CFOutput{
+		"ServiceEndpoint": {
+			Description: "URL of the service endpoint",
+			Value:       sub("https://${" + restAPIID + "}.execute-api.${AWS::Region}.amazonaws.com/${Stage}"),
+		},
+	}
+}
+
+// authorizer registers the authorizer and returns its logical ID
+func (e *exporter) authorizer(a *Authorizer, res *VariableResolver) string {
+	if a == nil {
+		return ""
+	}
+
+	arn := resolveExport(res, a.ARN)
+	for id, existing := range e.authorizers {
+		if existing == arn {
+			return id
+		}
+	}
+
+	id := "Authorizer" + logicalID(a.Name)
+	if len(a.Name) == 0 {
+		id = "Authorizer" + strconv.Itoa(len(e.authorizers)+1)
+	}
+	e.authorizers[id] = arn
+
+	return id
+}
+
+// topic returns the ARN of the SNS topic, creating the topic if only a name is given
+func (e *exporter) topic(ev *SNSEvent, res *VariableResolver) interface{} {
+	name := resolveExport(res, ev.TopicName)
+	if strings.HasPrefix(name, "arn:") {
+		return exportValue(name)
+	}
+
+	id := "SNSTopic" + logicalID(name)
+	if _, ok := e.t.Resources[id]; !ok {
+		props := map[string]interface{}{"TopicName": exportValue(name)}
+		if len(ev.DisplayName) > 0 {
+			props["DisplayName"] = ev.DisplayName
+		}
+		e.t.Resources[id] = &CFResource{Type: "AWS::SNS::Topic", Properties: props}
+	}
+
+	return ref(id)
+}
+
+// bucket adds the S3 bucket of an event and returns its logical ID
+func (e *exporter) bucket(ev *S3Event, res *VariableResolver) string {
+	name := resolveExport(res, ev.Bucket)
+	id := "S3Bucket" + logicalID(name)
+	if _, ok := e.t.Resources[id]; !ok {
+		e.t.Resources[id] = &CFResource{Type: "AWS::S3::Bucket", Properties: map[string]interface{}{
+			"BucketName": exportValue(name),
+		}}
+	}
+
+	return id
+}
+
+// userPool adds the cognito user pool of an event and returns its logical ID
+func (e *exporter) userPool(ev *CognitoEvent) string {
+	id := "CognitoUserPool" + logicalID(ev.Pool)
+	if _, ok := e.t.Resources[id]; !ok {
+		e.t.Resources[id] = &CFResource{Type: "AWS::Cognito::UserPool", Properties: map[string]interface{}{
+			"UserPoolName": ev.Pool,
+		}}
+	}
+
+	return id
+}
+
+// secretParameter registers a secret as NoEcho template parameter referenced instead of the ssm parameter
+func (e *exporter) secretParameter(path string) (string, bool) {
+	name := "Secret" + logicalID(path[strings.LastIndex(path, "/")+1:])
+	e.t.Parameters[name] = CFParameter{
+		Type:        "String",
+		NoEcho:      true,
+		Description: "Value of the secret " + path,
+	}
+
+	return "${" + name + "}", true
+}
+
+// streamProps returns the event type and properties for a DynamoDB or Kinesis stream
+func streamProps(ev *StreamEvent, res *VariableResolver) (string, map[string]interface{}) {
+	arn := resolveExport(res, ev.ARN)
+	position := ev.StartingPosition
+	if len(position) == 0 {
+		position = "LATEST"
+	}
+
+	t := "DynamoDB"
+	if strings.Contains(arn, ":kinesis:") {
+		t = "Kinesis"
+	}
+
+	return t, batchProps(map[string]interface{}{
+		"Stream":           exportValue(arn),
+		"StartingPosition": strings.ToUpper(position),
+	}, ev.BatchSize)
+}
+
+// batchProps adds the batch size to the properties if set
+func batchProps(props map[string]interface{}, size int) map[string]interface{} {
+	if size > 0 {
+		props["BatchSize"] = size
+	}
+
+	return props
+}
+
+// resolveExport resolves the serverless variables of a value for the export
+func resolveExport(res *VariableResolver, val string) string {
+	v, err := res.Resolve(val)
+	if err != nil {
+		log.Fatalf("Error resolving %s: %s", val, err)
+	}
+
+	return v
+}
+
+// resolveExportValue resolves the serverless variables of all strings in passed through properties
+func resolveExportValue(res *VariableResolver, val interface{}) interface{} {
+	switch t := val.(type) {
+	case string:
+		return exportValue(resolveExport(res, t))
+	case map[interface{}]interface{}:
+		for k, v := range t {
+			t[k] = resolveExportValue(res, v)
+		}
+	case []interface{}:
+		for i, v := range t {
+			t[i] = resolveExportValue(res, v)
+		}
+	}
+
+	return val
+}
+
+// exportValue wraps values referencing template parameters in Fn::Sub
+func exportValue(val string) interface{} {
+	if strings.Contains(val, "${") {
+		return sub(val)
+	}
+
+	return val
+}
+
+// ref returns the Ref intrinsic function for a logical ID
+func ref(id string) map[string]string {
+	return map[string]string{"Ref": id}
+}
+
+// getAtt returns the Fn::GetAtt intrinsic function for an attribute of a resource
+func getAtt(id, attribute string) map[string][]string {
+	return map[string][]string{"Fn::GetAtt": {id, attribute}}
+}
+
+// sub returns the Fn::Sub intrinsic function for a string
+func sub(s string) map[string]string {
+	return map[string]string{"Fn::Sub": s}
+}
+
+// eventID returns the logical ID of the i-th event of a type
+func eventID(t string, i int) string {
+	return t + strconv.Itoa(i+1)
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// logicalID returns a valid logical ID for the given name
+func logicalID(name string) string {
+	return alphanumeric(flect.New(nonAlphanumeric.ReplaceAllString(name, " ")).Pascalize().String())
+}
+
+// alphanumeric removes all non alphanumeric characters
+func alphanumeric(s string) string {
+	return nonAlphanumeric.ReplaceAllString(s, "")
+}
+
+// sortedKeys returns the sorted keys of a map with string keys
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch t := m.(type) {
+	case map[string]*ResourceDefinition:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	case map[string]*ServerlessFunction:
+		for k := range t {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
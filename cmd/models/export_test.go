@@ -0,0 +1,170 @@
+package models
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// noteService is the serverless.yml of a resource used by the export tests
+const noteService = `service:
+  name: svc-note
+provider:
+  name: aws
+  runtime: provided.al2023
+  stage: ${opt:stage, 'dev'}
+  environment:
+    API_KEY: ${ssm:/blog/${self:provider.stage}/API_KEY}
+    NOTE_TABLE_NAME: svc-notes-${opt:stage, self:provider.stage}
+functions:
+  create_note:
+    handler: bin/create
+    events:
+    - http:
+        path: notes
+        method: post
+        cors: true
+  read_note:
+    handler: bin/read
+    events:
+    - http:
+        path: notes/{id}
+        method: get
+resources:
+  Resources:
+    NoteDynamoDbTable:
+      Type: AWS::DynamoDB::Table
+      Properties:
+        AttributeDefinitions:
+        - AttributeName: id
+          AttributeType: S
+        KeySchema:
+        - AttributeName: id
+          KeyType: HASH
+        TableName: svc-notes-${opt:stage, self:provider.stage}
+`
+
+func TestExportTemplate(t *testing.T) {
+	dir := writeProject(t, map[string]string{"functions/note/serverless.yml": noteService})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1"}
+
+	tests := []struct {
+		target     string
+		transform  string
+		parameters []string
+		resources  map[string]string
+		code       map[string]interface{}
+	}{
+		{
+			target:     SAMTarget,
+			transform:  "AWS::Serverless-2016-10-31",
+			parameters: []string{"SecretAPIKEY", "Stage"},
+			resources: map[string]string{
+				"ApiGatewayRestApi":  "AWS::Serverless::Api",
+				"CreateNoteFunction": "AWS::Serverless::Function",
+				"ReadNoteFunction":   "AWS::Serverless::Function",
+				"NoteDynamoDbTable":  "AWS::DynamoDB::Table",
+				"NoteRole":           "AWS::IAM::Role",
+			},
+			code: map[string]interface{}{"CodeUri": "functions/note/bin/create.zip"},
+		},
+		{
+			target:     CloudFormationTarget,
+			parameters: []string{"ArtifactBucket", "SecretAPIKEY", "Stage"},
+			resources: map[string]string{
+				"ApiGatewayRestApi":             "AWS::ApiGateway::RestApi",
+				"ApiGatewayResourceNotes":       "AWS::ApiGateway::Resource",
+				"ApiGatewayResourceNotesID":     "AWS::ApiGateway::Resource",
+				"ApiGatewayMethodNotesPost":     "AWS::ApiGateway::Method",
+				"ApiGatewayMethodNotesOptions":  "AWS::ApiGateway::Method",
+				"ApiGatewayMethodNotesIDGet":    "AWS::ApiGateway::Method",
+				"ApiGatewayDeployment":          "AWS::ApiGateway::Deployment",
+				"CreateNoteFunction":            "AWS::Lambda::Function",
+				"CreateNoteFunctionPermission1": "AWS::Lambda::Permission",
+				"ReadNoteFunction":              "AWS::Lambda::Function",
+				"ReadNoteFunctionPermission1":   "AWS::Lambda::Permission",
+				"NoteDynamoDbTable":             "AWS::DynamoDB::Table",
+				"NoteRole":                      "AWS::IAM::Role",
+			},
+			code: map[string]interface{}{"Code": map[string]interface{}{
+				"S3Bucket": map[string]string{"Ref": "ArtifactBucket"},
+				"S3Key":    "note/create.zip",
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			tmpl := m.ExportTemplate([]string{"note"}, tt.target)
+
+			if tmpl.Transform != tt.transform {
+				t.Errorf("Transform = %q, want %q", tmpl.Transform, tt.transform)
+			}
+			var parameters []string
+			for name := range tmpl.Parameters {
+				parameters = append(parameters, name)
+			}
+			sort.Strings(parameters)
+			if !reflect.DeepEqual(parameters, tt.parameters) {
+				t.Errorf("Parameters = %v, want %v", parameters, tt.parameters)
+			}
+			if !tmpl.Parameters["SecretAPIKEY"].NoEcho {
+				t.Error("secret parameter is not NoEcho")
+			}
+
+			if len(tmpl.Resources) != len(tt.resources) {
+				t.Errorf("got %d resources, want %d", len(tmpl.Resources), len(tt.resources))
+			}
+			for id, typ := range tt.resources {
+				if r, ok := tmpl.Resources[id]; !ok || r.Type != typ {
+					t.Errorf("resource %s = %v, want type %s", id, r, typ)
+				}
+			}
+
+			fn := tmpl.Resources["CreateNoteFunction"].Properties
+			for k, v := range tt.code {
+				if !reflect.DeepEqual(fn[k], v) {
+					t.Errorf("%s = %v, want %v", k, fn[k], v)
+				}
+			}
+			if fn["Handler"] != bootstrap {
+				t.Errorf("Handler = %v, want %s", fn["Handler"], bootstrap)
+			}
+			variables := fn["Environment"].(map[string]interface{})["Variables"].(map[string]interface{})
+			wantVariables := map[string]interface{}{
+				"API_KEY":         map[string]string{"Fn::Sub": "${SecretAPIKEY}"},
+				"NOTE_TABLE_NAME": map[string]string{"Fn::Sub": "svc-notes-${Stage}"},
+			}
+			if !reflect.DeepEqual(variables, wantVariables) {
+				t.Errorf("Environment = %v, want %v", variables, wantVariables)
+			}
+
+			table := tmpl.Resources["NoteDynamoDbTable"].Properties
+			if want := map[string]string{"Fn::Sub": "svc-notes-${Stage}"}; !reflect.DeepEqual(table["TableName"], want) {
+				t.Errorf("TableName = %v, want %v", table["TableName"], want)
+			}
+		})
+	}
+}
+
+func TestLogicalID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"create_user", "CreateUser"},
+		{"blogPost", "BlogPost"},
+		{"users/{id}", "UsersID"},
+		{"API_KEY", "APIKEY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logicalID(tt.name); got != tt.want {
+				t.Errorf("logicalID(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// DryRun indicates that the staged changes are printed instead of committed and commands are only recorded
+var DryRun bool
+
+// virtualFS stages the files written, the directories created and the paths removed by a command
+// until they are committed to disk, as well as the actions recorded during a dry run
+type virtualFS struct {
+	files   map[string]stagedFile
+	dirs    map[string]os.FileMode
+	removed map[string]bool
+	actions []string
+}
+
+// stagedFile is the content and permission of a file to be written on commit
+type stagedFile struct {
+	data []byte
+	perm os.FileMode
+}
+
+var vfs = newVirtualFS()
+
+func newVirtualFS() *virtualFS {
+	return &virtualFS{
+		files:   map[string]stagedFile{},
+		dirs:    map[string]os.FileMode{},
+		removed: map[string]bool{},
+	}
+}
+
+// WriteFile stages the data to be written to the file on commit
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	vfs.files[filepath.Clean(path)] = stagedFile{data: append([]byte{}, data...), perm: perm}
+	return nil
+}
+
+// AppendFile stages the data to be appended to the file on commit
+func AppendFile(path string, data []byte) error {
+	content, err := ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	return WriteFile(path, append(content, data...), perm)
+}
+
+// ReadFile reads the file taking the staged changes into account
+func ReadFile(path string) ([]byte, error) {
+	path = filepath.Clean(path)
+	if f, ok := vfs.files[path]; ok {
+		return f.data, nil
+	}
+	if vfs.isRemoved(path) {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+
+	return ioutil.ReadFile(path)
+}
+
+// MkdirAll stages the directory to be created on commit
+func MkdirAll(path string, perm os.FileMode) error {
+	vfs.dirs[filepath.Clean(path)] = perm
+	return nil
+}
+
+// RemoveAll stages the path to be removed on commit
+func RemoveAll(path string) error {
+	path = filepath.Clean(path)
+	vfs.removed[path] = true
+	for f := range vfs.files {
+		if isWithin(f, path) {
+			delete(vfs.files, f)
+		}
+	}
+	for d := range vfs.dirs {
+		if isWithin(d, path) {
+			delete(vfs.dirs, d)
+		}
+	}
+
+	return nil
+}
+
+// Record adds an action to the dry run report
+func Record(format string, args ...interface{}) {
+	vfs.actions = append(vfs.actions, fmt.Sprintf(format, args...))
+}
+
+// isRemoved checks whether the path or one of its parents was removed
+func (v *virtualFS) isRemoved(path string) bool {
+	for p := path; ; p = filepath.Dir(p) {
+		if v.removed[p] {
+			return true
+		}
+		if filepath.Dir(p) == p {
+			return false
+		}
+	}
+}
+
+// isWithin checks whether the path equals or is located in the given directory
+func isWithin(path, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// Commit writes the staged changes to disk. New content is written to temporary files next to
+// their destination and renamed into place, replaced and removed paths are kept until all changes
+// succeeded, so any error rolls back to the previous state of the project.
+func Commit() {
+	if DryRun {
+		return
+	}
+
+	t := &transaction{}
+	if err := t.apply(vfs); err != nil {
+		if rbErr := t.rollback(); rbErr != nil {
+			log.Fatalf("Error writing changes: %s (rollback failed: %s)", err, rbErr)
+		}
+		log.Fatalf("Error writing changes, all changes rolled back: %s", err)
+	}
+	t.cleanup()
+
+	vfs = newVirtualFS()
+}
+
+// transaction keeps track of the applied changes to be able to roll them back
+type transaction struct {
+	undo []func() error
+	// backups are the replaced or removed paths deleted once the transaction succeeded
+	backups []string
+	temps   []string
+}
+
+// apply writes the staged changes of the virtual filesystem
+func (t *transaction) apply(v *virtualFS) error {
+	// move the removed paths aside first as new content may be written to them
+	var removed []string
+	for p := range v.removed {
+		removed = append(removed, p)
+	}
+	sort.Strings(removed)
+	for _, p := range removed {
+		if err := t.backup(p); err != nil {
+			return err
+		}
+	}
+
+	var dirs []string
+	for d := range v.dirs {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		if err := t.mkdirAll(d, v.dirs[d]); err != nil {
+			return err
+		}
+	}
+
+	// write the new content next to its destination before replacing anything
+	paths := sortedPaths(v.files)
+	temps := map[string]string{}
+	for _, p := range paths {
+		if err := t.mkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		tmp, err := writeTemp(p, v.files[p])
+		if err != nil {
+			return err
+		}
+		t.temps = append(t.temps, tmp)
+		temps[p] = tmp
+	}
+
+	for _, p := range paths {
+		if err := t.backup(p); err != nil {
+			return err
+		}
+		if err := os.Rename(temps[p], p); err != nil {
+			return err
+		}
+		path := p
+		t.undo = append(t.undo, func() error { return os.Remove(path) })
+	}
+
+	return nil
+}
+
+// backup moves an existing path aside to restore it on rollback
+func (t *transaction) backup(path string) error {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	bak := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.mug-%d.bak", filepath.Base(path), os.Getpid()))
+	if err := os.Rename(path, bak); err != nil {
+		return err
+	}
+	t.backups = append(t.backups, bak)
+	t.undo = append(t.undo, func() error { return os.Rename(bak, path) })
+
+	return nil
+}
+
+// mkdirAll creates the directory and all missing parents removing them again on rollback
+func (t *transaction) mkdirAll(dir string, perm os.FileMode) error {
+	// find the topmost missing directory
+	missing := ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = d
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err
+	}
+	t.undo = append(t.undo, func() error { return os.RemoveAll(missing) })
+
+	return nil
+}
+
+// rollback reverts the applied changes in reverse order
+func (t *transaction) rollback() error {
+	var errs []string
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		if err := t.undo[i](); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, tmp := range t.temps {
+		os.Remove(tmp)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// cleanup removes the backups of a successful transaction
+func (t *transaction) cleanup() {
+	for _, bak := range t.backups {
+		if err := os.RemoveAll(bak); err != nil {
+			log.Printf("Error removing backup %s: %s", bak, err)
+		}
+	}
+}
+
+// writeTemp writes the staged file to a temporary file in the destination directory
+func writeTemp(path string, f stagedFile) (string, error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".mug-")
+	if err != nil {
+		return "", err
+	}
+	defer tmp.Close()
+
+	if _, err := tmp.Write(f.data); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Chmod(f.perm); err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+
+	return tmp.Name(), nil
+}
+
+// sortedPaths returns the paths of the staged files in order
+func sortedPaths(files map[string]stagedFile) []string {
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	return paths
+}
+
+// PrintDryRun prints the unified diff of all staged files and the actions that would have been run
+func PrintDryRun() {
+	wd := GetWorkingDir()
+	rel := func(path string) string {
+		if r, err := filepath.Rel(wd, path); err == nil {
+			return r
+		}
+		return path
+	}
+
+	var removed []string
+	for p := range vfs.removed {
+		if _, err := os.Stat(p); err == nil {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(removed)
+	for _, p := range removed {
+		fmt.Printf("Would remove %s\n", rel(p))
+	}
+
+	for _, p := range sortedPaths(vfs.files) {
+		data := vfs.files[p].data
+		oldName := "a/" + rel(p)
+		old, err := ioutil.ReadFile(p)
+		if err != nil || vfs.isRemoved(p) {
+			if err != nil && !os.IsNotExist(err) {
+				log.Fatal(err)
+			}
+			old, oldName = nil, "/dev/null"
+		}
+
+		if !utf8.Valid(data) || !utf8.Valid(old) {
+			if !bytes.Equal(old, data) {
+				fmt.Printf("Binary file %s would change\n", rel(p))
+			}
+			continue
+		}
+		fmt.Print(UnifiedDiff(oldName, "b/"+rel(p), old, data))
+	}
+
+	if len(vfs.actions) > 0 {
+		fmt.Println("\nWould run:")
+		for _, a := range vfs.actions {
+			fmt.Printf("  %s\n", a)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// snapshot returns the content of all files below the directory, directories are marked with a trailing slash
+func snapshot(t *testing.T, dir string) map[string]string {
+	files := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if info.IsDir() {
+			files[rel+"/"] = ""
+			return nil
+		}
+		data, err := ioutil.ReadFile(path)
+		files[rel] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func TestStaging(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"keep.txt":      "keep",
+		"old/file.txt":  "old",
+		"old/other.txt": "other",
+	})
+	defer os.RemoveAll(dir)
+	defer func() { vfs = newVirtualFS() }()
+	before := snapshot(t, dir)
+
+	WriteFile(filepath.Join(dir, "keep.txt"), []byte("changed"), 0644)
+	WriteFile(filepath.Join(dir, "new", "file.txt"), []byte("new"), 0644)
+	AppendFile(filepath.Join(dir, "new", "file.txt"), []byte(" appended"))
+	RemoveAll(filepath.Join(dir, "old"))
+	MkdirAll(filepath.Join(dir, "empty"), 0755)
+
+	// nothing is written before the commit, but reads see the staged changes
+	if got := snapshot(t, dir); !reflect.DeepEqual(got, before) {
+		t.Errorf("staged changes were written before the commit: %v", got)
+	}
+	tests := []struct {
+		path    string
+		want    string
+		missing bool
+	}{
+		{path: "keep.txt", want: "changed"},
+		{path: "new/file.txt", want: "new appended"},
+		{path: "old/file.txt", missing: true},
+		{path: "old", missing: true},
+	}
+	for _, tt := range tests {
+		data, err := ReadFile(filepath.Join(dir, tt.path))
+		if tt.missing {
+			if !os.IsNotExist(err) {
+				t.Errorf("ReadFile(%s) error = %v, want not exist", tt.path, err)
+			}
+			continue
+		}
+		if err != nil || string(data) != tt.want {
+			t.Errorf("ReadFile(%s) = %q, %v, want %q", tt.path, data, err, tt.want)
+		}
+	}
+
+	Commit()
+	want := map[string]string{
+		"keep.txt":     "changed",
+		"new/":         "",
+		"new/file.txt": "new appended",
+		"empty/":       "",
+	}
+	if got := snapshot(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("after Commit() = %v, want %v", got, want)
+	}
+	if len(vfs.files)+len(vfs.dirs)+len(vfs.removed) > 0 {
+		t.Error("Commit() didn't reset the staged changes")
+	}
+}
+
+func TestRemoveAllDropsStagedFiles(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+	vfs = newVirtualFS()
+
+	WriteFile("/project/functions/note/serverless.yml", []byte("service: note"), 0644)
+	WriteFile("/project/functions/notes/serverless.yml", []byte("service: notes"), 0644)
+	MkdirAll("/project/functions/note/bin", 0755)
+	RemoveAll("/project/functions/note")
+
+	if got := sortedPaths(vfs.files); !reflect.DeepEqual(got, []string{"/project/functions/notes/serverless.yml"}) {
+		t.Errorf("staged files = %v", got)
+	}
+	if len(vfs.dirs) > 0 {
+		t.Errorf("staged directories = %v", vfs.dirs)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"a.txt":        "a",
+		"old/file.txt": "old",
+		"blocker":      "a file where a directory is expected",
+		"z/nested.txt": "nested",
+	})
+	defer os.RemoveAll(dir)
+	before := snapshot(t, dir)
+
+	v := newVirtualFS()
+	v.files[filepath.Join(dir, "a.txt")] = stagedFile{data: []byte("changed"), perm: 0644}
+	v.files[filepath.Join(dir, "new", "deep", "file.txt")] = stagedFile{data: []byte("new"), perm: 0644}
+	v.files[filepath.Join(dir, "blocker", "file.txt")] = stagedFile{data: []byte("fails"), perm: 0644}
+	v.dirs[filepath.Join(dir, "created", "sub")] = 0755
+	v.removed[filepath.Join(dir, "old")] = true
+
+	tr := &transaction{}
+	if err := tr.apply(v); err == nil {
+		t.Fatal("apply() succeeded writing below a file")
+	}
+	if err := tr.rollback(); err != nil {
+		t.Fatalf("rollback() error = %v", err)
+	}
+
+	if got := snapshot(t, dir); !reflect.DeepEqual(got, before) {
+		t.Errorf("rollback() left %v, want %v", got, before)
+	}
+}
@@ -1,7 +1,6 @@
 package models
 
 import (
-	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -9,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/gobuffalo/flect"
 	"github.com/gobuffalo/packr/v2"
@@ -19,8 +19,8 @@ var (
 	ResourceBox = packr.New("resource", "../../templates/resource")
 	// FunctionBox is the packr box containing the function file templates
 	FunctionBox = packr.New("function", "../../templates/function")
-	// MakeBox is the packr box containing the Makefile template
-	MakeBox = packr.New("make", "../../templates/make")
+	// E2EBox is the packr box containing the templates of the end-to-end tests
+	E2EBox = packr.New("e2e", "../../templates/e2e")
 )
 
 // GetWorkingDir get the directory the current command is run out of
@@ -64,31 +64,30 @@ func awsType(s string) string {
 }
 
 // LoadTemplateFromBox loads a *text/template.Template from a packr.Box
+// preferring the project-local override in .mug/templates
 func LoadTemplateFromBox(b *packr.Box, file string) *template.Template {
-	// load string from template
-	ts, err := b.FindString(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return LoadProjectTemplate(GetWorkingDir(), b, file)
+}
 
-	// add FuncMap to remove bin/ for debug target
-	funcMap := template.FuncMap{
-		"TrimBinPrefix": func(s string) string {
-			return strings.TrimPrefix(s, "bin/")
-		},
-		"Pascalize": func(s string) string {
-			return flect.New(s).Pascalize().String()
-		},
-		"Underscore": func(s string) string {
-			return flect.New(s).Underscore().String()
-		},
-		"First": func(s flect.Ident) string {
-			return string(s.String()[0])
-		},
+// LoadProjectTemplate loads a *text/template.Template from the override in .mug/templates
+// of the given project or from the packr.Box if it doesn't exist
+func LoadProjectTemplate(projectPath string, b *packr.Box, file string) *template.Template {
+	// load string from override or template
+	var ts string
+	data, err := ReadFile(TemplateOverridePath(projectPath, b, file))
+	if err == nil {
+		ts = string(data)
+	} else if os.IsNotExist(err) {
+		ts, err = b.FindString(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		log.Fatal(err)
 	}
 
 	// create new template with string
-	t, err := template.New(file).Funcs(funcMap).Parse(ts)
+	t, err := template.New(file).Funcs(templateFuncs()).Parse(ts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -108,6 +107,10 @@ func appendStringIfMissing(slice []string, i string) []string {
 
 // RunCmd will run an OS command with the given arguments
 func RunCmd(name string, args ...string) {
+	if DryRun {
+		Record("%s %s", name, strings.Join(args, " "))
+		return
+	}
 	cmd := exec.Command(name, args...)
 
 	err := execCmd(cmd)
@@ -118,6 +121,10 @@ func RunCmd(name string, args ...string) {
 
 // RunCmdWithEnv will run an OS command with the given arguments and an environment
 func RunCmdWithEnv(envs []string, name string, args ...string) {
+	if DryRun {
+		Record("%s %s %s", strings.Join(envs, " "), name, strings.Join(args, " "))
+		return
+	}
 	cmdEnv := append(os.Environ(), envs...)
 	cmd := exec.Command(name, args...)
 	cmd.Env = cmdEnv
@@ -128,6 +135,43 @@ func RunCmdWithEnv(envs []string, name string, args ...string) {
 	}
 }
 
+// StartCmd starts an OS command with the given arguments without waiting for it to exit
+func StartCmd(name string, args ...string) *exec.Cmd {
+	if DryRun {
+		Record("%s %s", name, strings.Join(args, " "))
+		return nil
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		log.Fatalf("Executing %s failed with %s\n", name, err)
+	}
+
+	return cmd
+}
+
+// StopCmd interrupts a command started with StartCmd and kills it if it doesn't exit within a few seconds
+func StopCmd(cmd *exec.Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+
+	cmd.Process.Signal(os.Interrupt)
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		<-exited
+	}
+}
+
 func execCmd(cmd *exec.Cmd) error {
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -147,7 +191,7 @@ func RemoveFiles(pPath, aName, fName string) {
 	folders := []string{folder, filepath.Join(pPath, "mocks", aName+"Mocks")}
 
 	for _, folder := range folders {
-		err := os.RemoveAll(folder)
+		err := RemoveAll(folder)
 		if err != nil {
 			log.Fatalf("Error deleting function folder %s: %s", folder, err)
 		}
@@ -155,19 +199,7 @@ func RemoveFiles(pPath, aName, fName string) {
 }
 
 func readDataFromFile(path string) ([]byte, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-
-	defer f.Close()
-
-	data, err := ioutil.ReadAll(f)
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
+	return ReadFile(path)
 }
 
 // GetFuncName returns the generated function name for a given resource/ function group name and a functionName
@@ -221,42 +253,3 @@ func GetList(projectPath, wish string) []string {
 
 	return list
 }
-
-// CreateLambdaNetwork spins up a lambda network for dynamodb and AWS SAM to interact with one another
-func CreateLambdaNetwork() {
-	// check if network exists
-	out, err := exec.Command("docker", "network", "ls", "--filter", "name=^lambda-local$", "--format", "{{.Name}}").Output()
-	if err != nil {
-		log.Fatal(err)
-	}
-	// create network if it doesn't exist
-	if len(out) == 0 {
-		log.Println("Creating lambda-local docker network")
-		RunCmd("docker", "network", "create", "lambda-local")
-	} else {
-		log.Println("Docker network lambda-local already exists, skipping creation...")
-	}
-}
-
-// StartLocalDynamoDB spins up the dynamodb-local docker image
-func StartLocalDynamoDB() {
-	// check if container exists
-	out, err := exec.Command("docker", "ps", "-a", "--filter", "network=lambda-local", "--filter", "ancestor=amazon/dynamodb-local", "--filter", "name=dynamodb", "--format", "{{.Status}}").Output()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	if strings.HasPrefix(string(out), "Exited") {
-		log.Println("Restarting dynamodb-local container...")
-		RunCmd("docker", "restart", "dynamodb")
-	}
-
-	// create container if it doesn't exist already
-	if len(out) == 0 {
-		log.Println("Starting dynamodb-local...")
-		wd := GetWorkingDir()
-		RunCmd("docker", "run", "-v", fmt.Sprintf("%s:/dynamodb_local_db", wd), "-p", "8000:8000", "--net=lambda-local", "--name", "dynamodb", "-d", "amazon/dynamodb-local")
-	}
-
-	log.Println("dynamodb-local running.")
-}
@@ -0,0 +1,254 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// kitManifest is the file describing the variables and resources of a starter kit
+const kitManifest = "kit.yml"
+
+// Kit is a starter kit new projects are created from. Its files/ directory is copied into the project,
+// files ending with .tmpl are rendered with the project config and the kit variables.
+type Kit struct {
+	Variables []KitVariable `yaml:"variables"`
+	Resources []KitResource `yaml:"resources"`
+
+	path  string
+	clone bool
+}
+
+// KitVariable is a variable of a starter kit, which is passed with --set or prompted for
+type KitVariable struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+}
+
+// KitResource is a predefined resource of a starter kit described like the flags of 'mug add resource'
+type KitResource struct {
+	Name        string `yaml:"name"`
+	Attributes  string `yaml:"attributes"`
+	KeySchema   string `yaml:"keySchema"`
+	GenerateID  bool   `yaml:"generateID"`
+	AddDates    bool   `yaml:"addDates"`
+	SoftDelete  bool   `yaml:"softDelete"`
+	BillingMode string `yaml:"billingMode"`
+	ReadUnits   int64  `yaml:"readUnits"`
+	WriteUnits  int64  `yaml:"writeUnits"`
+}
+
+// LoadKit loads a starter kit from a local directory or clones it from a git repository.
+// A branch or tag of the repository can be selected with url#ref.
+func LoadKit(src string) *Kit {
+	k := &Kit{path: src}
+
+	if isGitURL(src) {
+		dir, err := ioutil.TempDir("", "mug-kit-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		k.path, k.clone = dir, true
+
+		args := []string{"clone", "--depth", "1"}
+		if i := strings.LastIndex(src, "#"); i > 0 {
+			args = append(args, "--branch", src[i+1:])
+			src = src[:i]
+		}
+		log.Printf("Cloning starter kit %s", src)
+		// clone directly as the kit is needed even during a dry run
+		cmd := exec.Command("git", append(args, src, dir)...)
+		if err := execCmd(cmd); err != nil {
+			k.Close()
+			log.Fatalf("Cloning starter kit %s failed with %s", src, err)
+		}
+	} else if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		log.Fatalf("Starter kit %s is not a directory", src)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(k.path, kitManifest))
+	if err != nil && !os.IsNotExist(err) {
+		k.Close()
+		log.Fatal(err)
+	}
+	if err := yaml.Unmarshal(data, k); err != nil {
+		k.Close()
+		log.Fatalf("Invalid %s of starter kit: %s", kitManifest, err)
+	}
+
+	return k
+}
+
+// Close removes the cloned repository of the kit
+func (k *Kit) Close() {
+	if k.clone {
+		os.RemoveAll(k.path)
+	}
+}
+
+// ResolveVariables returns the kit variables taking the given values and prompting for missing ones
+func (k *Kit) ResolveVariables(set map[string]string, in io.Reader) map[string]string {
+	vars := map[string]string{}
+	for key, val := range set {
+		vars[key] = val
+	}
+
+	r := bufio.NewReader(in)
+	for _, v := range k.Variables {
+		if _, ok := vars[v.Name]; ok {
+			continue
+		}
+
+		desc := v.Description
+		if len(desc) == 0 {
+			desc = v.Name
+		}
+		if len(v.Default) > 0 {
+			fmt.Printf("%s [%s]: ", desc, v.Default)
+		} else {
+			fmt.Printf("%s: ", desc)
+		}
+		val, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			log.Fatal(err)
+		}
+
+		val = strings.TrimSpace(val)
+		if len(val) == 0 {
+			val = v.Default
+		}
+		if len(val) == 0 {
+			log.Fatalf("Variable %s of the starter kit is required (pass it with --set %s=value)", v.Name, v.Name)
+		}
+		vars[v.Name] = val
+	}
+
+	return vars
+}
+
+// Apply copies and renders the kit files into the project and adds the predefined resources
+func (k *Kit) Apply(mc MUGConfig, vars map[string]string) MUGConfig {
+	data := map[string]interface{}{
+		"Config": mc,
+		"Vars":   vars,
+	}
+
+	root := filepath.Join(k.path, "files")
+	if _, err := os.Stat(root); err == nil {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			return k.copyFile(mc.ProjectPath, rel, info, data)
+		})
+		if err != nil {
+			log.Fatalf("Error applying starter kit: %s", err)
+		}
+	}
+
+	for _, r := range k.Resources {
+		m := New(r.Name, false, r.Attributes, r.options())
+		m.Imports = m.GetImports()
+
+		var sc ServerlessConfig
+		mc, sc = m.GetConfigsFor(mc)
+		m.RenderTemplates(mc)
+		m.Write(mc.ProjectPath)
+		sc.Write(mc.ProjectPath, m.Name)
+		mc.WriteEvents(sc, m.Name, "apigw")
+		log.Printf("Added resource %s of starter kit", m.Name)
+	}
+
+	return mc
+}
+
+// copyFile copies a single kit file into the project, file name and .tmpl files are rendered
+func (k *Kit) copyFile(projectPath, rel string, info os.FileInfo, data map[string]interface{}) error {
+	content, err := ioutil.ReadFile(filepath.Join(k.path, "files", rel))
+	if err != nil {
+		return err
+	}
+
+	name, err := renderKitTemplate(rel, filepath.ToSlash(rel), data)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(name, ".tmpl") {
+		name = strings.TrimSuffix(name, ".tmpl")
+		rendered, err := renderKitTemplate(rel, string(content), data)
+		if err != nil {
+			return err
+		}
+		content = []byte(rendered)
+	}
+
+	return WriteFile(filepath.Join(projectPath, filepath.FromSlash(name)), content, info.Mode().Perm())
+}
+
+// renderKitTemplate renders a template of the kit with the template functions
+func renderKitTemplate(name, text string, data map[string]interface{}) (string, error) {
+	t, err := template.New(name).Funcs(templateFuncs()).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// options returns the options of the resource for New with the defaults of 'mug add resource'
+func (r KitResource) options() map[string]interface{} {
+	keySchema, billing := r.KeySchema, r.BillingMode
+	if len(keySchema) == 0 {
+		keySchema = "id:HASH"
+	}
+	if len(billing) == 0 {
+		billing = "provisioned"
+	}
+	capacity := map[string]int64{"read": 1, "write": 1}
+	if r.ReadUnits > 0 {
+		capacity["read"] = r.ReadUnits
+	}
+	if r.WriteUnits > 0 {
+		capacity["write"] = r.WriteUnits
+	}
+
+	return map[string]interface{}{
+		"id":         r.GenerateID,
+		"dates":      r.AddDates,
+		"softDelete": r.SoftDelete,
+		"keySchema":  keySchema,
+		"billing":    billing,
+		"capacity":   capacity,
+	}
+}
+
+// isGitURL checks whether the kit source is a git repository rather than a local directory
+func isGitURL(src string) bool {
+	for _, p := range []string{"https://", "http://", "ssh://", "git://", "git@", "file://"} {
+		if strings.HasPrefix(src, p) {
+			return true
+		}
+	}
+
+	return strings.HasSuffix(strings.SplitN(src, "#", 2)[0], ".git")
+}
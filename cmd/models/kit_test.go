@@ -0,0 +1,126 @@
+package models
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// kitFiles are the files of the starter kit used by the kit tests
+var kitFiles = map[string]string{
+	"kit.yml": `variables:
+- name: owner
+  description: Owner of the project
+- name: license
+  default: MIT
+resources:
+- name: post
+  attributes: title,body
+  generateID: true
+`,
+	"files/README.md.tmpl":                  "# {{ .Config.ProjectName }} by {{ .Vars.owner }} ({{ .Vars.license }})\n",
+	"files/{{ .Vars.owner }}/notes.txt":     "{{ not rendered }}\n",
+	"files/functions/shared/helper.go.tmpl": "package {{ Underscore .Config.ProjectName }}\n",
+}
+
+func TestIsGitURL(t *testing.T) {
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{"https://github.com/crolly/mug-kit", true},
+		{"git@github.com:crolly/mug-kit.git", true},
+		{"ssh://git@example.com/kit", true},
+		{"file:///tmp/kit", true},
+		{"../kits/api.git#v1.0.0", true},
+		{"../kits/api", false},
+		{"/home/user/kit", false},
+	}
+
+	for _, tt := range tests {
+		if got := isGitURL(tt.src); got != tt.want {
+			t.Errorf("isGitURL(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestKitResolveVariables(t *testing.T) {
+	k := &Kit{Variables: []KitVariable{
+		{Name: "owner"},
+		{Name: "license", Default: "MIT"},
+		{Name: "team", Default: "core"},
+	}}
+
+	got := k.ResolveVariables(map[string]string{"team": "platform", "extra": "x"}, strings.NewReader("crolly\n\n"))
+	want := map[string]string{"owner": "crolly", "license": "MIT", "team": "platform", "extra": "x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveVariables() = %v, want %v", got, want)
+	}
+}
+
+func TestKitApply(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+
+	kitDir := writeProject(t, kitFiles)
+	defer os.RemoveAll(kitDir)
+
+	sources := []struct {
+		name string
+		src  func(t *testing.T) string
+	}{
+		{"directory", func(t *testing.T) string { return kitDir }},
+		{"git repository", func(t *testing.T) string {
+			if _, err := exec.LookPath("git"); err != nil {
+				t.Skip("git is not available")
+			}
+			for _, args := range [][]string{
+				{"init", "-q"},
+				{"add", "-A"},
+				{"-c", "user.name=mug", "-c", "user.email=mug@example.com", "commit", "-qm", "kit"},
+			} {
+				cmd := exec.Command("git", args...)
+				cmd.Dir = kitDir
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v: %s", args, out)
+				}
+			}
+			return "file://" + kitDir
+		}},
+	}
+
+	for _, s := range sources {
+		t.Run(s.name, func(t *testing.T) {
+			vfs = newVirtualFS()
+			dir := writeProject(t, nil)
+			defer os.RemoveAll(dir)
+
+			k := LoadKit(s.src(t))
+			defer k.Close()
+			if len(k.Variables) != 2 || len(k.Resources) != 1 {
+				t.Fatalf("LoadKit() = %+v", k)
+			}
+
+			mc := k.Apply(MUGConfig{ProjectName: "blog-api", ProjectPath: dir, Region: "eu-central-1"}, map[string]string{"owner": "crolly", "license": "MIT"})
+
+			for path, want := range map[string]string{
+				"README.md":                  "# blog-api by crolly (MIT)\n",
+				"crolly/notes.txt":           "{{ not rendered }}\n",
+				"functions/shared/helper.go": "package blog_api\n",
+			} {
+				data, err := ReadFile(filepath.Join(dir, path))
+				if err != nil || string(data) != want {
+					t.Errorf("%s = %q, %v, want %q", path, data, err, want)
+				}
+			}
+			if _, ok := mc.Resources["post"]; !ok {
+				t.Errorf("resource post of the kit wasn't added: %v", mc.Resources)
+			}
+			if _, err := ReadFile(filepath.Join(dir, "functions", "post", "serverless.yml")); err != nil {
+				t.Errorf("serverless.yml of resource post: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,524 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/rpc"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// runtimeAPIPrefix is the path prefix of the Lambda runtime API
+	runtimeAPIPrefix = "/2018-06-01/runtime/"
+	// defaultTimeout is the timeout of functions in seconds if none is configured, like in the serverless framework
+	defaultTimeout = 6
+	// defaultMemorySize is the memory of functions in MB if none is configured, like in the serverless framework
+	defaultMemorySize = 1024
+)
+
+// LocalFunction is a function binary built with BuildNative running on the local machine.
+// Functions of the OS-only runtimes are invoked through an emulated Lambda runtime API,
+// functions of the go1.x runtime through their RPC interface.
+type LocalFunction struct {
+	Resource string
+	Name     string
+
+	bin     string
+	env     []string
+	rpc     bool
+	timeout time.Duration
+
+	// mu serializes the invocations like a single Lambda instance
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	exited  chan struct{}
+	api     *runtimeAPI
+	client  *rpc.Client
+	exitErr error
+}
+
+// invocation is a pending invocation of the runtime API
+type invocation struct {
+	id       string
+	payload  []byte
+	deadline time.Time
+	result   chan invocationResult
+}
+
+// invocationResult is the response or error posted by the function
+type invocationResult struct {
+	payload []byte
+	err     error
+}
+
+// FunctionError is an error returned by the handler of a function
+type FunctionError struct {
+	Message string `json:"errorMessage"`
+	Type    string `json:"errorType"`
+}
+
+func (e *FunctionError) Error() string {
+	if len(e.Type) > 0 {
+		return e.Type + ": " + e.Message
+	}
+	return e.Message
+}
+
+// invokeRequest mirrors messages.InvokeRequest of aws-lambda-go used by the RPC interface of the go1.x runtime
+type invokeRequest struct {
+	Payload               []byte
+	RequestId             string
+	XAmznTraceId          string
+	Deadline              invokeDeadline
+	InvokedFunctionArn    string
+	CognitoIdentityId     string
+	CognitoIdentityPoolId string
+	ClientContext         []byte
+}
+
+// invokeDeadline mirrors messages.InvokeRequest_Timestamp of aws-lambda-go
+type invokeDeadline struct {
+	Seconds int64
+	Nanos   int64
+}
+
+// invokeResponse mirrors messages.InvokeResponse of aws-lambda-go
+type invokeResponse struct {
+	Payload []byte
+	Error   *invokeResponseError
+}
+
+// invokeResponseError mirrors messages.InvokeResponse_Error of aws-lambda-go
+type invokeResponseError struct {
+	Message    string
+	Type       string
+	ShouldExit bool
+}
+
+// LocalFunctions returns the functions of the given resources/ function groups with their environment
+// resolved for the stage using the local tables of the mode. The binaries have to be built with BuildNative.
+func (m MUGConfig) LocalFunctions(list []string, stage, mode string) map[string]*LocalFunction {
+	functions := map[string]*LocalFunction{}
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		env := m.ResolveEnvironment(sc, r, stage, mode)
+		for name := range sc.Functions {
+			functions[name] = m.newLocalFunction(sc, r, name, mode, env)
+		}
+	}
+
+	return functions
+}
+
+// LocalFunction returns a single function of a resource/ function group with its environment resolved for the stage.
+// The binary has to be built with BuildNative.
+func (m MUGConfig) LocalFunction(r, name, stage string) (*LocalFunction, error) {
+	sc := m.ReadServerlessConfig(r)
+	fn, ok := sc.FunctionName(r, name)
+	if !ok {
+		return nil, fmt.Errorf("function %s doesn't exist in %s", name, r)
+	}
+
+	return m.newLocalFunction(sc, r, fn, "debug", m.ResolveEnvironment(sc, r, stage, "debug")), nil
+}
+
+// newLocalFunction returns the local function with the environment of a Lambda function running in the mode
+func (m MUGConfig) newLocalFunction(sc ServerlessConfig, r, name, mode string, env map[string]string) *LocalFunction {
+	fn := sc.Functions[name]
+	handler := strings.TrimPrefix(fn.Handler, "bin/")
+	runtime, _ := sc.FunctionRuntime(name)
+
+	timeout := fn.Timeout
+	if timeout == 0 {
+		timeout = sc.Provider.Timeout
+	}
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	memory := fn.MemorySize
+	if memory == 0 {
+		memory = defaultMemorySize
+	}
+
+	f := &LocalFunction{
+		Resource: r,
+		Name:     name,
+		bin:      filepath.Join(m.ProjectPath, "functions", r, "local", handler),
+		rpc:      !IsProvidedRuntime(runtime),
+		timeout:  time.Duration(timeout) * time.Second,
+		env: []string{
+			"MODE=" + mode,
+			"DYNAMODB_ENDPOINT=" + m.DynamoDB().Endpoint(),
+			"AWS_REGION=" + m.Region,
+			"AWS_DEFAULT_REGION=" + m.Region,
+			"AWS_LAMBDA_FUNCTION_NAME=" + name,
+			"AWS_LAMBDA_FUNCTION_VERSION=$LATEST",
+			"AWS_LAMBDA_FUNCTION_MEMORY_SIZE=" + strconv.Itoa(int(memory)),
+			"_HANDLER=" + fn.Handler,
+		},
+	}
+	if !f.rpc {
+		f.bin = filepath.Join(f.bin, bootstrap)
+	}
+	for k, v := range env {
+		f.env = append(f.env, k+"="+v)
+	}
+
+	return f
+}
+
+// Invoke invokes the function with the payload and returns its response.
+// The function is started on the first invocation and restarted if it exited.
+func (f *LocalFunction) Invoke(payload []byte) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.start(); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(f.timeout)
+	if f.rpc {
+		return f.invokeRPC(payload, deadline)
+	}
+	return f.invokeRuntimeAPI(payload, deadline)
+}
+
+// Stop terminates the process of the function
+func (f *LocalFunction) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.stop()
+}
+
+// start starts the process of the function if it isn't running
+func (f *LocalFunction) start() error {
+	if f.cmd != nil {
+		select {
+		case <-f.exited:
+			f.stop()
+		default:
+			return nil
+		}
+	}
+
+	env := append(os.Environ(), f.env...)
+	var port string
+	if f.rpc {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return err
+		}
+		port = strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+		l.Close()
+		env = append(env, "_LAMBDA_SERVER_PORT="+port)
+	} else {
+		api, err := startRuntimeAPI(f.arn())
+		if err != nil {
+			return err
+		}
+		f.api = api
+		env = append(env, "AWS_LAMBDA_RUNTIME_API="+api.addr())
+	}
+
+	cmd := exec.Command(f.bin)
+	cmd.Dir = filepath.Dir(f.bin)
+	cmd.Env = env
+	// the logs of the function are kept apart from the output of mug like in CloudWatch
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		f.stop()
+		return fmt.Errorf("starting %s/%s failed with %s", f.Resource, f.Name, err)
+	}
+	f.cmd, f.exited = cmd, make(chan struct{})
+	go func(exited chan struct{}) {
+		f.exitErr = cmd.Wait()
+		close(exited)
+	}(f.exited)
+
+	if f.rpc {
+		client, err := dialRPC("127.0.0.1:"+port, f.exited)
+		if err != nil {
+			f.stop()
+			return fmt.Errorf("connecting to %s/%s failed with %s", f.Resource, f.Name, err)
+		}
+		f.client = client
+	}
+
+	return nil
+}
+
+// stop kills the process and shuts the runtime API or RPC connection down
+func (f *LocalFunction) stop() {
+	if f.client != nil {
+		f.client.Close()
+		f.client = nil
+	}
+	if f.cmd != nil {
+		f.cmd.Process.Kill()
+		<-f.exited
+		f.cmd = nil
+	}
+	if f.api != nil {
+		f.api.close()
+		f.api = nil
+	}
+}
+
+// dialRPC connects to the RPC interface of a go1.x function once it is listening
+func dialRPC(addr string, exited chan struct{}) (*rpc.Client, error) {
+	for i := 0; i < 100; i++ {
+		client, err := rpc.Dial("tcp", addr)
+		if err == nil {
+			return client, nil
+		}
+		select {
+		case <-exited:
+			return nil, fmt.Errorf("function exited")
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	return nil, fmt.Errorf("function isn't listening on %s", addr)
+}
+
+// invokeRPC invokes a go1.x function through Function.Invoke
+func (f *LocalFunction) invokeRPC(payload []byte, deadline time.Time) ([]byte, error) {
+	req := &invokeRequest{
+		Payload:            payload,
+		RequestId:          requestID(),
+		Deadline:           invokeDeadline{Seconds: deadline.Unix(), Nanos: int64(deadline.Nanosecond())},
+		InvokedFunctionArn: f.arn(),
+	}
+	resp := &invokeResponse{}
+
+	call := f.client.Go("Function.Invoke", req, resp, nil)
+	select {
+	case <-call.Done:
+	case <-time.After(time.Until(deadline)):
+		f.stop()
+		return nil, f.timeoutError()
+	}
+	if call.Error != nil {
+		f.stop()
+		return nil, fmt.Errorf("invoking %s/%s failed with %s", f.Resource, f.Name, call.Error)
+	}
+	if resp.Error != nil {
+		if resp.Error.ShouldExit {
+			f.stop()
+		}
+		return nil, &FunctionError{Message: resp.Error.Message, Type: resp.Error.Type}
+	}
+
+	return resp.Payload, nil
+}
+
+// invokeRuntimeAPI passes the invocation to the function polling the runtime API and waits for its result
+func (f *LocalFunction) invokeRuntimeAPI(payload []byte, deadline time.Time) ([]byte, error) {
+	inv := &invocation{
+		id:       requestID(),
+		payload:  payload,
+		deadline: deadline,
+		result:   make(chan invocationResult, 1),
+	}
+	api := f.api
+	api.add(inv)
+	defer api.remove(inv.id)
+
+	timeout := time.After(time.Until(deadline))
+	select {
+	case api.next <- inv:
+	case <-f.exited:
+		return nil, f.exitError()
+	case <-timeout:
+		f.stop()
+		return nil, f.timeoutError()
+	}
+
+	select {
+	case res := <-inv.result:
+		return res.payload, res.err
+	case <-f.exited:
+		return nil, f.exitError()
+	case <-timeout:
+		f.stop()
+		return nil, f.timeoutError()
+	}
+}
+
+// arn returns a local ARN of the function
+func (f *LocalFunction) arn() string {
+	return "arn:aws:lambda:local:000000000000:function:" + f.Name
+}
+
+func (f *LocalFunction) timeoutError() error {
+	return &FunctionError{Message: fmt.Sprintf("Task timed out after %.2f seconds", f.timeout.Seconds())}
+}
+
+func (f *LocalFunction) exitError() error {
+	err := f.exitErr
+	if err == nil {
+		err = fmt.Errorf("exit status 0")
+	}
+	if f.api != nil {
+		if initErr := f.api.initError(); initErr != nil {
+			err = initErr
+		}
+	}
+	return fmt.Errorf("%s/%s exited with %s", f.Resource, f.Name, err)
+}
+
+// requestID returns a random request id formatted like a UUID
+func requestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal(err)
+	}
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// runtimeAPI emulates the Lambda runtime API for a single function
+type runtimeAPI struct {
+	arn      string
+	listener net.Listener
+	server   *http.Server
+	next     chan *invocation
+
+	mu      sync.Mutex
+	pending map[string]*invocation
+	initErr error
+}
+
+// startRuntimeAPI starts the runtime API of the function with the ARN on a random local port
+func startRuntimeAPI(arn string) (*runtimeAPI, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	api := &runtimeAPI{
+		arn:      arn,
+		listener: l,
+		next:     make(chan *invocation),
+		pending:  map[string]*invocation{},
+	}
+	api.server = &http.Server{Handler: api}
+	go api.server.Serve(l)
+
+	return api, nil
+}
+
+func (a *runtimeAPI) addr() string {
+	return a.listener.Addr().String()
+}
+
+func (a *runtimeAPI) close() {
+	a.server.Close()
+}
+
+func (a *runtimeAPI) add(inv *invocation) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.pending[inv.id] = inv
+}
+
+func (a *runtimeAPI) remove(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.pending, id)
+}
+
+// take removes and returns the pending invocation, so each invocation is answered once
+func (a *runtimeAPI) take(id string) *invocation {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	inv := a.pending[id]
+	delete(a.pending, id)
+	return inv
+}
+
+func (a *runtimeAPI) initError() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.initErr
+}
+
+// ServeHTTP handles the next, response and error endpoints of the runtime API
+func (a *runtimeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, runtimeAPIPrefix)
+	switch {
+	case r.Method == http.MethodGet && path == "invocation/next":
+		select {
+		case inv := <-a.next:
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", inv.id)
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(inv.deadline.UnixNano()/int64(time.Millisecond), 10))
+			w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", a.arn)
+			w.Header().Set("Lambda-Runtime-Trace-Id", fmt.Sprintf("Root=1-%08x-%s", time.Now().Unix(), strings.Replace(inv.id, "-", "", -1)[:24]))
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(inv.payload)
+		case <-r.Context().Done():
+		}
+
+	case r.Method == http.MethodPost && path == "init/error":
+		a.mu.Lock()
+		a.initErr = readFunctionError(r)
+		a.mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+
+	case r.Method == http.MethodPost && strings.HasPrefix(path, "invocation/"):
+		parts := strings.Split(strings.TrimPrefix(path, "invocation/"), "/")
+		if len(parts) != 2 || (parts[1] != "response" && parts[1] != "error") {
+			http.NotFound(w, r)
+			return
+		}
+		// the result channel has room for exactly one result, which only the first post gets to send
+		inv := a.take(parts[0])
+		if inv == nil {
+			http.Error(w, `{"errorMessage":"unknown or already answered request id"}`, http.StatusBadRequest)
+			return
+		}
+
+		if parts[1] == "error" {
+			inv.result <- invocationResult{err: readFunctionError(r)}
+		} else {
+			data, err := ioutil.ReadAll(r.Body)
+			inv.result <- invocationResult{payload: data, err: err}
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// readFunctionError reads the error posted by the function
+func readFunctionError(r *http.Request) error {
+	e := &FunctionError{}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, e); err != nil || len(e.Message) == 0 {
+		e.Message = string(data)
+	}
+	if len(e.Type) == 0 {
+		e.Type = r.Header.Get("Lambda-Runtime-Function-Error-Type")
+	}
+
+	return e
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRuntime lets the function be served by the handler polling the runtime API in-process instead of a binary.
+// The returned function shuts the runtime API down.
+func fakeRuntime(t *testing.T, f *LocalFunction, handler func(payload []byte) ([]byte, *FunctionError)) func() {
+	api, err := startRuntimeAPI(f.arn())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a running process as far as start is concerned
+	f.api, f.cmd, f.exited = api, &exec.Cmd{}, make(chan struct{})
+
+	base := "http://" + api.addr() + runtimeAPIPrefix
+	go func() {
+		for {
+			resp, err := http.Get(base + "invocation/next")
+			if err != nil {
+				return
+			}
+			payload, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			id := resp.Header.Get("Lambda-Runtime-Aws-Request-Id")
+
+			out, ferr := handler(payload)
+			url := base + "invocation/" + id + "/response"
+			if ferr != nil {
+				url = base + "invocation/" + id + "/error"
+				out = []byte(`{"errorMessage":"` + ferr.Message + `","errorType":"` + ferr.Type + `"}`)
+			}
+			if resp, err := http.Post(url, "application/json", bytes.NewReader(out)); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}()
+
+	return api.close
+}
+
+func TestLocalFunctionInvokeRuntimeAPI(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(payload []byte) ([]byte, *FunctionError)
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "response",
+			handler: func(payload []byte) ([]byte, *FunctionError) { return append([]byte("echo "), payload...), nil },
+			want:    `echo {"id":"1"}`,
+		},
+		{
+			name: "error",
+			handler: func(payload []byte) ([]byte, *FunctionError) {
+				return nil, &FunctionError{Message: "boom", Type: "errorString"}
+			},
+			wantErr: &FunctionError{Message: "boom", Type: "errorString"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &LocalFunction{Resource: "note", Name: "read_note", timeout: 5 * time.Second}
+			defer fakeRuntime(t, f, tt.handler)()
+
+			// the function serves one invocation after the other
+			for i := 0; i < 2; i++ {
+				out, err := f.Invoke([]byte(`{"id":"1"}`))
+				if string(out) != tt.want || !reflect.DeepEqual(err, tt.wantErr) {
+					t.Errorf("Invoke() = %s, %v, want %s, %v", out, err, tt.want, tt.wantErr)
+				}
+			}
+		})
+	}
+}
+
+func TestRuntimeAPI(t *testing.T) {
+	api, err := startRuntimeAPI("arn:aws:lambda:local:000000000000:function:read_note")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.close()
+	base := "http://" + api.addr() + runtimeAPIPrefix
+
+	inv := &invocation{id: requestID(), payload: []byte(`{}`), deadline: time.Now().Add(time.Minute), result: make(chan invocationResult, 1)}
+	api.add(inv)
+	go func() { api.next <- inv }()
+
+	resp, err := http.Get(base + "invocation/next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	for header, want := range map[string]string{
+		"Lambda-Runtime-Aws-Request-Id":       inv.id,
+		"Lambda-Runtime-Invoked-Function-Arn": api.arn,
+		"Lambda-Runtime-Deadline-Ms":          strconv.FormatInt(inv.deadline.UnixNano()/int64(time.Millisecond), 10),
+	} {
+		if got := resp.Header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	// only the first result of an invocation is accepted
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"invocation/" + inv.id + "/response", http.StatusAccepted},
+		{"invocation/" + inv.id + "/response", http.StatusBadRequest},
+		{"invocation/" + inv.id + "/error", http.StatusBadRequest},
+		{"invocation/unknown/response", http.StatusBadRequest},
+		{"invocation/" + inv.id + "/other", http.StatusNotFound},
+		{"init/error", http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		resp, err := http.Post(base+tt.path, "application/json", strings.NewReader(`{"errorMessage":"failed","errorType":"Runtime.Init"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("POST %s = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+	}
+
+	if res := <-inv.result; res.err != nil || string(res.payload) != `{"errorMessage":"failed","errorType":"Runtime.Init"}` {
+		t.Errorf("result = %s, %v", res.payload, res.err)
+	}
+	if err := api.initError(); !reflect.DeepEqual(err, &FunctionError{Message: "failed", Type: "Runtime.Init"}) {
+		t.Errorf("initError() = %v", err)
+	}
+}
+
+func TestLocalFunction(t *testing.T) {
+	dir := writeProject(t, map[string]string{"functions/note/serverless.yml": `service:
+  name: svc-note
+provider:
+  name: aws
+  runtime: provided.al2023
+  environment:
+    STAGE: ${opt:stage}
+functions:
+  create_note:
+    handler: bin/create
+  read_note:
+    handler: bin/read
+    timeout: 30
+  legacy_note:
+    handler: bin/legacy
+    runtime: go1.x
+`})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1"}
+	local := filepath.Join(dir, "functions", "note", "local")
+
+	tests := []struct {
+		name     string
+		function string
+		bin      string
+		rpc      bool
+		timeout  time.Duration
+		wantErr  bool
+	}{
+		{name: "create_note", function: "create_note", bin: filepath.Join(local, "create", bootstrap), timeout: defaultTimeout * time.Second},
+		{name: "create", function: "create_note", bin: filepath.Join(local, "create", bootstrap), timeout: defaultTimeout * time.Second},
+		{name: "read", function: "read_note", bin: filepath.Join(local, "read", bootstrap), timeout: 30 * time.Second},
+		{name: "legacy_note", function: "legacy_note", bin: filepath.Join(local, "legacy"), rpc: true, timeout: defaultTimeout * time.Second},
+		{name: "delete", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := m.LocalFunction("note", tt.name, "prod")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LocalFunction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if f.Name != tt.function || f.bin != tt.bin || f.rpc != tt.rpc || f.timeout != tt.timeout {
+				t.Errorf("LocalFunction() = %s %s rpc %v timeout %s, want %s %s rpc %v timeout %s", f.Name, f.bin, f.rpc, f.timeout, tt.function, tt.bin, tt.rpc, tt.timeout)
+			}
+			for _, v := range []string{"MODE=debug", "STAGE=prod", "AWS_REGION=eu-central-1", "AWS_LAMBDA_FUNCTION_NAME=" + tt.function} {
+				if !Contains(f.env, v) {
+					t.Errorf("environment %v doesn't contain %s", f.env, v)
+				}
+			}
+		})
+	}
+}
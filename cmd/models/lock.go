@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	// lockFile is the name of the lock file preventing concurrent mug invocations in a project
+	lockFile = ".mug.lock"
+
+	// LockAnnotation marks the commands writing project files, only those take the project lock,
+	// e.g. Annotations: map[string]string{models.LockAnnotation: "true"}
+	LockAnnotation = "mug:lock"
+)
+
+// Lock acquires the project lock in the working directory and fails if another mug process holds it
+func Lock() {
+	path := filepath.Join(GetWorkingDir(), lockFile)
+
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			defer f.Close()
+			if _, err := fmt.Fprint(f, os.Getpid()); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if !os.IsExist(err) {
+			log.Fatal(err)
+		}
+
+		// remove the lock left behind by a process that terminated e.g. with log.Fatal
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		if pid, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil && processAlive(pid) {
+			log.Fatalf("Project is locked by mug process %d, remove %s if the process doesn't exist anymore", pid, path)
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+	}
+
+	log.Fatalf("Could not acquire the project lock %s", path)
+}
+
+// Unlock releases the project lock if it is held by this process
+func Unlock() {
+	path := filepath.Join(GetWorkingDir(), lockFile)
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return
+	} else if err != nil {
+		log.Fatal(err)
+	}
+	// the lock was released early and acquired by another mug process meanwhile
+	if strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		return
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+}
+
+// processAlive checks whether a process with the given pid is running
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// FindProcess fails on windows for processes that don't exist
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || err == syscall.EPERM
+}
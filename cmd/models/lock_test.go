@@ -0,0 +1,107 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// chdirTemp changes into a new temporary directory and returns a function restoring the working directory
+func chdirTemp(t *testing.T) (string, func()) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "mug-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir, func() {
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+	}
+}
+
+// exitedPid returns the pid of a process that already terminated
+func exitedPid(t *testing.T) int {
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("true is not available:", err)
+	}
+
+	return cmd.Process.Pid
+}
+
+func TestLock(t *testing.T) {
+	tests := []struct {
+		name   string
+		holder func(t *testing.T) string
+	}{
+		{"unlocked", nil},
+		{"stale lock", func(t *testing.T) string { return strconv.Itoa(exitedPid(t)) }},
+		{"corrupt lock", func(t *testing.T) string { return "no pid" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, restore := chdirTemp(t)
+			defer restore()
+			path := filepath.Join(dir, lockFile)
+
+			if tt.holder != nil {
+				if err := ioutil.WriteFile(path, []byte(tt.holder(t)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			Lock()
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != strconv.Itoa(os.Getpid()) {
+				t.Errorf("lock holds pid %s, want %d", data, os.Getpid())
+			}
+
+			Unlock()
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("lock still exists after Unlock: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnlockForeignLock(t *testing.T) {
+	dir, restore := chdirTemp(t)
+	defer restore()
+	path := filepath.Join(dir, lockFile)
+
+	// the lock was released early and acquired by another process meanwhile
+	if err := ioutil.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	Unlock()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Unlock removed the lock of another process: %v", err)
+	}
+
+	// no lock at all
+	os.Remove(path)
+	Unlock()
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("processAlive() = false for the running process")
+	}
+	if pid := exitedPid(t); processAlive(pid) {
+		t.Errorf("processAlive() = true for the terminated process %d", pid)
+	}
+}
@@ -1,9 +1,9 @@
 package models
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"path/filepath"
 	"strings"
@@ -309,6 +309,11 @@ func (m *Model) checkKeys() (bool, error) {
 
 // GetConfigs returns the MUGConfig and ServerlessConfig for this Model
 func (m Model) GetConfigs() (MUGConfig, ServerlessConfig) {
+	return m.GetConfigsFor(ReadMUGConfig())
+}
+
+// GetConfigsFor returns the given MUGConfig updated with this Model and the ServerlessConfig for this Model
+func (m Model) GetConfigsFor(mc MUGConfig) (MUGConfig, ServerlessConfig) {
 	attributeDefinitions := map[string]AttributeDefinition{}
 	for _, k := range m.KeySchema {
 		a := m.Attributes[k]
@@ -325,7 +330,9 @@ func (m Model) GetConfigs() (MUGConfig, ServerlessConfig) {
 		Ident:      flect.New(m.Name),
 		Attributes: attributeDefinitions,
 	}
-	mc := ReadMUGConfig()
+	if mc.Resources == nil {
+		mc.Resources = make(map[string]*NewResource)
+	}
 	mc.Resources[m.Name] = r
 	// mc.Write()
 
@@ -353,6 +360,62 @@ func (m Model) GetConfigs() (MUGConfig, ServerlessConfig) {
 	return mc, sc
 }
 
+// RenderTemplates renders the CRUDL functions, the model and its mocks for this Model
+func (m Model) RenderTemplates(config MUGConfig) {
+	temps := []string{
+		"create",
+		"read",
+		"update",
+		"delete",
+		"list",
+		"model",
+		"modelMocks",
+	}
+
+	data := map[string]interface{}{
+		"Model":  m,
+		"Config": config,
+	}
+
+	mName := m.Ident.Camelize().String()
+
+	// iterate over resource templates and execute
+	for _, t := range temps {
+		// create the function folder for function templete (except model)
+		folder := filepath.Join(config.ProjectPath, "functions", mName)
+		if t == "model" {
+			MkdirAll(folder, 0755)
+			renderResourceFile(config, mName+".go", "model.tmpl", folder, data)
+		} else if t == "modelMocks" {
+			mockString := mName + "Mocks"
+			folder = filepath.Join(config.ProjectPath, "mocks", mockString)
+			MkdirAll(folder, 0755)
+			renderResourceFile(config, mockString+".go", "modelMocks.tmpl", folder, data)
+		} else {
+			folder = filepath.Join(folder, t)
+			MkdirAll(folder, 0755)
+			for _, tf := range []string{"main", "main_test"} {
+				renderResourceFile(config, tf+".go", filepath.Join(t, tf+".tmpl"), folder, data)
+			}
+			// the tests of each function package run against their own table
+			renderResourceFile(config, "setup_test.go", "setup_test.tmpl", folder, data)
+		}
+	}
+}
+
+func renderResourceFile(config MUGConfig, fName, tPath, folder string, data map[string]interface{}) {
+	// load template
+	tmpl := LoadProjectTemplate(config.ProjectPath, ResourceBox, tPath)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(filepath.Join(folder, fName), buf.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
 // Write write the Model definition to the modelName.json
 func (m Model) Write(path string) {
 	json, err := json.MarshalIndent(m, "", "  ")
@@ -360,12 +423,25 @@ func (m Model) Write(path string) {
 		log.Fatal(err)
 	}
 
-	err = ioutil.WriteFile(filepath.Join(path, "functions", m.Name, fmt.Sprintf("%s.json", m.Name)), json, 0644)
+	err = WriteFile(filepath.Join(path, "functions", m.Name, fmt.Sprintf("%s.json", m.Name)), json, 0644)
 	if err != nil {
 		log.Fatal(err)
 	}
 }
 
+// ReadModel reads the Model definition of a resource from the modelName.json
+func ReadModel(path, name string) (Model, error) {
+	var m Model
+	data, err := readDataFromFile(filepath.Join(path, "functions", name, fmt.Sprintf("%s.json", name)))
+	if err != nil {
+		return m, err
+	}
+
+	err = json.Unmarshal(data, &m)
+
+	return m, err
+}
+
 // String prints a representation of a model
 func (m Model) String() string {
 	var sb strings.Builder
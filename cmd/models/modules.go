@@ -0,0 +1,115 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// goVersion is the minimum go version written to the go.mod of new projects
+const goVersion = "1.13"
+
+// semver matches the versions of Gopkg.lock which can be used as module versions
+var semver = regexp.MustCompile(`^v\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?$`)
+
+// GoMod returns the content of a go.mod for the module path with the given requirements
+func GoMod(modulePath string, requires map[string]string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "module %s\n\ngo %s\n", modulePath, goVersion)
+
+	if len(requires) > 0 {
+		var paths []string
+		for p := range requires {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		buf.WriteString("\nrequire (\n")
+		for _, p := range paths {
+			fmt.Fprintf(&buf, "\t%s %s\n", p, requires[p])
+		}
+		buf.WriteString(")\n")
+	}
+
+	return buf.Bytes()
+}
+
+// UsesModules checks whether the project is managed with go modules instead of dep
+func (m MUGConfig) UsesModules() bool {
+	_, err := ReadFile(filepath.Join(m.ProjectPath, "go.mod"))
+	return err == nil
+}
+
+// MigrateModules replaces the dep configuration of the project with a go.mod keeping the locked versions
+func (m MUGConfig) MigrateModules() {
+	if m.UsesModules() {
+		log.Fatal("Project uses go modules already")
+	}
+	if len(m.ImportPath) == 0 {
+		log.Fatal("ImportPath of mug.config.json is empty, cannot determine the module path")
+	}
+
+	requires := map[string]string{}
+	lock := filepath.Join(m.ProjectPath, "Gopkg.lock")
+	data, err := ReadFile(lock)
+	if err == nil {
+		requires = readDepLock(data)
+	} else if !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	if err := WriteFile(filepath.Join(m.ProjectPath, "go.mod"), GoMod(m.ImportPath, requires), 0644); err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range []string{"Gopkg.toml", "Gopkg.lock", "vendor"} {
+		if err := RemoveAll(filepath.Join(m.ProjectPath, f)); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// readDepLock returns the projects of a Gopkg.lock locked to a semantic version.
+// Projects locked to a revision only are resolved by 'go mod tidy'.
+func readDepLock(data []byte) map[string]string {
+	requires := map[string]string{}
+
+	var name, version string
+	add := func() {
+		v := "v" + strings.TrimPrefix(version, "v")
+		// modules of major version 2 or higher require the version suffix in their path
+		if len(name) > 0 && semver.MatchString(v) && (strings.HasPrefix(v, "v0.") || strings.HasPrefix(v, "v1.")) {
+			requires[name] = v
+		}
+		name, version = "", ""
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "[") {
+			add()
+			continue
+		}
+
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		val := strings.Trim(strings.TrimSpace(kv[1]), `"`)
+		switch strings.TrimSpace(kv[0]) {
+		case "name":
+			name = val
+		case "version":
+			version = val
+		}
+	}
+	add()
+
+	return requires
+}
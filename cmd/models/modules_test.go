@@ -0,0 +1,120 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// gopkgLock is a Gopkg.lock with projects locked to versions and revisions
+const gopkgLock = `# This file is autogenerated, do not edit; changes may be undone by the next 'dep ensure'.
+
+
+[[projects]]
+  digest = "1:abc"
+  name = "github.com/aws/aws-lambda-go"
+  packages = ["events", "lambda"]
+  pruneopts = "UT"
+  revision = "0b6d37d4cb7f4e2d7e8c0d0c6a8d2b4f9f0a1b2c"
+  version = "v1.13.2"
+
+[[projects]]
+  name = "github.com/gofrs/uuid"
+  revision = "6b08a5c5172ba18946672b49749cde22873dd7c2"
+  version = "3.2.0"
+
+[[projects]]
+  name = "github.com/go-redis/redis"
+  revision = "a8a1c1d1e0d1e0d1e0d1e0d1e0d1e0d1e0d1e0d1"
+  version = "v6.15.2"
+
+[[projects]]
+  branch = "master"
+  name = "golang.org/x/net"
+  revision = "d28f0bde5980168871434b95cfc858db9f2a7a99"
+
+[[projects]]
+  name = "github.com/pkg/errors"
+  revision = "ba968bfe8b2f7e042a574c888954fccecfa385b4"
+  version = "v0.8.1"
+
+[solve-meta]
+  analyzer-name = "dep"
+  input-imports = ["github.com/aws/aws-lambda-go/events"]
+`
+
+func TestReadDepLock(t *testing.T) {
+	want := map[string]string{
+		"github.com/aws/aws-lambda-go": "v1.13.2",
+		"github.com/pkg/errors":        "v0.8.1",
+	}
+	if got := readDepLock([]byte(gopkgLock)); !reflect.DeepEqual(got, want) {
+		t.Errorf("readDepLock() = %v, want %v", got, want)
+	}
+}
+
+func TestGoMod(t *testing.T) {
+	tests := []struct {
+		name     string
+		requires map[string]string
+		want     string
+	}{
+		{
+			name: "no requirements",
+			want: "module github.com/crolly/blog\n\ngo " + goVersion + "\n",
+		},
+		{
+			name:     "sorted requirements",
+			requires: map[string]string{"github.com/pkg/errors": "v0.8.1", "github.com/aws/aws-lambda-go": "v1.13.2"},
+			want: "module github.com/crolly/blog\n\ngo " + goVersion + "\n\nrequire (\n" +
+				"\tgithub.com/aws/aws-lambda-go v1.13.2\n\tgithub.com/pkg/errors v0.8.1\n)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(GoMod("github.com/crolly/blog", tt.requires)); got != tt.want {
+				t.Errorf("GoMod() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateModules(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+	vfs = newVirtualFS()
+
+	dir := writeProject(t, map[string]string{
+		"Gopkg.toml":                        "[prune]\n",
+		"Gopkg.lock":                        gopkgLock,
+		"vendor/github.com/pkg/errors/a.go": "package errors\n",
+		"functions/note/note.go":            "package note\n",
+	})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, ImportPath: "github.com/crolly/blog"}
+
+	if m.UsesModules() {
+		t.Fatal("UsesModules() = true before the migration")
+	}
+	m.MigrateModules()
+	if !m.UsesModules() {
+		t.Error("UsesModules() = false after the migration")
+	}
+
+	data, err := ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := string(GoMod(m.ImportPath, readDepLock([]byte(gopkgLock)))); string(data) != want {
+		t.Errorf("go.mod =\n%s\nwant\n%s", data, want)
+	}
+	for _, f := range []string{"Gopkg.toml", "Gopkg.lock", "vendor/github.com/pkg/errors/a.go"} {
+		if _, err := ReadFile(filepath.Join(dir, f)); !os.IsNotExist(err) {
+			t.Errorf("%s wasn't removed: %v", f, err)
+		}
+	}
+	if _, err := ReadFile(filepath.Join(dir, "functions", "note", "note.go")); err != nil {
+		t.Errorf("project file was removed: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// lambdaZipLimit is the maximum size of a zip uploaded directly to Lambda
+	lambdaZipLimit = 50 * 1024 * 1024
+	// lambdaUnzippedLimit is the maximum size of the unzipped deployment package
+	lambdaUnzippedLimit = 250 * 1024 * 1024
+	// sizeWarning is the share of the Lambda limits from which on a package is reported as close to them
+	sizeWarning = 0.8
+)
+
+// zipTime is the modification time of all zip entries, the earliest time the zip format can represent
+var zipTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// packageTarget is the deployment package of a single function
+type packageTarget struct {
+	Resource string
+	Function string
+	Zip      string
+	entries  []packageEntry
+
+	zipped   int64
+	unzipped int64
+}
+
+// packageEntry is a file added to a deployment package
+type packageEntry struct {
+	Name string
+	Path string
+	Mode os.FileMode
+}
+
+// Package zips the built binaries of the given resources/ function groups with the files declared in
+// package.include of their functions and points the package.artifact of the functions at the zips.
+// The zips are reproducible: the entries are sorted and have a fixed timestamp.
+func (m MUGConfig) Package(list []string) {
+	var targets []*packageTarget
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		for _, fn := range sortedKeys(sc.Functions) {
+			sc.updatePackage(fn)
+			t, err := m.packageTarget(r, fn, sc)
+			if err != nil {
+				log.Fatal(err)
+			}
+			targets = append(targets, t)
+		}
+		sc.Write(m.ProjectPath, r)
+	}
+
+	if DryRun {
+		for _, t := range targets {
+			var names []string
+			for _, e := range t.entries {
+				names = append(names, e.Name)
+			}
+			Record("zip %s %s", t.Zip, strings.Join(names, " "))
+		}
+		return
+	}
+
+	for _, t := range targets {
+		data, err := t.zip()
+		if err != nil {
+			log.Fatalf("Packaging %s/%s failed with %s", t.Resource, t.Function, err)
+		}
+		t.zipped = int64(len(data))
+		if err := WriteFile(filepath.Join(m.ProjectPath, t.Zip), data, 0644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	printPackageReport(targets)
+}
+
+// packageTarget collects the binary and the included files of a function.
+// The go1.x runtime executes the handler path, the OS-only runtimes the bootstrap in the root of the zip.
+func (m MUGConfig) packageTarget(r, fn string, sc ServerlessConfig) (*packageTarget, error) {
+	f := sc.Functions[fn]
+	name := strings.TrimPrefix(f.Handler, "bin/")
+	dir := filepath.Join(m.ProjectPath, "functions", r)
+	t := &packageTarget{
+		Resource: r,
+		Function: name,
+		Zip:      filepath.Join("functions", r, filepath.FromSlash(f.Package.Artifact)),
+	}
+
+	bin := packageEntry{Name: "bin/" + name, Path: filepath.Join(dir, "bin", name), Mode: 0755}
+	if runtime, _ := sc.FunctionRuntime(fn); IsProvidedRuntime(runtime) {
+		bin = packageEntry{Name: bootstrap, Path: filepath.Join(dir, "bin", name, bootstrap), Mode: 0755}
+	}
+	info, err := os.Stat(bin.Path)
+	if err != nil || info.IsDir() {
+		return nil, fmt.Errorf("binary %s of %s/%s doesn't exist, build it first", bin.Path, r, name)
+	}
+	t.entries = append(t.entries, bin)
+	t.unzipped += info.Size()
+
+	if len(f.Package.Includes) > 0 {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if info.IsDir() {
+				// the build folders only contain binaries and packages
+				if rel == "bin" || rel == "debug" || rel == "local" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.Mode().IsRegular() || !matchAny(f.Package.Includes, rel) {
+				return nil
+			}
+
+			mode := os.FileMode(0644)
+			if info.Mode()&0111 != 0 {
+				mode = 0755
+			}
+			t.entries = append(t.entries, packageEntry{Name: rel, Path: path, Mode: mode})
+			t.unzipped += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(t.entries, func(i, j int) bool { return t.entries[i].Name < t.entries[j].Name })
+	return t, nil
+}
+
+// zip returns the reproducible zip of the package entries
+func (t *packageTarget) zip() ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range t.entries {
+		h := &zip.FileHeader{Name: e.Name, Method: zip.Deflate, Modified: zipTime}
+		h.SetMode(e.Mode)
+		w, err := zw.CreateHeader(h)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Open(e.Path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// printPackageReport prints the sizes of the packages and warns about packages close to the Lambda limits
+func printPackageReport(targets []*packageTarget) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FUNCTION\tZIPPED\tUNZIPPED\tLIMIT")
+	for _, t := range targets {
+		usage := float64(t.zipped) / lambdaZipLimit
+		if u := float64(t.unzipped) / lambdaUnzippedLimit; u > usage {
+			usage = u
+		}
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%.0f%%\n", t.Resource, t.Function, formatSize(t.zipped), formatSize(t.unzipped), usage*100)
+	}
+	w.Flush()
+
+	for _, t := range targets {
+		switch {
+		case t.zipped > lambdaZipLimit || t.unzipped > lambdaUnzippedLimit:
+			log.Printf("Warning: %s/%s exceeds the Lambda limits of %s zipped and %s unzipped", t.Resource, t.Function, formatSize(lambdaZipLimit), formatSize(lambdaUnzippedLimit))
+		case t.zipped > lambdaZipLimit*sizeWarning || t.unzipped > lambdaUnzippedLimit*sizeWarning:
+			log.Printf("Warning: %s/%s is close to the Lambda limits of %s zipped and %s unzipped", t.Resource, t.Function, formatSize(lambdaZipLimit), formatSize(lambdaUnzippedLimit))
+		}
+	}
+}
+
+// matchAny checks whether the slash separated path matches one of the glob patterns of the serverless framework
+func matchAny(patterns []string, path string) bool {
+	for _, p := range patterns {
+		if globRegexp(p).MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// globRegexp converts a glob pattern with ** matching across folders to a regular expression
+func globRegexp(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(pattern, "./")
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				// **/ also matches no folder at all
+				expr.WriteString("(.*/)?")
+				i += 2
+				continue
+			}
+			if strings.HasPrefix(pattern[i:], "**") {
+				expr.WriteString(".*")
+				i++
+				continue
+			}
+			expr.WriteString("[^/]*")
+		case '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.MustCompile(expr.String())
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"templates/**", "templates/mail/welcome.html", true},
+		{"templates/*", "templates/mail/welcome.html", false},
+		{"templates/*", "templates/index.html", true},
+		{"**/*.json", "config.json", true},
+		{"**/*.json", "config/dev/app.json", true},
+		{"./config.json", "config.json", true},
+		{"config.?son", "config.json", true},
+		{"config.json", "configXjson", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchAny([]string{tt.pattern}, tt.path); got != tt.want {
+			t.Errorf("matchAny(%s, %s) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPackage(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+
+	dir := writeProject(t, map[string]string{
+		"functions/note/serverless.yml": `service:
+  name: svc-note
+provider:
+  name: aws
+  runtime: provided.al2023
+functions:
+  create_note:
+    handler: bin/create
+    package:
+      include:
+      - templates/**
+  list_notes:
+    handler: bin/list
+    runtime: go1.x
+`,
+		"functions/note/bin/create/bootstrap":    "create binary",
+		"functions/note/bin/list":                "list binary",
+		"functions/note/templates/mail.txt":      "mail",
+		"functions/note/templates/html/note.htm": "note",
+		"functions/note/note.go":                 "package note",
+	})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir}
+
+	zips := func() map[string][]byte {
+		vfs = newVirtualFS()
+		m.Package([]string{"note"})
+		files := map[string][]byte{}
+		for _, p := range sortedPaths(vfs.files) {
+			if filepath.Ext(p) == ".zip" {
+				rel, _ := filepath.Rel(dir, p)
+				files[filepath.ToSlash(rel)] = vfs.files[p].data
+			}
+		}
+		return files
+	}
+
+	first := zips()
+	// the binaries are rebuilt with a new modification time
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(dir, "functions", "note", "bin", "list"), later, later)
+	if second := zips(); !reflect.DeepEqual(first, second) {
+		t.Error("packaging twice produced different zips")
+	}
+
+	tests := []struct {
+		zip     string
+		entries []string
+		modes   []os.FileMode
+	}{
+		{
+			zip:     "functions/note/bin/create.zip",
+			entries: []string{bootstrap, "templates/html/note.htm", "templates/mail.txt"},
+			modes:   []os.FileMode{0755, 0644, 0644},
+		},
+		{
+			zip:     "functions/note/bin/list.zip",
+			entries: []string{"bin/list"},
+			modes:   []os.FileMode{0755},
+		},
+	}
+	for _, tt := range tests {
+		data, ok := first[tt.zip]
+		if !ok {
+			t.Errorf("%s wasn't written", tt.zip)
+			continue
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var entries []string
+		var modes []os.FileMode
+		for _, f := range zr.File {
+			entries = append(entries, f.Name)
+			modes = append(modes, f.Mode().Perm())
+			if !f.Modified.Equal(zipTime) {
+				t.Errorf("%s/%s modified %s, want %s", tt.zip, f.Name, f.Modified, zipTime)
+			}
+		}
+		if !reflect.DeepEqual(entries, tt.entries) || !reflect.DeepEqual(modes, tt.modes) {
+			t.Errorf("%s = %v %v, want %v %v", tt.zip, entries, modes, tt.entries, tt.modes)
+		}
+	}
+
+	sc := m.ReadServerlessConfig("note")
+	if a := sc.Functions["create_note"].Package.Artifact; a != "bin/create.zip" {
+		t.Errorf("artifact of create_note = %s, want bin/create.zip", a)
+	}
+}
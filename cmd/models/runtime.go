@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// GoRuntime is the deprecated go1.x Lambda runtime
+	GoRuntime = "go1.x"
+	// ProvidedAL2Runtime is the OS-only runtime on Amazon Linux 2
+	ProvidedAL2Runtime = "provided.al2"
+	// ProvidedAL2023Runtime is the OS-only runtime on Amazon Linux 2023
+	ProvidedAL2023Runtime = "provided.al2023"
+
+	// X86Architecture is the x86_64 Lambda architecture
+	X86Architecture = "x86_64"
+	// ARMArchitecture is the arm64 (Graviton) Lambda architecture
+	ARMArchitecture = "arm64"
+
+	// bootstrap is the name of the executable of the OS-only runtimes
+	bootstrap = "bootstrap"
+)
+
+// ValidateRuntime checks the runtime and architecture are supported
+func ValidateRuntime(runtime, arch string) error {
+	switch runtime {
+	case GoRuntime, ProvidedAL2Runtime, ProvidedAL2023Runtime:
+	default:
+		return fmt.Errorf("unsupported runtime %s, choose between %s, %s and %s", runtime, ProvidedAL2023Runtime, ProvidedAL2Runtime, GoRuntime)
+	}
+
+	switch arch {
+	case X86Architecture:
+	case ARMArchitecture:
+		if runtime == GoRuntime {
+			return fmt.Errorf("the %s runtime doesn't support %s", GoRuntime, ARMArchitecture)
+		}
+	default:
+		return fmt.Errorf("unsupported architecture %s, choose between %s and %s", arch, X86Architecture, ARMArchitecture)
+	}
+
+	return nil
+}
+
+// IsProvidedRuntime checks whether the runtime is an OS-only runtime executing a bootstrap binary
+func IsProvidedRuntime(runtime string) bool {
+	return strings.HasPrefix(runtime, "provided")
+}
+
+// goArch returns the GOARCH for the Lambda architecture
+func goArch(arch string) string {
+	if arch == ARMArchitecture {
+		return "arm64"
+	}
+	return "amd64"
+}
+
+// FunctionRuntime returns the runtime and architecture of a function taking the provider defaults into account
+func (s ServerlessConfig) FunctionRuntime(name string) (string, string) {
+	runtime, arch := s.Provider.Runtime, s.Provider.Architecture
+	if fn := s.Functions[name]; fn != nil {
+		if len(fn.RunTime) > 0 {
+			runtime = fn.RunTime
+		}
+		if len(fn.Architecture) > 0 {
+			arch = fn.Architecture
+		}
+	}
+	if len(runtime) == 0 {
+		runtime = GoRuntime
+	}
+	if len(arch) == 0 {
+		arch = X86Architecture
+	}
+
+	return runtime, arch
+}
+
+// SetRuntime sets the runtime and architecture of all functions
+func (s *ServerlessConfig) SetRuntime(runtime, arch string) {
+	s.Provider.Runtime = runtime
+	s.Provider.Architecture = arch
+	if arch == X86Architecture {
+		// x86_64 is the default of the serverless framework
+		s.Provider.Architecture = ""
+	}
+
+	for name := range s.Functions {
+		s.updatePackage(name)
+	}
+}
+
+// SetFunctionRuntime overrides the runtime and architecture of a single function
+func (s *ServerlessConfig) SetFunctionRuntime(name, runtime, arch string) error {
+	fn := s.Functions[name]
+	if fn == nil {
+		return fmt.Errorf("function %s doesn't exist", name)
+	}
+
+	fn.RunTime, fn.Architecture = "", ""
+	if pr, pa := s.FunctionRuntime(name); pr != runtime || pa != arch {
+		fn.RunTime, fn.Architecture = runtime, arch
+	}
+	s.updatePackage(name)
+
+	return nil
+}
+
+// updatePackage points the function at its zip built by 'mug package', files declared in include are added to it
+func (s *ServerlessConfig) updatePackage(name string) {
+	fn := s.Functions[name]
+	handler := strings.TrimPrefix(fn.Handler, "bin/")
+
+	var includes []string
+	for _, i := range fn.Package.Includes {
+		// the binary is always packaged
+		if i != "bin/"+handler {
+			includes = append(includes, i)
+		}
+	}
+	fn.Package = Package{
+		Includes:     includes,
+		Artifact:     "bin/" + handler + ".zip",
+		Individually: true,
+	}
+}
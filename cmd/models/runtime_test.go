@@ -0,0 +1,91 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateRuntime(t *testing.T) {
+	tests := []struct {
+		runtime, arch string
+		wantErr       bool
+	}{
+		{ProvidedAL2023Runtime, X86Architecture, false},
+		{ProvidedAL2023Runtime, ARMArchitecture, false},
+		{ProvidedAL2Runtime, ARMArchitecture, false},
+		{GoRuntime, X86Architecture, false},
+		{GoRuntime, ARMArchitecture, true},
+		{"nodejs18.x", X86Architecture, true},
+		{ProvidedAL2023Runtime, "amd64", true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateRuntime(tt.runtime, tt.arch); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRuntime(%s, %s) error = %v, wantErr %v", tt.runtime, tt.arch, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFunctionRuntime(t *testing.T) {
+	sc := ServerlessConfig{
+		Provider: Provider{Runtime: ProvidedAL2023Runtime},
+		Functions: map[string]*ServerlessFunction{
+			"create": {Handler: "bin/create"},
+			"report": {Handler: "bin/report", RunTime: ProvidedAL2Runtime, Architecture: ARMArchitecture},
+		},
+	}
+
+	tests := []struct {
+		name          string
+		sc            ServerlessConfig
+		runtime, arch string
+	}{
+		{"create", sc, ProvidedAL2023Runtime, X86Architecture},
+		{"report", sc, ProvidedAL2Runtime, ARMArchitecture},
+		{"missing", sc, ProvidedAL2023Runtime, X86Architecture},
+		{"create", ServerlessConfig{}, GoRuntime, X86Architecture},
+	}
+
+	for _, tt := range tests {
+		runtime, arch := tt.sc.FunctionRuntime(tt.name)
+		if runtime != tt.runtime || arch != tt.arch {
+			t.Errorf("FunctionRuntime(%s) = %s, %s, want %s, %s", tt.name, runtime, arch, tt.runtime, tt.arch)
+		}
+	}
+}
+
+func TestSetFunctionRuntime(t *testing.T) {
+	sc := ServerlessConfig{
+		Functions: map[string]*ServerlessFunction{
+			"create": {Handler: "bin/create", Package: Package{Includes: []string{"bin/create", "templates/**"}}},
+			"read":   {Handler: "bin/read"},
+		},
+	}
+	sc.SetRuntime(ProvidedAL2023Runtime, X86Architecture)
+	if sc.Provider.Architecture != "" {
+		t.Errorf("default architecture %s written to the provider", sc.Provider.Architecture)
+	}
+
+	// a function overriding the provider defaults keeps its own runtime
+	if err := sc.SetFunctionRuntime("read", ProvidedAL2023Runtime, ARMArchitecture); err != nil {
+		t.Fatal(err)
+	}
+	if fn := sc.Functions["read"]; fn.RunTime != ProvidedAL2023Runtime || fn.Architecture != ARMArchitecture {
+		t.Errorf("read runs on %s/%s", fn.RunTime, fn.Architecture)
+	}
+	// setting the defaults again removes the override
+	if err := sc.SetFunctionRuntime("read", ProvidedAL2023Runtime, X86Architecture); err != nil {
+		t.Fatal(err)
+	}
+	if fn := sc.Functions["read"]; fn.RunTime != "" || fn.Architecture != "" {
+		t.Errorf("read keeps the override %s/%s", fn.RunTime, fn.Architecture)
+	}
+	if err := sc.SetFunctionRuntime("missing", ProvidedAL2023Runtime, X86Architecture); err == nil {
+		t.Error("SetFunctionRuntime() succeeded for a missing function")
+	}
+
+	want := Package{Includes: []string{"templates/**"}, Artifact: "bin/create.zip", Individually: true}
+	if got := sc.Functions["create"].Package; !reflect.DeepEqual(got, want) {
+		t.Errorf("Package = %+v, want %+v", got, want)
+	}
+}
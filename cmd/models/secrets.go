@@ -0,0 +1,301 @@
+package models
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// SecretKeyEnv is the environment variable holding the passphrase for the local secret store
+	SecretKeyEnv = "MUG_SECRET_KEY"
+
+	// stageVariable is the serverless variable referenced in secret parameter paths
+	stageVariable = "${self:provider.stage}"
+)
+
+// SecretStore represents the encrypted local secret store of a project
+type SecretStore struct {
+	path        string
+	projectName string
+	key         []byte
+	Secrets     map[string]string
+}
+
+// SecretPath returns the SSM parameter path of a secret for the given stage
+func (m MUGConfig) SecretPath(stage, key string) string {
+	return "/" + m.ProjectName + "/" + stage + "/" + key
+}
+
+// SecretReference returns the serverless variable referencing the SSM parameter of a secret,
+// SecureString parameters are decrypted by default since serverless v3
+func (m MUGConfig) SecretReference(key string) string {
+	return "${ssm:" + m.SecretPath(stageVariable, key) + "}"
+}
+
+// PutSSMSecret writes a secret as SecureString to the AWS SSM Parameter Store
+func (m MUGConfig) PutSSMSecret(stage, profile, key, value string) {
+	if DryRun {
+		Record("aws ssm put-parameter --name %s --type SecureString --overwrite", m.SecretPath(stage, key))
+		return
+	}
+
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:  aws.Config{Region: aws.String(m.Region)},
+		Profile: profile,
+	}))
+	svc := ssm.New(sess)
+
+	_, err := svc.PutParameter(&ssm.PutParameterInput{
+		Name:      aws.String(m.SecretPath(stage, key)),
+		Value:     aws.String(value),
+		Type:      aws.String(ssm.ParameterTypeSecureString),
+		Overwrite: aws.Bool(true),
+	})
+	if err != nil {
+		log.Fatalf("Error writing secret %s to SSM: %s", key, err)
+	}
+}
+
+// GetSSMSecret returns a lookup for SecureString parameters in the AWS SSM Parameter Store
+func (m MUGConfig) GetSSMSecret(profile string) func(path string) (string, bool) {
+	sess := session.Must(session.NewSessionWithOptions(session.Options{
+		Config:  aws.Config{Region: aws.String(m.Region)},
+		Profile: profile,
+	}))
+	svc := ssm.New(sess)
+
+	return func(path string) (string, bool) {
+		out, err := svc.GetParameter(&ssm.GetParameterInput{
+			Name:           aws.String(path),
+			WithDecryption: aws.Bool(true),
+		})
+		if err != nil {
+			return "", false
+		}
+
+		return aws.StringValue(out.Parameter.Value), true
+	}
+}
+
+// ReadSecretStore reads and decrypts the local secret store of the project
+func (m MUGConfig) ReadSecretStore() *SecretStore {
+	s := &SecretStore{
+		path:        filepath.Join(m.ProjectPath, ".mug", "secrets.enc"),
+		projectName: m.ProjectName,
+		Secrets:     map[string]string{},
+	}
+
+	data, err := readDataFromFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s
+		}
+		log.Fatal(err)
+	}
+
+	s.key = secretKey(m.ProjectName)
+	plain, err := decrypt(s.key, data)
+	if err != nil {
+		log.Fatalf("Error decrypting local secret store %s: %s", s.path, err)
+	}
+	if err := json.Unmarshal(plain, &s.Secrets); err != nil {
+		log.Fatal(err)
+	}
+
+	return s
+}
+
+// Set adds or replaces the secret stored under the given parameter path
+func (s *SecretStore) Set(path, value string) {
+	s.Secrets[path] = value
+}
+
+// Get returns the secret stored under the given parameter path
+func (s *SecretStore) Get(path string) (string, bool) {
+	v, ok := s.Secrets[path]
+	return v, ok
+}
+
+// Write encrypts the local secret store and writes it to .mug/secrets.enc
+func (s *SecretStore) Write() {
+	plain, err := json.Marshal(s.Secrets)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// make sure the key exists
+	if s.key == nil {
+		s.key = secretKey(s.projectName)
+	}
+	data, err := encrypt(s.key, plain)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(s.path, data, 0600); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ResolveSecrets returns the secrets of a resource/ function group resolved for local usage
+func (m MUGConfig) ResolveSecrets(sc ServerlessConfig, r, stage string) map[string]string {
+	res, err := m.NewVariableResolver(sc, r, map[string]string{"stage": stage})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	secrets := map[string]string{}
+	for key, val := range sc.Provider.Environments {
+		if strings.Contains(val, "${file(") || strings.Contains(val, "${ssm:") {
+			v, err := res.Resolve(val)
+			if err != nil {
+				log.Fatalf("Error resolving secret %s of %s: %s (set it with 'mug secret set %s --backend local')", key, r, err, key)
+			}
+			secrets[key] = v
+		}
+	}
+
+	return secrets
+}
+
+// secretKey returns the key of the local secret store either derived from MUG_SECRET_KEY
+// or from the key file in the user's home directory, which is generated if it doesn't exist
+func secretKey(projectName string) []byte {
+	if p := os.Getenv(SecretKeyEnv); len(p) > 0 {
+		k := sha256.Sum256([]byte(p))
+		return k[:]
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatal(err)
+	}
+	kf := filepath.Join(home, ".mug", "keys", projectName+".key")
+
+	data, err := readDataFromFile(kf)
+	if err == nil {
+		k, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			log.Fatalf("Invalid secret key file %s: %s", kf, err)
+		}
+		return k
+	} else if !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	// generate a new key for the project
+	k := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, k); err != nil {
+		log.Fatal(err)
+	}
+	if DryRun {
+		// never print the generated key with the dry run diff
+		Record("generate secret key %s", kf)
+		return k
+	}
+	if err := os.MkdirAll(filepath.Dir(kf), 0700); err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(kf, []byte(hex.EncodeToString(k)), 0600); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Generated new secret key %s, share it with your team or set %s instead", kf, SecretKeyEnv)
+
+	return k
+}
+
+// encrypt seals the plain data with AES-GCM prepending the random nonce
+func encrypt(key, plain []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// decrypt opens data sealed by encrypt
+func decrypt(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("secret store corrupted")
+	}
+	nonce, cipherText := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	return gcm.Open(nil, nonce, cipherText, nil)
+}
+
+// newGCM returns the AES-GCM cipher for the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// SetSecret references the secret in the environment of the ServerlessConfig and removes
+// a plaintext value of the same key from the secrets.yml of the resource/ function group
+func (s *ServerlessConfig) SetSecret(mc MUGConfig, rName, key string) {
+	// make sure map exists
+	if len(s.Provider.Environments) == 0 {
+		s.Provider.Environments = map[string]string{}
+	}
+	s.Provider.Environments[key] = mc.SecretReference(key)
+
+	path := filepath.Join(mc.ProjectPath, "functions", rName, "secrets.yml")
+	data, err := readDataFromFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return
+		}
+		log.Fatal(err)
+	}
+
+	secrets := map[string]string{}
+	if err := yaml.Unmarshal(data, &secrets); err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := secrets[key]; !ok {
+		return
+	}
+	delete(secrets, key)
+	log.Printf("Removed plaintext %s from %s", key, path)
+
+	yml, err := yaml.Marshal(secrets)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := WriteFile(path, yml, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
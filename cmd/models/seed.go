@@ -0,0 +1,346 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/gobuffalo/flect"
+)
+
+// FixtureFormats are the file formats of the fixtures of the local tables
+var FixtureFormats = []string{"json", "yaml"}
+
+// batchSize is the maximum number of items of a DynamoDB batch write
+const batchSize = 25
+
+// localTable is the local DynamoDB table of a resource
+type localTable struct {
+	resource string
+	name     string
+	keys     []string
+}
+
+// Seed writes the fixtures of the resources into their local tables of the mode.
+// If generate is greater than zero, the given number of items is generated from the model instead.
+func (m MUGConfig) Seed(list []string, mode string, generate int) {
+	var svc *dynamodb.DynamoDB
+	if !DryRun {
+		svc = m.localDynamoDB()
+	}
+
+	for _, t := range m.localTables(list, mode) {
+		var items []map[string]interface{}
+		source := "fixtures"
+		if generate > 0 {
+			model, err := ReadModel(m.ProjectPath, t.resource)
+			if err != nil {
+				log.Fatalf("Error reading model of %s: %s", t.resource, err)
+			}
+			items = mockItems(model, generate)
+			source = "mock generator"
+		} else {
+			items = m.readFixtures(t)
+		}
+
+		if len(items) == 0 {
+			log.Printf("No fixtures found for %s in %s, skipping...", t.resource, m.fixturesPath(t.resource))
+			continue
+		}
+		if DryRun {
+			Record("seed %d items from %s into local DynamoDB table %s", len(items), source, t.name)
+			continue
+		}
+
+		if err := batchWrite(svc, t.name, items); err != nil {
+			log.Fatalf("Error seeding table %s: %s", t.name, err)
+		}
+		log.Printf("Seeded %d items from %s into %s", len(items), source, t.name)
+	}
+}
+
+// Dump exports the items of the local tables of the mode to the fixtures of the resources.
+// The fixtures are replaced, so seeding them again restores the tables.
+func (m MUGConfig) Dump(list []string, mode, format string) {
+	if !Contains(FixtureFormats, format) {
+		log.Fatalf("Unknown format %s, choose between %s", format, strings.Join(FixtureFormats, ", "))
+	}
+	svc := m.localDynamoDB()
+
+	for _, t := range m.localTables(list, mode) {
+		items, err := scanTable(svc, t)
+		if err != nil {
+			log.Fatalf("Error dumping table %s: %s", t.name, err)
+		}
+
+		var data []byte
+		if format == "yaml" {
+			data, err = yaml.Marshal(items)
+		} else {
+			data, err = json.MarshalIndent(items, "", "  ")
+			data = append(data, '\n')
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// remove the fixtures of the other formats, they would be seeded as well
+		path := filepath.Join(m.fixturesPath(t.resource), t.resource+"."+format)
+		for _, f := range m.fixtureFiles(t.resource) {
+			if f != path {
+				if err := RemoveAll(f); err != nil {
+					log.Fatal(err)
+				}
+			}
+		}
+		if err := WriteFile(path, data, 0644); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Dumped %d items of %s to %s", len(items), t.name, path)
+	}
+}
+
+// localTables returns the local tables of the mode of the resources in the list, sorted by resource
+func (m MUGConfig) localTables(list []string, mode string) []localTable {
+	var tables []localTable
+	for n, r := range m.Resources {
+		if !Contains(list, n) {
+			continue
+		}
+
+		sc := m.ReadServerlessConfig(n)
+		rName := r.Ident.Pascalize().String() + "DynamoDbTable"
+		res := sc.Resources.Resources[rName]
+		if res == nil {
+			log.Fatalf("Resourse %s not valid. Please check your serverless.yml or your command.", rName)
+		}
+
+		t := localTable{resource: n, name: m.LocalTableName(sc, n, res.Properties, mode)}
+		for _, k := range res.Properties.KeySchema {
+			t.keys = append(t.keys, flect.New(k.AttributeName).Underscore().String())
+		}
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].resource < tables[j].resource })
+
+	return tables
+}
+
+// fixturesPath returns the folder of the fixtures of a resource
+func (m MUGConfig) fixturesPath(r string) string {
+	return filepath.Join(m.ProjectPath, "functions", r, "fixtures")
+}
+
+// fixtureFiles returns the sorted JSON and YAML files in the fixtures folder of a resource
+func (m MUGConfig) fixtureFiles(r string) []string {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(m.fixturesPath(r), pattern))
+		if err != nil {
+			log.Fatal(err)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	return files
+}
+
+// readFixtures reads the items of all fixture files of the table's resource.
+// Items with the same key are only written once, the last one wins.
+func (m MUGConfig) readFixtures(t localTable) []map[string]interface{} {
+	var items []map[string]interface{}
+	index := map[string]int{}
+	for _, f := range m.fixtureFiles(t.resource) {
+		data, err := ReadFile(f)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var list []map[string]interface{}
+		if filepath.Ext(f) == ".json" {
+			err = json.Unmarshal(data, &list)
+		} else {
+			var raw []map[interface{}]interface{}
+			err = yaml.Unmarshal(data, &raw)
+			for _, item := range raw {
+				list = append(list, normalizeYAML(item).(map[string]interface{}))
+			}
+		}
+		if err != nil {
+			log.Fatalf("Error reading fixtures %s, expected a list of items: %s", f, err)
+		}
+
+		for _, item := range list {
+			var key []string
+			for _, k := range t.keys {
+				if _, ok := item[k]; !ok {
+					log.Fatalf("Item in fixtures %s is missing the key attribute %s", f, k)
+				}
+				key = append(key, fmt.Sprint(item[k]))
+			}
+
+			id := strings.Join(key, "/")
+			if i, ok := index[id]; ok {
+				items[i] = item
+				continue
+			}
+			index[id] = len(items)
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// normalizeYAML converts the maps decoded from YAML to maps with string keys, so they can be marshalled
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeYAML(e)
+		}
+	case time.Time:
+		return t.Format(time.RFC3339)
+	}
+
+	return v
+}
+
+// batchWrite puts the items into the table in batches, retrying unprocessed items
+func batchWrite(svc *dynamodb.DynamoDB, table string, items []map[string]interface{}) error {
+	var avs []map[string]*dynamodb.AttributeValue
+	for _, item := range items {
+		av, err := dynamodbattribute.MarshalMap(item)
+		if err != nil {
+			return err
+		}
+		avs = append(avs, av)
+	}
+
+	return batchWriteItems(svc, table, avs)
+}
+
+// batchWriteItems puts the DynamoDB items into the table in batches, retrying unprocessed items
+func batchWriteItems(svc *dynamodb.DynamoDB, table string, items []map[string]*dynamodb.AttributeValue) error {
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var requests []*dynamodb.WriteRequest
+		for _, item := range items[start:end] {
+			requests = append(requests, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: item}})
+		}
+
+		pending := map[string][]*dynamodb.WriteRequest{table: requests}
+		for retry := 0; len(pending[table]) > 0; retry++ {
+			if retry > 0 {
+				time.Sleep(time.Duration(retry*100) * time.Millisecond)
+			}
+			if retry > 10 {
+				return fmt.Errorf("%d items couldn't be processed", len(pending[table]))
+			}
+
+			out, err := svc.BatchWriteItem(&dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+
+	return nil
+}
+
+// scanTable returns all items of the table sorted by their keys
+func scanTable(svc *dynamodb.DynamoDB, t localTable) ([]map[string]interface{}, error) {
+	avs, err := scanItems(svc, t.name)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]interface{}
+	if err := dynamodbattribute.UnmarshalListOfMaps(avs, &items); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, k := range t.keys {
+			a, b := fmt.Sprint(items[i][k]), fmt.Sprint(items[j][k])
+			if a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+
+	return items, nil
+}
+
+// scanItems returns all DynamoDB items of the table
+func scanItems(svc *dynamodb.DynamoDB, table string) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	err := svc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(table)}, func(out *dynamodb.ScanOutput, last bool) bool {
+		items = append(items, out.Items...)
+		return true
+	})
+
+	return items, err
+}
+
+// mockItems returns n items of the model with generated data, which is the same on every run
+func mockItems(model Model, n int) []map[string]interface{} {
+	items := make([]map[string]interface{}, n)
+	for i := range items {
+		items[i] = mockModel(model, i+1)
+	}
+
+	return items
+}
+
+// mockModel returns the i-th generated item of the model, varying the sample values by the index
+func mockModel(model Model, i int) map[string]interface{} {
+	item := sampleModel(model)
+	for _, a := range model.Attributes {
+		key := a.Ident.Underscore().String()
+		switch v := item[key].(type) {
+		case string:
+			switch {
+			case a.GoType == "uuid.UUID" || strings.HasSuffix(strings.ToLower(a.Name), "id"):
+				item[key] = exampleUUID(model.Name, a.Name, fmt.Sprint(i))
+			case a.GoType == "time.Time" || a.GoType == "*time.Time":
+				t, _ := time.Parse(time.RFC3339, v)
+				item[key] = t.Add(time.Duration(i) * time.Hour).Format(time.RFC3339)
+			case strings.Contains(v, "@"):
+				item[key] = strings.Replace(v, "@", fmt.Sprintf("+%d@", i), 1)
+			default:
+				item[key] = fmt.Sprintf("%s %d", v, i)
+			}
+		case int:
+			item[key] = v * i
+		case float64:
+			item[key] = v * float64(i)
+		case bool:
+			item[key] = i%2 == 1
+		}
+	}
+
+	return item
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestReadFixtures(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"functions/post/fixtures/01-posts.json": `[
+  {"author": "jane", "slug": "hello", "title": "Hello"},
+  {"author": "jane", "slug": "draft", "title": "Draft", "tags": ["go", "aws"]}
+]`,
+		"functions/post/fixtures/02-update.yaml": `- author: jane
+  slug: draft
+  title: Published
+  published: 2019-06-01T10:00:00Z
+  meta:
+    views: 3
+- author: john
+  slug: hello
+  title: Hello from John
+`,
+		"functions/post/fixtures/README.md": "not a fixture",
+	})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectPath: dir}
+
+	items := m.readFixtures(localTable{resource: "post", name: "blog-posts-debug", keys: []string{"author", "slug"}})
+
+	// the items are unique by their keys, later files replace earlier items in place
+	want := []map[string]interface{}{
+		{"author": "jane", "slug": "hello", "title": "Hello"},
+		{"author": "jane", "slug": "draft", "title": "Published", "published": "2019-06-01T10:00:00Z", "meta": map[string]interface{}{"views": 3}},
+		{"author": "john", "slug": "hello", "title": "Hello from John"},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("readFixtures() = %v, want %v", items, want)
+	}
+}
+
+func TestMockItems(t *testing.T) {
+	model := New("user", false, "name,email,age:int,active:bool", KitResource{GenerateID: true}.options())
+
+	items := mockItems(model, 3)
+	if !reflect.DeepEqual(items, mockItems(model, 3)) {
+		t.Error("mockItems() isn't stable between runs")
+	}
+
+	ids := map[interface{}]bool{}
+	for i, item := range items {
+		ids[item["id"]] = true
+		want := map[string]interface{}{
+			"id":     exampleUUID("user", "id", strconv.Itoa(i+1)),
+			"name":   "Jane Doe " + strconv.Itoa(i+1),
+			"age":    42 * (i + 1),
+			"active": i%2 == 0,
+		}
+		for k, v := range want {
+			if !reflect.DeepEqual(item[k], v) {
+				t.Errorf("item %d %s = %v, want %v", i, k, item[k], v)
+			}
+		}
+	}
+	if len(ids) != len(items) {
+		t.Errorf("mockItems() generated duplicate ids: %v", ids)
+	}
+	if items[1]["email"] != "jane.doe+2@example.com" {
+		t.Errorf("email = %v, want jane.doe+2@example.com", items[1]["email"])
+	}
+}
@@ -0,0 +1,506 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// AuthorizerMock checks the identity source of authorized routes and passes the claims of the token unverified
+	AuthorizerMock = "mock"
+	// AuthorizerOff ignores the authorizers of the routes
+	AuthorizerOff = "off"
+
+	// corsHeaders are the headers allowed by the CORS configuration of the serverless framework
+	corsHeaders = "Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token,X-Amz-User-Agent"
+)
+
+// LocalAPI emulates API Gateway routing the http events of the functions to the local functions
+type LocalAPI struct {
+	Stage      string
+	Authorizer string
+
+	routes    []*route
+	functions map[string]*LocalFunction
+}
+
+// route is an http event of a function
+type route struct {
+	method     string
+	path       string
+	segments   []string
+	cors       bool
+	private    bool
+	authorizer *Authorizer
+	scopes     []string
+	fn         *LocalFunction
+}
+
+// proxyRequest is the API Gateway proxy integration request passed to the functions
+type proxyRequest struct {
+	Resource                        string              `json:"resource"`
+	Path                            string              `json:"path"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string   `json:"pathParameters"`
+	StageVariables                  map[string]string   `json:"stageVariables"`
+	RequestContext                  proxyRequestContext `json:"requestContext"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+}
+
+// proxyRequestContext is the request context of the API Gateway proxy integration request
+type proxyRequestContext struct {
+	AccountID        string                 `json:"accountId"`
+	ResourceID       string                 `json:"resourceId"`
+	Stage            string                 `json:"stage"`
+	RequestID        string                 `json:"requestId"`
+	Identity         map[string]string      `json:"identity"`
+	ResourcePath     string                 `json:"resourcePath"`
+	Authorizer       map[string]interface{} `json:"authorizer,omitempty"`
+	HTTPMethod       string                 `json:"httpMethod"`
+	APIID            string                 `json:"apiId"`
+	RequestTimeEpoch int64                  `json:"requestTimeEpoch"`
+}
+
+// proxyResponse is the API Gateway proxy integration response returned by the functions
+type proxyResponse struct {
+	StatusCode        int                 `json:"statusCode"`
+	Headers           map[string]string   `json:"headers"`
+	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
+	Body              string              `json:"body"`
+	IsBase64Encoded   bool                `json:"isBase64Encoded"`
+}
+
+// NewLocalAPI returns the local API of the http events of the given resources/ function groups
+// using the local tables of the mode. The binaries have to be built with BuildNative.
+func (m MUGConfig) NewLocalAPI(list []string, stage, mode, authorizer string) *LocalAPI {
+	a := &LocalAPI{
+		Stage:      stage,
+		Authorizer: authorizer,
+		functions:  m.LocalFunctions(list, stage, mode),
+	}
+
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		for _, name := range sortedKeys(sc.Functions) {
+			for _, ev := range sc.Functions[name].Events {
+				if ev.HTTP == nil {
+					continue
+				}
+				path := strings.Trim(ev.HTTP.Path, "/")
+				a.routes = append(a.routes, &route{
+					method:     strings.ToUpper(ev.HTTP.Method),
+					path:       "/" + path,
+					segments:   strings.Split(path, "/"),
+					cors:       ev.HTTP.CORS,
+					private:    ev.HTTP.Private,
+					authorizer: ev.HTTP.Authorizer,
+					scopes:     ev.HTTP.Scopes,
+					fn:         a.functions[name],
+				})
+			}
+		}
+	}
+
+	// static segments take precedence over path parameters like in API Gateway
+	sort.SliceStable(a.routes, func(i, j int) bool {
+		return a.routes[i].rank() > a.routes[j].rank()
+	})
+
+	return a
+}
+
+// ListenAndServe serves the API on the address until it is interrupted and stops the functions afterwards
+func (a *LocalAPI) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: a}
+
+	for _, r := range a.routes {
+		log.Printf("%-7s http://%s%s -> %s/%s", r.method, l.Addr(), r.path, r.fn.Resource, r.fn.Name)
+	}
+	log.Printf("Serving the API at http://%s, stop with Ctrl+C", l.Addr())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+	go func() {
+		<-interrupt
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	err = server.Serve(l)
+	a.Stop()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop stops the processes of all functions
+func (a *LocalAPI) Stop() {
+	for _, f := range a.functions {
+		f.Stop()
+	}
+}
+
+// ServeHTTP routes the request to the function of the matching http event
+func (a *LocalAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	status := a.serve(w, r)
+	log.Printf("%s %s %d %s", r.Method, r.URL.Path, status, time.Since(start).Round(time.Millisecond))
+}
+
+// serve handles the request and returns the status code of the response
+func (a *LocalAPI) serve(w http.ResponseWriter, r *http.Request) int {
+	rt, params := a.match(r.Method, r.URL.Path)
+	if rt == nil {
+		if r.Method == http.MethodOptions {
+			if a.corsRoute(r.URL.Path) != nil {
+				setCORSHeaders(w.Header())
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(a.methods(r.URL.Path), ","))
+				w.WriteHeader(http.StatusOK)
+				return http.StatusOK
+			}
+		}
+		// API Gateway answers unknown routes like this
+		return writeMessage(w, http.StatusForbidden, "Missing Authentication Token")
+	}
+	if rt.cors {
+		setCORSHeaders(w.Header())
+	}
+
+	if rt.private && len(r.Header.Get("X-Api-Key")) == 0 {
+		return writeMessage(w, http.StatusForbidden, "Forbidden")
+	}
+
+	authorizer, err := a.authorize(rt, r)
+	if err != nil {
+		log.Printf("Authorizer of %s %s: %s", rt.method, rt.path, err)
+		return writeMessage(w, http.StatusUnauthorized, "Unauthorized")
+	}
+
+	payload, err := a.proxyRequest(rt, r, params, authorizer)
+	if err != nil {
+		return writeMessage(w, http.StatusBadRequest, err.Error())
+	}
+
+	out, err := rt.fn.Invoke(payload)
+	if err != nil {
+		log.Printf("%s/%s failed: %s", rt.fn.Resource, rt.fn.Name, err)
+		return writeMessage(w, http.StatusBadGateway, "Internal server error")
+	}
+
+	resp := proxyResponse{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		log.Printf("%s/%s returned an invalid proxy response: %s", rt.fn.Resource, rt.fn.Name, err)
+		return writeMessage(w, http.StatusBadGateway, "Internal server error")
+	}
+
+	return writeProxyResponse(w, resp)
+}
+
+// match returns the route matching the method and path with the path parameters
+func (a *LocalAPI) match(method, path string) (*route, map[string]string) {
+	for _, rt := range a.routes {
+		if rt.method != "ANY" && rt.method != method {
+			continue
+		}
+		if params, ok := rt.match(path); ok {
+			return rt, params
+		}
+	}
+
+	return nil, nil
+}
+
+// corsRoute returns a route with CORS enabled matching the path with any method
+func (a *LocalAPI) corsRoute(path string) *route {
+	for _, rt := range a.routes {
+		if _, ok := rt.match(path); ok && rt.cors {
+			return rt
+		}
+	}
+
+	return nil
+}
+
+// methods returns the methods of the routes matching the path
+func (a *LocalAPI) methods(path string) []string {
+	methods := []string{http.MethodOptions}
+	for _, rt := range a.routes {
+		if _, ok := rt.match(path); ok {
+			methods = appendStringIfMissing(methods, rt.method)
+		}
+	}
+
+	return methods
+}
+
+// match checks whether the path matches the route and returns the path parameters.
+// {name} matches a single segment, {name+} the rest of the path.
+func (rt *route) match(path string) (map[string]string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	params := map[string]string{}
+
+	for i, s := range rt.segments {
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "+}") {
+			if i >= len(segments) {
+				return nil, false
+			}
+			params[strings.TrimSuffix(s[1:], "+}")] = strings.Join(segments[i:], "/")
+			return params, true
+		}
+		if i >= len(segments) {
+			return nil, false
+		}
+		if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+			if len(segments[i]) == 0 {
+				return nil, false
+			}
+			params[s[1:len(s)-1]] = segments[i]
+			continue
+		}
+		if s != segments[i] {
+			return nil, false
+		}
+	}
+	if len(segments) != len(rt.segments) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// rank orders the routes by their static segments, greedy path parameters match last
+func (rt *route) rank() int {
+	rank := 0
+	for _, s := range rt.segments {
+		switch {
+		case strings.HasSuffix(s, "+}"):
+			rank -= 100
+		case !strings.HasPrefix(s, "{"):
+			rank++
+		}
+	}
+
+	return rank
+}
+
+// authorize applies the authorizer of the route in the configured mode and returns the authorizer context
+func (a *LocalAPI) authorize(rt *route, r *http.Request) (map[string]interface{}, error) {
+	if rt.authorizer == nil || a.Authorizer == AuthorizerOff {
+		return nil, nil
+	}
+
+	header := "Authorization"
+	if src := rt.authorizer.IdentitySource; strings.HasPrefix(src, "method.request.header.") {
+		header = strings.TrimPrefix(src, "method.request.header.")
+	}
+	token := r.Header.Get(header)
+	if len(token) == 0 {
+		return nil, fmt.Errorf("header %s is missing", header)
+	}
+	if expr := rt.authorizer.IdentityValidationExpression; len(expr) > 0 {
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, err
+		}
+		if !re.MatchString(token) {
+			return nil, fmt.Errorf("header %s doesn't match %s", header, expr)
+		}
+	}
+
+	claims, err := jwtClaims(strings.TrimPrefix(token, "Bearer "))
+	cognito := rt.authorizer.Type == "COGNITO_USER_POOLS" || strings.Contains(rt.authorizer.ARN, "cognito-idp") || strings.Contains(rt.authorizer.ARN, "COGNITO")
+	if err != nil {
+		if cognito {
+			return nil, err
+		}
+		// custom authorizers receive the token as principal
+		return map[string]interface{}{"principalId": token}, nil
+	}
+
+	if exp, ok := claims["exp"].(float64); ok && time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if len(rt.scopes) > 0 {
+		scope, _ := claims["scope"].(string)
+		granted := false
+		for _, s := range strings.Fields(scope) {
+			if Contains(rt.scopes, s) {
+				granted = true
+			}
+		}
+		if !granted {
+			return nil, fmt.Errorf("token has none of the scopes %s", strings.Join(rt.scopes, ", "))
+		}
+	}
+
+	if cognito {
+		return map[string]interface{}{"claims": claims}, nil
+	}
+	principal, _ := claims["sub"].(string)
+	ctx := map[string]interface{}{"principalId": principal}
+	for k, v := range claims {
+		ctx[k] = v
+	}
+	return ctx, nil
+}
+
+// jwtClaims returns the claims of a JWT without verifying its signature
+func jwtClaims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is no JWT")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %s", err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(data, &claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %s", err)
+	}
+
+	return claims, nil
+}
+
+// proxyRequest returns the proxy integration request of the http request
+func (a *LocalAPI) proxyRequest(rt *route, r *http.Request, params map[string]string, authorizer map[string]interface{}) ([]byte, error) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req := proxyRequest{
+		Resource:   rt.path,
+		Path:       r.URL.Path,
+		HTTPMethod: r.Method,
+		RequestContext: proxyRequestContext{
+			AccountID:    "000000000000",
+			ResourceID:   "local",
+			Stage:        a.Stage,
+			RequestID:    requestID(),
+			ResourcePath: rt.path,
+			Authorizer:   authorizer,
+			HTTPMethod:   r.Method,
+			APIID:        "local",
+			Identity: map[string]string{
+				"sourceIp":  sourceIP(r),
+				"userAgent": r.UserAgent(),
+			},
+			RequestTimeEpoch: time.Now().UnixNano() / int64(time.Millisecond),
+		},
+	}
+	if len(params) > 0 {
+		req.PathParameters = params
+	}
+
+	if len(r.Header) > 0 {
+		req.Headers, req.MultiValueHeaders = map[string]string{}, map[string][]string{}
+		for k, v := range r.Header {
+			req.Headers[k] = v[len(v)-1]
+			req.MultiValueHeaders[k] = v
+		}
+		if len(r.Host) > 0 {
+			req.Headers["Host"], req.MultiValueHeaders["Host"] = r.Host, []string{r.Host}
+		}
+	}
+
+	if query := r.URL.Query(); len(query) > 0 {
+		req.QueryStringParameters, req.MultiValueQueryStringParameters = map[string]string{}, map[string][]string{}
+		for k, v := range query {
+			req.QueryStringParameters[k] = v[len(v)-1]
+			req.MultiValueQueryStringParameters[k] = v
+		}
+	}
+
+	if utf8.Valid(body) {
+		req.Body = string(body)
+	} else {
+		req.Body, req.IsBase64Encoded = base64.StdEncoding.EncodeToString(body), true
+	}
+
+	return json.Marshal(req)
+}
+
+// writeProxyResponse writes the proxy integration response of a function
+func writeProxyResponse(w http.ResponseWriter, resp proxyResponse) int {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	for k, vs := range resp.MultiValueHeaders {
+		w.Header().Del(k)
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+
+	body := []byte(resp.Body)
+	if resp.IsBase64Encoded {
+		data, err := base64.StdEncoding.DecodeString(resp.Body)
+		if err != nil {
+			return writeMessage(w, http.StatusBadGateway, "Internal server error")
+		}
+		body = data
+	}
+
+	status := resp.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+
+	return status
+}
+
+// writeMessage writes an error response formatted like the responses of API Gateway
+func writeMessage(w http.ResponseWriter, status int, message string) int {
+	data, _ := json.Marshal(map[string]string{"message": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+
+	return status
+}
+
+// setCORSHeaders sets the headers of the default CORS configuration of the serverless framework
+func setCORSHeaders(h http.Header) {
+	h.Set("Access-Control-Allow-Origin", "*")
+	h.Set("Access-Control-Allow-Headers", corsHeaders)
+	h.Set("Access-Control-Allow-Credentials", "false")
+}
+
+// sourceIP returns the ip of the client
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
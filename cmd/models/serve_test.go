@@ -0,0 +1,225 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// routedService is the serverless.yml of a resource with the http events used by the local API tests
+const routedService = `service:
+  name: svc-note
+provider:
+  name: aws
+  runtime: provided.al2023
+functions:
+  create_note:
+    handler: bin/create
+    events:
+    - http:
+        path: notes
+        method: post
+        cors: true
+  read_note:
+    handler: bin/read
+    events:
+    - http:
+        path: notes/{id}
+        method: get
+        cors: true
+  search_notes:
+    handler: bin/search
+    events:
+    - http:
+        path: notes/search
+        method: get
+  admin:
+    handler: bin/admin
+    events:
+    - http:
+        path: admin
+        method: get
+        private: true
+  me:
+    handler: bin/me
+    events:
+    - http:
+        path: me
+        method: get
+        authorizer:
+          arn: arn:aws:cognito-idp:eu-central-1:000000000000:userpool/eu-central-1_abc
+        scopes:
+        - notes/read
+  files:
+    handler: bin/files
+    events:
+    - http:
+        path: files/{path+}
+        method: any
+`
+
+func TestRouteMatch(t *testing.T) {
+	tests := []struct {
+		route  string
+		path   string
+		params map[string]string
+		ok     bool
+	}{
+		{"notes", "/notes", map[string]string{}, true},
+		{"notes", "/notes/", map[string]string{}, true},
+		{"notes", "/notes/1", nil, false},
+		{"notes/{id}", "/notes/1", map[string]string{"id": "1"}, true},
+		{"notes/{id}", "/notes", nil, false},
+		{"notes/{id}", "/notes//", nil, false},
+		{"notes/{id}/tags/{tag}", "/notes/1/tags/go", map[string]string{"id": "1", "tag": "go"}, true},
+		{"files/{path+}", "/files/a/b.txt", map[string]string{"path": "a/b.txt"}, true},
+		{"files/{path+}", "/files", nil, false},
+	}
+
+	for _, tt := range tests {
+		rt := &route{segments: strings.Split(tt.route, "/")}
+		params, ok := rt.match(tt.path)
+		if ok != tt.ok || !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("route %s match(%s) = %v, %v, want %v, %v", tt.route, tt.path, params, ok, tt.params, tt.ok)
+		}
+	}
+}
+
+// jwt returns an unsigned JWT with the claims
+func jwt(claims string) string {
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + ".sig"
+}
+
+func TestLocalAPI(t *testing.T) {
+	dir := writeProject(t, map[string]string{"functions/note/serverless.yml": routedService})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1"}
+
+	a := m.NewLocalAPI([]string{"note"}, "dev", "debug", AuthorizerMock)
+	// each function answers with the function name and the proxy request it received
+	for name, f := range a.functions {
+		name := name
+		defer fakeRuntime(t, f, func(payload []byte) ([]byte, *FunctionError) {
+			req := proxyRequest{}
+			if err := json.Unmarshal(payload, &req); err != nil {
+				return nil, &FunctionError{Message: err.Error()}
+			}
+			body, _ := json.Marshal(map[string]interface{}{
+				"function":   name,
+				"resource":   req.Resource,
+				"params":     req.PathParameters,
+				"query":      req.QueryStringParameters,
+				"body":       req.Body,
+				"authorizer": req.RequestContext.Authorizer,
+			})
+			out, _ := json.Marshal(proxyResponse{StatusCode: http.StatusCreated, Headers: map[string]string{"X-Function": name}, Body: string(body)})
+			return out, nil
+		})()
+	}
+	server := httptest.NewServer(a)
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		header   map[string]string
+		body     string
+		status   int
+		function string
+		want     map[string]interface{}
+		cors     string
+	}{
+		{
+			name: "static route", method: http.MethodPost, path: "/notes?draft=true", body: `{"title":"t"}`,
+			status: http.StatusCreated, function: "create_note", cors: "*",
+			want: map[string]interface{}{"resource": "/notes", "query": map[string]interface{}{"draft": "true"}, "body": `{"title":"t"}`},
+		},
+		{
+			name: "path parameter", method: http.MethodGet, path: "/notes/42",
+			status: http.StatusCreated, function: "read_note", cors: "*",
+			want: map[string]interface{}{"resource": "/notes/{id}", "params": map[string]interface{}{"id": "42"}},
+		},
+		{
+			name: "static segment before parameter", method: http.MethodGet, path: "/notes/search",
+			status: http.StatusCreated, function: "search_notes",
+		},
+		{
+			name: "greedy parameter with any method", method: http.MethodDelete, path: "/files/a/b.txt",
+			status: http.StatusCreated, function: "files",
+			want: map[string]interface{}{"params": map[string]interface{}{"path": "a/b.txt"}},
+		},
+		{name: "unknown route", method: http.MethodGet, path: "/users", status: http.StatusForbidden},
+		{name: "wrong method", method: http.MethodDelete, path: "/notes", status: http.StatusForbidden},
+		{name: "cors preflight", method: http.MethodOptions, path: "/notes", status: http.StatusOK, cors: "*"},
+		{name: "private without api key", method: http.MethodGet, path: "/admin", status: http.StatusForbidden},
+		{
+			name: "private with api key", method: http.MethodGet, path: "/admin", header: map[string]string{"X-Api-Key": "key"},
+			status: http.StatusCreated, function: "admin",
+		},
+		{name: "authorizer without token", method: http.MethodGet, path: "/me", status: http.StatusUnauthorized},
+		{
+			name: "authorizer without scope", method: http.MethodGet, path: "/me",
+			header: map[string]string{"Authorization": jwt(`{"sub":"u1","scope":"notes/write"}`)},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "authorizer with expired token", method: http.MethodGet, path: "/me",
+			header: map[string]string{"Authorization": jwt(`{"sub":"u1","scope":"notes/read","exp":1}`)},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "authorizer", method: http.MethodGet, path: "/me",
+			header: map[string]string{"Authorization": "Bearer " + jwt(`{"sub":"u1","scope":"notes/read"}`)},
+			status: http.StatusCreated, function: "me",
+			want: map[string]interface{}{"authorizer": map[string]interface{}{"claims": map[string]interface{}{"sub": "u1", "scope": "notes/read"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			data, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.status, data)
+			}
+			if got := resp.Header.Get("Access-Control-Allow-Origin"); got != tt.cors {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.cors)
+			}
+			if got := resp.Header.Get("X-Function"); got != tt.function {
+				t.Errorf("routed to %q, want %q", got, tt.function)
+			}
+			if len(tt.function) == 0 {
+				return
+			}
+
+			got := map[string]interface{}{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if !reflect.DeepEqual(got[k], v) {
+					t.Errorf("%s = %v, want %v", k, got[k], v)
+				}
+			}
+		})
+	}
+}
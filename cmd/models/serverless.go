@@ -1,7 +1,7 @@
 package models
 
 import (
-	"io/ioutil"
+	"bytes"
 	"log"
 	"os"
 	"path/filepath"
@@ -35,6 +35,7 @@ type Service struct {
 type Provider struct {
 	Name                string
 	Runtime             string
+	Architecture        string `yaml:"architecture,omitempty"`
 	Region              string
 	Stage               string
 	StackName           string            `yaml:"stackName,omitempty"`
@@ -85,6 +86,7 @@ type LogConfig struct {
 type Package struct {
 	Excludes     []string `yaml:"exclude,omitempty"`
 	Includes     []string `yaml:"include,omitempty"`
+	Artifact     string   `yaml:"artifact,omitempty"`
 	Individually bool     `yaml:",omitempty"`
 }
 
@@ -97,6 +99,7 @@ type ServerlessFunction struct {
 	MemorySize          int               `yaml:",omitempty"`
 	ReservedConcurrency int               `yaml:"reservedConcurrency,omitempty"`
 	RunTime             string            `yaml:"runtime,omitempty"`
+	Architecture        string            `yaml:"architecture,omitempty"`
 	Timeout             int               `yaml:",omitempty"`
 	AWSKMSKeyARN        string            `yaml:"awsKmsKeyArn,omitempty"`
 	Environments        map[string]string `yaml:"environment,omitempty"`
@@ -326,7 +329,7 @@ func NewDefaultServerlessConfig() ServerlessConfig {
 	s := ServerlessConfig{
 		Provider: Provider{
 			Name:    "aws",
-			Runtime: "go1.x",
+			Runtime: GoRuntime,
 			Stage:   "${opt:stage, 'dev'}",
 			RoleStatements: []RoleStatement{
 				RoleStatement{
@@ -374,8 +377,26 @@ func (s *ServerlessConfig) updateSecrets(path string) {
 }
 
 func (s *ServerlessConfig) updateEnv(projectPath, resourcePath string) {
-	// read env file
-	if env, _ := godotenv.Read(filepath.Join(projectPath, ".env"), filepath.Join(resourcePath, ".env")); len(env) > 0 {
+	// read env files, the resource environment takes precedence
+	env := map[string]string{}
+	for _, path := range []string{filepath.Join(projectPath, ".env"), filepath.Join(resourcePath, ".env")} {
+		data, err := ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			log.Fatal(err)
+		}
+		vars, err := godotenv.Parse(bytes.NewReader(data))
+		if err != nil {
+			log.Fatalf("Error parsing %s: %s", path, err)
+		}
+		for k, v := range vars {
+			env[k] = v
+		}
+	}
+
+	if len(env) > 0 {
 		// merge environment into ServerlessConfig
 		if err := mergo.Merge(&s.Provider.Environments, env); err != nil {
 			log.Fatal(err)
@@ -395,7 +416,7 @@ func (s *ServerlessConfig) Write(projectPath, mn string) {
 	fp := filepath.Join(rp, "serverless.yml")
 	// make sure directory exists
 	if _, err := os.Stat(rp); os.IsNotExist(err) {
-		if err := os.MkdirAll(rp, 0755); err != nil {
+		if err := MkdirAll(rp, 0755); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -405,7 +426,7 @@ func (s *ServerlessConfig) Write(projectPath, mn string) {
 		log.Fatal(err)
 	}
 
-	err = ioutil.WriteFile(fp, yml, 0644)
+	err = WriteFile(fp, yml, 0644)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -483,14 +504,6 @@ func (s *ServerlessConfig) AddFunction(fn *Function) {
 	}
 	s.Functions[fn.Name] = &ServerlessFunction{
 		Handler: "bin/" + fn.Handler,
-		Package: Package{
-			Includes: []string{
-				"bin/" + fn.Handler,
-			},
-			Excludes: []string{
-				"./**",
-			},
-		},
 		Events: []Events{
 			Events{
 				HTTP: &HTTPEvent{
@@ -501,6 +514,7 @@ func (s *ServerlessConfig) AddFunction(fn *Function) {
 			},
 		},
 	}
+	s.updatePackage(fn.Name)
 }
 
 // RemoveFunction removes a function from the ServerlessConfig
@@ -533,7 +547,7 @@ func (s *ServerlessConfig) AddPoolEnv(mc MUGConfig, rName, pool string) {
 		log.Fatal(err)
 	}
 
-	err = ioutil.WriteFile(path, yml, 0644)
+	err = WriteFile(path, yml, 0644)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// tableTimeout is how long mug waits for a local table to become active after an update
+const tableTimeout = 2 * time.Minute
+
+// migrateTable updates the existing local table to the properties of the resource without losing its items.
+// Global secondary indexes are added and removed in place, other changes recreate the table and copy the items.
+func migrateTable(svc *dynamodb.DynamoDB, tableName string, props Properties) {
+	want := tableInput(tableName, props)
+	out, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+	if err != nil {
+		log.Fatalf("Error describing table %s: %s", tableName, err)
+	}
+	have := out.Table
+
+	var changes, deletes, creates []string
+	recreate := false
+
+	if from, to := keysString(have.KeySchema), keysString(want.KeySchema); from != to {
+		changes = append(changes, fmt.Sprintf("key schema changed from %s to %s", from, to))
+		recreate = true
+	}
+
+	haveTypes, wantTypes := attributeTypes(have.AttributeDefinitions), attributeTypes(want.AttributeDefinitions)
+	for _, a := range sortedStrings(wantTypes) {
+		if t, ok := haveTypes[a]; ok && t != wantTypes[a] {
+			changes = append(changes, fmt.Sprintf("type of attribute %s changed from %s to %s", a, t, wantTypes[a]))
+			recreate = true
+		}
+	}
+
+	haveLSI, wantLSI := map[string]string{}, map[string]string{}
+	for _, i := range have.LocalSecondaryIndexes {
+		haveLSI[*i.IndexName] = indexString(i.KeySchema, i.Projection)
+	}
+	for _, i := range want.LocalSecondaryIndexes {
+		wantLSI[*i.IndexName] = indexString(i.KeySchema, i.Projection)
+	}
+	if !reflect.DeepEqual(haveLSI, wantLSI) {
+		changes = append(changes, fmt.Sprintf("local secondary indexes changed from %v to %v", haveLSI, wantLSI))
+		recreate = true
+	}
+
+	haveGSI, wantGSI := map[string]string{}, map[string]string{}
+	for _, i := range have.GlobalSecondaryIndexes {
+		haveGSI[*i.IndexName] = indexString(i.KeySchema, i.Projection)
+	}
+	for _, i := range want.GlobalSecondaryIndexes {
+		wantGSI[*i.IndexName] = indexString(i.KeySchema, i.Projection)
+	}
+	for _, name := range sortedStrings(haveGSI) {
+		if to, ok := wantGSI[name]; !ok {
+			changes = append(changes, fmt.Sprintf("global secondary index %s removed", name))
+			deletes = append(deletes, name)
+		} else if from := haveGSI[name]; from != to {
+			changes = append(changes, fmt.Sprintf("global secondary index %s changed from %s to %s", name, from, to))
+			deletes = append(deletes, name)
+			creates = append(creates, name)
+		}
+	}
+	for _, name := range sortedStrings(wantGSI) {
+		if _, ok := haveGSI[name]; !ok {
+			changes = append(changes, fmt.Sprintf("global secondary index %s added", name))
+			creates = append(creates, name)
+		}
+	}
+
+	if len(changes) == 0 {
+		log.Printf("Table %s is up to date, skipping creation...", tableName)
+		return
+	}
+	for _, c := range changes {
+		log.Printf("Table %s: %s", tableName, c)
+	}
+
+	if recreate {
+		recreateTable(svc, want)
+	} else {
+		updateIndexes(svc, want, deletes, creates)
+	}
+}
+
+// recreateTable recreates the table with the input, copying the items which fit the new schema
+func recreateTable(svc *dynamodb.DynamoDB, input *dynamodb.CreateTableInput) {
+	tableName := *input.TableName
+	items, err := scanItems(svc, tableName)
+	if err != nil {
+		log.Fatalf("Error reading the items of table %s: %s", tableName, err)
+	}
+
+	var fitting []map[string]*dynamodb.AttributeValue
+	for _, item := range items {
+		if itemFits(item, input) {
+			fitting = append(fitting, item)
+		}
+	}
+
+	deleteTable(svc, tableName)
+	if _, err := svc.CreateTable(input); err != nil {
+		log.Fatalf("Error creating table %s: %s", tableName, err)
+	}
+	if err := batchWriteItems(svc, tableName, fitting); err != nil {
+		log.Fatalf("Error copying the items into table %s: %s", tableName, err)
+	}
+
+	log.Printf("Table %s recreated, %d items copied", tableName, len(fitting))
+	if skipped := len(items) - len(fitting); skipped > 0 {
+		log.Printf("%d items of table %s skipped, they don't have the key attributes of the new schema", skipped, tableName)
+	}
+}
+
+// updateIndexes deletes and creates the global secondary indexes of the table one after another
+func updateIndexes(svc *dynamodb.DynamoDB, input *dynamodb.CreateTableInput, deletes, creates []string) {
+	tableName := *input.TableName
+	types := attributeTypes(input.AttributeDefinitions)
+
+	for _, name := range deletes {
+		_, err := svc.UpdateTable(&dynamodb.UpdateTableInput{
+			TableName: input.TableName,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(name)}},
+			},
+		})
+		if err != nil {
+			log.Fatalf("Error deleting index %s of table %s: %s", name, tableName, err)
+		}
+		waitForTable(svc, tableName)
+		log.Printf("Index %s of table %s deleted", name, tableName)
+	}
+
+	for _, name := range creates {
+		var index *dynamodb.GlobalSecondaryIndex
+		for _, i := range input.GlobalSecondaryIndexes {
+			if *i.IndexName == name {
+				index = i
+			}
+		}
+
+		// only the attributes of the new index may be defined
+		var attributes []*dynamodb.AttributeDefinition
+		for _, k := range index.KeySchema {
+			attributes = append(attributes, &dynamodb.AttributeDefinition{
+				AttributeName: k.AttributeName,
+				AttributeType: aws.String(types[*k.AttributeName]),
+			})
+		}
+
+		_, err := svc.UpdateTable(&dynamodb.UpdateTableInput{
+			TableName:            input.TableName,
+			AttributeDefinitions: attributes,
+			GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+				{Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+					IndexName:             index.IndexName,
+					KeySchema:             index.KeySchema,
+					Projection:            index.Projection,
+					ProvisionedThroughput: index.ProvisionedThroughput,
+				}},
+			},
+		})
+		if err != nil {
+			log.Fatalf("Error creating index %s of table %s: %s", name, tableName, err)
+		}
+		waitForTable(svc, tableName)
+		log.Printf("Index %s of table %s created", name, tableName)
+	}
+}
+
+// waitForTable waits until the table and all of its indexes are active
+func waitForTable(svc *dynamodb.DynamoDB, tableName string) {
+	deadline := time.Now().Add(tableTimeout)
+	for {
+		out, err := svc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+		if err != nil {
+			log.Fatalf("Error describing table %s: %s", tableName, err)
+		}
+
+		active := aws.StringValue(out.Table.TableStatus) == dynamodb.TableStatusActive
+		for _, i := range out.Table.GlobalSecondaryIndexes {
+			active = active && aws.StringValue(i.IndexStatus) == dynamodb.IndexStatusActive
+		}
+		if active {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			log.Fatalf("Table %s didn't become active within %s", tableName, tableTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// itemFits checks whether the item has the key attributes of the table and local indexes with the defined types
+// and whether the attributes of the global indexes it has are of the defined types
+func itemFits(item map[string]*dynamodb.AttributeValue, input *dynamodb.CreateTableInput) bool {
+	types := attributeTypes(input.AttributeDefinitions)
+
+	required := input.KeySchema
+	for _, i := range input.LocalSecondaryIndexes {
+		required = append(required, i.KeySchema...)
+	}
+	for _, k := range required {
+		if !hasType(item[*k.AttributeName], types[*k.AttributeName]) {
+			return false
+		}
+	}
+
+	for _, i := range input.GlobalSecondaryIndexes {
+		for _, k := range i.KeySchema {
+			if av, ok := item[*k.AttributeName]; ok && !hasType(av, types[*k.AttributeName]) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// hasType checks whether the attribute value is of the scalar type
+func hasType(av *dynamodb.AttributeValue, t string) bool {
+	if av == nil {
+		return false
+	}
+
+	switch t {
+	case dynamodb.ScalarAttributeTypeS:
+		return av.S != nil
+	case dynamodb.ScalarAttributeTypeN:
+		return av.N != nil
+	case dynamodb.ScalarAttributeTypeB:
+		return av.B != nil
+	}
+
+	return false
+}
+
+// attributeTypes returns the types of the defined attributes by their name
+func attributeTypes(defs []*dynamodb.AttributeDefinition) map[string]string {
+	types := map[string]string{}
+	for _, a := range defs {
+		types[*a.AttributeName] = *a.AttributeType
+	}
+
+	return types
+}
+
+// keysString returns the readable key schema, e.g. "id HASH, created_at RANGE"
+func keysString(keys []*dynamodb.KeySchemaElement) string {
+	var s []string
+	for _, k := range keys {
+		s = append(s, *k.AttributeName+" "+*k.KeyType)
+	}
+
+	return strings.Join(s, ", ")
+}
+
+// indexString returns the readable key schema and projection of an index
+func indexString(keys []*dynamodb.KeySchemaElement, p *dynamodb.Projection) string {
+	s := "(" + keysString(keys)
+	if p != nil {
+		s += " projecting " + aws.StringValue(p.ProjectionType)
+	}
+
+	return s + ")"
+}
+
+// sortedStrings returns the sorted keys of a map of strings
+func sortedStrings(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
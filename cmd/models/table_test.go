@@ -0,0 +1,174 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// fakeDynamoDB answers the DynamoDB API calls of a table migration with the existing table and items
+// and records the operations
+type fakeDynamoDB struct {
+	table *dynamodb.TableDescription
+	items []map[string]*dynamodb.AttributeValue
+	ops   []string
+}
+
+func (f *fakeDynamoDB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+	body, _ := ioutil.ReadAll(r.Body)
+	req := map[string]interface{}{}
+	json.Unmarshal(body, &req)
+
+	var out interface{}
+	switch op {
+	case "DescribeTable":
+		out = &dynamodb.DescribeTableOutput{Table: f.table}
+	case "Scan":
+		out = &dynamodb.ScanOutput{Items: f.items, Count: aws.Int64(int64(len(f.items)))}
+	case "UpdateTable":
+		for _, u := range req["GlobalSecondaryIndexUpdates"].([]interface{}) {
+			for action, index := range u.(map[string]interface{}) {
+				op += " " + action + " " + index.(map[string]interface{})["IndexName"].(string)
+			}
+		}
+		out = &dynamodb.UpdateTableOutput{}
+	case "BatchWriteItem":
+		for _, requests := range req["RequestItems"].(map[string]interface{}) {
+			for _, pr := range requests.([]interface{}) {
+				item := pr.(map[string]interface{})["PutRequest"].(map[string]interface{})["Item"].(map[string]interface{})
+				op += " " + item["id"].(map[string]interface{})["S"].(string)
+			}
+		}
+		out = &dynamodb.BatchWriteItemOutput{}
+	case "DeleteTable", "CreateTable":
+		out = &dynamodb.DeleteTableOutput{}
+	default:
+		http.Error(w, op, http.StatusBadRequest)
+		return
+	}
+	f.ops = append(f.ops, op)
+
+	data, err := jsonutil.BuildJSON(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Write(data)
+}
+
+// describe returns the description of an active table created with the properties
+func describe(props Properties) *dynamodb.TableDescription {
+	in := tableInput("blog-posts-debug", props)
+	t := &dynamodb.TableDescription{
+		TableName:            in.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		KeySchema:            in.KeySchema,
+		AttributeDefinitions: in.AttributeDefinitions,
+	}
+	for _, i := range in.GlobalSecondaryIndexes {
+		t.GlobalSecondaryIndexes = append(t.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   i.IndexName,
+			KeySchema:   i.KeySchema,
+			Projection:  i.Projection,
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+		})
+	}
+
+	return t
+}
+
+func TestMigrateTable(t *testing.T) {
+	byID := Properties{
+		AttributeDefinitions: []AttributeDef{{AttributeName: "id", AttributeType: "S"}},
+		KeySchema:            []KeySchema{{AttributeName: "id", KeyType: "HASH"}},
+	}
+	index := func(name, attr, projection string) Properties {
+		p := byID
+		p.AttributeDefinitions = append(append([]AttributeDef{}, byID.AttributeDefinitions...), AttributeDef{AttributeName: attr, AttributeType: "S"})
+		p.GlobalSecondaryIndexes = []GlobalIndex{{
+			IndexName:  name,
+			KeySchema:  []KeySchema{{AttributeName: attr, KeyType: "HASH"}},
+			Projection: Projection{ProjectionType: projection},
+		}}
+		return p
+	}
+	byIDAndDate := Properties{
+		AttributeDefinitions: []AttributeDef{{AttributeName: "id", AttributeType: "S"}, {AttributeName: "created", AttributeType: "S"}},
+		KeySchema:            []KeySchema{{AttributeName: "id", KeyType: "HASH"}, {AttributeName: "created", KeyType: "RANGE"}},
+	}
+
+	items := []map[string]*dynamodb.AttributeValue{
+		{"id": {S: aws.String("1")}, "created": {S: aws.String("2019-06-01")}},
+		{"id": {S: aws.String("2")}},
+		{"id": {S: aws.String("3")}, "created": {N: aws.String("2019")}},
+	}
+
+	tests := []struct {
+		name string
+		have Properties
+		want Properties
+		ops  []string
+	}{
+		{
+			name: "up to date",
+			have: index("byAuthor", "author", "ALL"),
+			want: index("byAuthor", "author", "ALL"),
+			ops:  []string{"DescribeTable"},
+		},
+		{
+			name: "index added",
+			have: byID,
+			want: index("byAuthor", "author", "ALL"),
+			ops:  []string{"DescribeTable", "UpdateTable Create byAuthor", "DescribeTable"},
+		},
+		{
+			name: "index removed",
+			have: index("byAuthor", "author", "ALL"),
+			want: byID,
+			ops:  []string{"DescribeTable", "UpdateTable Delete byAuthor", "DescribeTable"},
+		},
+		{
+			name: "index changed",
+			have: index("byAuthor", "author", "ALL"),
+			want: index("byAuthor", "author", "KEYS_ONLY"),
+			ops:  []string{"DescribeTable", "UpdateTable Delete byAuthor", "DescribeTable", "UpdateTable Create byAuthor", "DescribeTable"},
+		},
+		{
+			// only the items with a string created attribute fit the new key schema
+			name: "key schema changed",
+			have: byID,
+			want: byIDAndDate,
+			ops:  []string{"DescribeTable", "Scan", "DeleteTable", "CreateTable", "BatchWriteItem 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDynamoDB{table: describe(tt.have), items: items}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+			svc := dynamodb.New(session.Must(session.NewSession(&aws.Config{
+				Endpoint:    aws.String(server.URL),
+				Region:      aws.String("eu-central-1"),
+				Credentials: credentials.NewStaticCredentials("local", "local", ""),
+			})))
+
+			migrateTable(svc, "blog-posts-debug", tt.want)
+			if !reflect.DeepEqual(fake.ops, tt.ops) {
+				t.Errorf("operations = %q, want %q", fake.ops, tt.ops)
+			}
+		})
+	}
+}
@@ -1,11 +1,9 @@
 package models
 
 import (
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"gopkg.in/yaml.v2"
@@ -33,11 +31,12 @@ type SAMFunction struct {
 
 // SAMFnProp ...
 type SAMFnProp struct {
-	Runtime     string              `yaml:"Runtime,omitempty"`
-	Handler     string              `yaml:"Handler,omitempty"`
-	CodeURI     string              `yaml:"CodeUri,omitempty"`
-	Events      map[string]SAMEvent `yaml:"Events,omitempty"`
-	Environment FnEnvironment       `yaml:"Environment,omitempty"`
+	Runtime       string              `yaml:"Runtime,omitempty"`
+	Handler       string              `yaml:"Handler,omitempty"`
+	CodeURI       string              `yaml:"CodeUri,omitempty"`
+	Architectures []string            `yaml:"Architectures,omitempty"`
+	Events        map[string]SAMEvent `yaml:"Events,omitempty"`
+	Environment   FnEnvironment       `yaml:"Environment,omitempty"`
 }
 
 // SAMEvent ...
@@ -74,35 +73,15 @@ func NewTemplate() TemplateConfig {
 }
 
 // AddFunctionsFromServerlessConfig adds the functions from the given
-// ServerlessConfig and resource/ function group
-func (t *TemplateConfig) AddFunctionsFromServerlessConfig(s ServerlessConfig, r string) {
+// ServerlessConfig and resource/ function group with the resolved environment
+func (t *TemplateConfig) AddFunctionsFromServerlessConfig(s ServerlessConfig, r string, env map[string]string) {
 	if len(t.Resources) == 0 {
 		t.Resources = map[string]SAMFunction{}
 	}
 
 	// add environments
-	for key, val := range s.Provider.Environments {
-		// get value in case it's stored in secret
-		if strings.HasPrefix(val, "${file") {
-			re := regexp.MustCompile(`\$\{file\((.*?)\):(.*?)\}`)
-			reFound := re.FindAllStringSubmatch(val, 3)[0]
-			fileName := reFound[1]
-			envKey := reFound[2]
-			f, err := ioutil.ReadFile(filepath.Join(s.ProjectPath, "functions", r, fileName))
-			if err != nil {
-				panic(err.Error())
-			}
-
-			envs := map[string]string{}
-			err = yaml.Unmarshal(f, envs)
-			if err != nil {
-				panic(err.Error())
-			}
-
-			t.Globals.Function.Environment.Variables[envKey] = envs[envKey]
-		} else {
-			t.Globals.Function.Environment.Variables[key] = val
-		}
+	for key, val := range env {
+		t.Globals.Function.Environment.Variables[key] = val
 	}
 
 	for n, f := range s.Functions {
@@ -110,12 +89,21 @@ func (t *TemplateConfig) AddFunctionsFromServerlessConfig(s ServerlessConfig, r
 		// ensure to add only http event functions
 		ev := f.Events[0].HTTP
 		if ev != nil {
+			// the OS-only runtimes execute the bootstrap in the folder of the function
+			runtime, arch := s.FunctionRuntime(n)
+			handler := strings.TrimPrefix(f.Handler, "bin/")
+			codeURI := filepath.Join(".", "functions", r, "debug")
+			if IsProvidedRuntime(runtime) {
+				handler, codeURI = bootstrap, filepath.Join(codeURI, handler)
+			}
+
 			t.Resources[fName] = SAMFunction{
 				Type: "AWS::Serverless::Function",
 				Properties: SAMFnProp{
-					Runtime: "go1.x",
-					Handler: strings.TrimPrefix(f.Handler, "bin/"),
-					CodeURI: filepath.Join(".", "functions", r, "debug"),
+					Runtime:       runtime,
+					Handler:       handler,
+					CodeURI:       codeURI,
+					Architectures: []string{arch},
 					Events: map[string]SAMEvent{
 						"http": SAMEvent{
 							Type: "Api",
@@ -131,12 +119,25 @@ func (t *TemplateConfig) AddFunctionsFromServerlessConfig(s ServerlessConfig, r
 	}
 }
 
+// WriteLocalTemplate renders the template.yml of aws-sam-cli for the resources/ function groups running in the mode
+// with their environment resolved for the stage
+func (m MUGConfig) WriteLocalTemplate(list []string, stage, mode string) {
+	t := NewTemplate()
+	t.Globals.Function.Environment.Variables["MODE"] = mode
+	t.Globals.Function.Environment.Variables["DYNAMODB_ENDPOINT"] = m.DynamoDB().NetworkEndpoint()
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		t.AddFunctionsFromServerlessConfig(sc, r, m.ResolveEnvironment(sc, r, stage, mode))
+	}
+	t.Write(m.ProjectPath)
+}
+
 // Write writes the TemplateConfig to template.yml
 func (t *TemplateConfig) Write(projectPath string) {
 	fp := filepath.Join(projectPath, "template.yml")
 	// make sure directory exists
 	if _, err := os.Stat(projectPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(projectPath, 0755); err != nil {
+		if err := MkdirAll(projectPath, 0755); err != nil {
 			log.Fatal(err)
 		}
 	}
@@ -146,7 +147,7 @@ func (t *TemplateConfig) Write(projectPath string) {
 		log.Fatal(err)
 	}
 
-	err = ioutil.WriteFile(fp, yml, 0644)
+	err = WriteFile(fp, yml, 0644)
 	if err != nil {
 		log.Fatal(err)
 	}
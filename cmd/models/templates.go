@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gobuffalo/flect"
+	"github.com/gobuffalo/packr/v2"
+)
+
+// TemplateBoxes are the packr boxes containing the built-in templates by name
+var TemplateBoxes = map[string]*packr.Box{
+	"resource": ResourceBox,
+	"function": FunctionBox,
+	"e2e":      E2EBox,
+}
+
+// templateData documents the data passed to the templates of each box
+var templateData = []struct {
+	Templates string
+	Data      map[string]interface{}
+}{
+	{"resource/*", map[string]interface{}{"Model": Model{}, "Config": MUGConfig{}}},
+	{"function/blueprint*.tmpl, function/resourceBlueprint.tmpl", map[string]interface{}{"ResourceName": "", "Function": flect.Ident{}, "Config": MUGConfig{}}},
+	{"function/resourceFunction.tmpl", map[string]interface{}{"Function": flect.Ident{}}},
+	{"e2e/resource_test.tmpl", map[string]interface{}{"Suite": E2ESuite{}, "Config": MUGConfig{}}},
+	{"e2e/main_test.tmpl", map[string]interface{}{"Config": MUGConfig{}}},
+}
+
+// templateFuncs returns the functions available in all templates
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		// remove bin/ from the handler
+		"TrimBinPrefix": func(s string) string {
+			return strings.TrimPrefix(s, "bin/")
+		},
+		"Pascalize": func(s string) string {
+			return flect.New(s).Pascalize().String()
+		},
+		"Underscore": func(s string) string {
+			return flect.New(s).Underscore().String()
+		},
+		"First": func(s flect.Ident) string {
+			return string(s.String()[0])
+		},
+	}
+}
+
+// TemplateOverridePath returns the path of the project-local override of a built-in template
+func TemplateOverridePath(projectPath string, b *packr.Box, file string) string {
+	return filepath.Join(projectPath, ".mug", "templates", b.Name, filepath.FromSlash(file))
+}
+
+// EjectTemplates copies the built-in templates of the given boxes to .mug/templates in the project.
+// Existing overrides are only replaced if force is set.
+func EjectTemplates(projectPath string, boxes []string, force bool) {
+	if len(boxes) == 0 {
+		for n := range TemplateBoxes {
+			boxes = append(boxes, n)
+		}
+		sort.Strings(boxes)
+	}
+
+	for _, n := range boxes {
+		b, ok := TemplateBoxes[n]
+		if !ok {
+			log.Fatalf("Unknown template set %s, available are resource, function and e2e", n)
+		}
+
+		for _, file := range b.List() {
+			path := TemplateOverridePath(projectPath, b, file)
+			if _, err := os.Stat(path); err == nil && !force {
+				log.Printf("Template %s exists already, skipping (use --force to overwrite)", path)
+				continue
+			}
+
+			data, err := b.Find(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := WriteFile(path, data, 0644); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("Ejected %s", path)
+		}
+	}
+}
+
+// WriteTemplateData writes the data contract of the templates and the available functions
+func WriteTemplateData(w io.Writer) {
+	for _, d := range templateData {
+		fmt.Fprintf(w, "%s\n", d.Templates)
+
+		var keys []string
+		for k := range d.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			t := reflect.TypeOf(d.Data[k])
+			fmt.Fprintf(w, "  .%s %s\n", k, t)
+			writeFields(w, t, "    ", map[reflect.Type]bool{})
+		}
+		fmt.Fprintln(w)
+	}
+
+	var funcs []string
+	for f := range templateFuncs() {
+		funcs = append(funcs, f)
+	}
+	sort.Strings(funcs)
+	fmt.Fprintf(w, "Functions: %s\n", strings.Join(funcs, ", "))
+}
+
+// writeFields writes the exported fields of a struct type recursively, seen prevents cycles
+func writeFields(w io.Writer, t reflect.Type, indent string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] {
+		return
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fmt.Fprintf(w, "%s.%s %s\n", indent, f.Name, f.Type)
+		writeFields(w, f.Type, indent+"  ", seen)
+	}
+}
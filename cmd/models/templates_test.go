@@ -0,0 +1,103 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+func TestLoadProjectTemplate(t *testing.T) {
+	builtin, err := FunctionBox.FindString("resourceFunction.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		override string
+	}{
+		{name: "built-in"},
+		{name: "override", override: "// {{ Pascalize .Function.String }} overridden\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeProject(t, nil)
+			defer os.RemoveAll(dir)
+			if len(tt.override) > 0 {
+				path := TemplateOverridePath(dir, FunctionBox, "resourceFunction.tmpl")
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(tt.override), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			src := builtin
+			if len(tt.override) > 0 {
+				src = tt.override
+			}
+			got := LoadProjectTemplate(dir, FunctionBox, "resourceFunction.tmpl").Tree.Root.String()
+			want := template.Must(template.New("").Funcs(templateFuncs()).Parse(src)).Tree.Root.String()
+			if got != want {
+				t.Errorf("LoadProjectTemplate() = %.60q..., want %.60q...", got, want)
+			}
+		})
+	}
+}
+
+func TestEjectTemplates(t *testing.T) {
+	defer func() { vfs = newVirtualFS() }()
+
+	tests := []struct {
+		name  string
+		force bool
+		want  string
+	}{
+		{name: "keep existing override", want: "custom"},
+		{name: "force", force: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vfs = newVirtualFS()
+			dir := writeProject(t, map[string]string{".mug/templates/e2e/main_test.tmpl": "custom"})
+			defer os.RemoveAll(dir)
+
+			EjectTemplates(dir, []string{"e2e"}, tt.force)
+
+			var ejected []string
+			for _, p := range sortedPaths(vfs.files) {
+				rel, _ := filepath.Rel(dir, p)
+				ejected = append(ejected, filepath.ToSlash(rel))
+			}
+			wantEjected := []string{".mug/templates/e2e/main_test.tmpl", ".mug/templates/e2e/resource_test.tmpl"}
+			if !tt.force {
+				wantEjected = wantEjected[1:]
+			}
+			if !reflect.DeepEqual(ejected, wantEjected) {
+				t.Errorf("ejected %v, want %v", ejected, wantEjected)
+			}
+
+			data, err := ReadFile(filepath.Join(dir, ".mug", "templates", "e2e", "main_test.tmpl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.want
+			if tt.force {
+				builtin, err := E2EBox.FindString("main_test.tmpl")
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = builtin
+			}
+			if string(data) != want {
+				t.Errorf("main_test.tmpl = %.40q..., want %.40q...", data, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,669 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+)
+
+// hclBlock represents a block of the HashiCorp Configuration Language
+type hclBlock struct {
+	kind   string
+	labels []string
+	attrs  [][2]string
+	blocks []*hclBlock
+}
+
+// newBlock returns a new block of the given kind and labels
+func newBlock(kind string, labels ...string) *hclBlock {
+	return &hclBlock{kind: kind, labels: labels}
+}
+
+// attr adds an attribute with an already rendered expression
+func (b *hclBlock) attr(key, expr string) *hclBlock {
+	b.attrs = append(b.attrs, [2]string{key, expr})
+	return b
+}
+
+// block adds and returns a nested block
+func (b *hclBlock) block(kind string, labels ...string) *hclBlock {
+	nb := newBlock(kind, labels...)
+	b.blocks = append(b.blocks, nb)
+	return nb
+}
+
+// write renders the block with the given indentation
+func (b *hclBlock) write(sb *strings.Builder, indent string) {
+	sb.WriteString(indent + b.kind)
+	for _, l := range b.labels {
+		sb.WriteString(" " + hclString(l))
+	}
+	sb.WriteString(" {\n")
+
+	width := 0
+	for _, a := range b.attrs {
+		if len(a[0]) > width {
+			width = len(a[0])
+		}
+	}
+	for _, a := range b.attrs {
+		// indent multi line expressions like maps and heredocs
+		expr := strings.Replace(a[1], "\n", "\n"+indent, -1)
+		sb.WriteString(fmt.Sprintf("%s  %-*s = %s\n", indent, width, a[0], expr))
+	}
+	for _, nb := range b.blocks {
+		if len(b.attrs) > 0 || nb != b.blocks[0] {
+			sb.WriteString("\n")
+		}
+		nb.write(sb, indent+"  ")
+	}
+
+	sb.WriteString(indent + "}\n")
+}
+
+// terraformExporter collects the Terraform blocks of all resources/ function groups
+type terraformExporter struct {
+	mc     MUGConfig
+	root   string
+	blocks []*hclBlock
+
+	secrets     map[string]bool
+	authorizers map[string]string
+	paths       map[string]string
+	apiIDs      []string
+}
+
+// ExportTerraform returns the Terraform configuration for the given resources/ function groups.
+// The root is the path of the project relative to the directory the configuration is written to.
+func (m MUGConfig) ExportTerraform(list []string, root string) string {
+	e := &terraformExporter{
+		mc:          m,
+		root:        filepath.ToSlash(root),
+		secrets:     map[string]bool{},
+		authorizers: map[string]string{},
+		paths:       map[string]string{},
+	}
+
+	e.add(newBlock("provider", "aws").attr("region", "var.region"))
+	e.add(newBlock("variable", "stage").attr("type", "string").attr("default", hclString("dev")))
+	e.add(newBlock("variable", "region").attr("type", "string").attr("default", hclString(m.Region)))
+
+	for _, r := range list {
+		e.addService(r)
+	}
+	e.addAPI()
+
+	var sb strings.Builder
+	sb.WriteString("# " + m.ProjectName + " exported by mug\n")
+	for _, b := range e.blocks {
+		sb.WriteString("\n")
+		b.write(&sb, "")
+	}
+
+	return sb.String()
+}
+
+// WriteTerraform writes the Terraform configuration to the given file
+func WriteTerraform(path, hcl string) {
+	if err := MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := WriteFile(path, []byte(hcl), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// add appends top level blocks
+func (e *terraformExporter) add(b ...*hclBlock) {
+	e.blocks = append(e.blocks, b...)
+}
+
+// addService adds tables, role and functions of a resource/ function group
+func (e *terraformExporter) addService(r string) {
+	sc := e.mc.ReadServerlessConfig(r)
+	res, err := e.mc.NewVariableResolver(sc, r, map[string]string{
+		"stage":  "${var.stage}",
+		"region": "${var.region}",
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	res.Secrets = e.secretVariable
+
+	model, err := ReadModel(e.mc.ProjectPath, r)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatal(err)
+	}
+
+	for _, name := range sortedKeys(sc.Resources.Resources) {
+		rd := sc.Resources.Resources[name]
+		if rd.Type == "AWS::DynamoDB::Table" {
+			e.addTable(tfName(name), rd.Properties, model, res)
+		} else {
+			log.Printf("Skipping resource %s of type %s for Terraform export", name, rd.Type)
+		}
+	}
+
+	role := tfName(r)
+	e.addRole(role, sc, res)
+
+	env := map[string]string{}
+	for k, v := range sc.Provider.Environments {
+		env[k] = resolveExport(res, v)
+	}
+
+	for _, name := range sortedKeys(sc.Functions) {
+		e.addFunction(r, name, sc, env, role, res)
+	}
+}
+
+// addTable adds the DynamoDB table of a resource using the key schema and billing mode of the Model if defined
+func (e *terraformExporter) addTable(name string, p Properties, m Model, res *VariableResolver) {
+	b := newBlock("resource", "aws_dynamodb_table", name).
+		attr("name", hclString(resolveExport(res, p.TableName)))
+
+	hash, rng := "", ""
+	for _, k := range p.KeySchema {
+		if k.KeyType == "HASH" {
+			hash = k.AttributeName
+		} else {
+			rng = k.AttributeName
+		}
+	}
+	if len(m.KeySchema["HASH"]) > 0 {
+		hash, rng = m.KeySchema["HASH"], m.KeySchema["RANGE"]
+	}
+
+	billing := p.BillingMode
+	if m.BillingMode == "ondemand" {
+		billing = "PAY_PER_REQUEST"
+	}
+	if billing == "PAY_PER_REQUEST" {
+		b.attr("billing_mode", hclString(billing))
+	} else {
+		read, write := int64(1), int64(1)
+		if p.ProvisionedThroughput != nil {
+			read, write = p.ProvisionedThroughput.ReadCapacityUnits, p.ProvisionedThroughput.WriteCapacityUnits
+		}
+		b.attr("billing_mode", hclString("PROVISIONED")).
+			attr("read_capacity", fmt.Sprint(read)).
+			attr("write_capacity", fmt.Sprint(write))
+	}
+
+	b.attr("hash_key", hclString(underscore(hash)))
+	if len(rng) > 0 {
+		b.attr("range_key", hclString(underscore(rng)))
+	}
+
+	for _, a := range p.AttributeDefinitions {
+		b.block("attribute").
+			attr("name", hclString(underscore(a.AttributeName))).
+			attr("type", hclString(a.AttributeType))
+	}
+
+	for _, i := range p.LocalSecondaryIndexes {
+		ib := b.block("local_secondary_index").attr("name", hclString(i.IndexName))
+		for _, k := range i.KeySchema {
+			if k.KeyType == "RANGE" {
+				ib.attr("range_key", hclString(underscore(k.AttributeName)))
+			}
+		}
+		projection(ib, i.Projection)
+	}
+
+	for _, i := range p.GlobalSecondaryIndexes {
+		ib := b.block("global_secondary_index").attr("name", hclString(i.IndexName))
+		for _, k := range i.KeySchema {
+			key := "hash_key"
+			if k.KeyType == "RANGE" {
+				key = "range_key"
+			}
+			ib.attr(key, hclString(underscore(k.AttributeName)))
+		}
+		if billing != "PAY_PER_REQUEST" {
+			read, write := int64(1), int64(1)
+			if i.ProvisionedThroughput != nil {
+				read, write = i.ProvisionedThroughput.ReadCapacityUnits, i.ProvisionedThroughput.WriteCapacityUnits
+			}
+			ib.attr("read_capacity", fmt.Sprint(read)).attr("write_capacity", fmt.Sprint(write))
+		}
+		projection(ib, i.Projection)
+	}
+
+	if len(p.TTLSpecification.AttributeName) > 0 {
+		b.block("ttl").
+			attr("attribute_name", hclString(underscore(p.TTLSpecification.AttributeName))).
+			attr("enabled", fmt.Sprint(p.TTLSpecification.Enabled))
+	}
+
+	e.add(b)
+}
+
+// projection adds the projection attributes of an index
+func projection(b *hclBlock, p Projection) {
+	t := p.ProjectionType
+	if len(t) == 0 {
+		t = "ALL"
+	}
+	b.attr("projection_type", hclString(t))
+	if len(p.NonKeyAttributes) > 0 {
+		b.attr("non_key_attributes", hclList(p.NonKeyAttributes))
+	}
+}
+
+// addRole adds the IAM role of a resource/ function group with the provider's role statements
+func (e *terraformExporter) addRole(name string, sc ServerlessConfig, res *VariableResolver) {
+	assume := map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": "lambda.amazonaws.com"},
+				"Action":    "sts:AssumeRole",
+			},
+		},
+	}
+
+	e.add(
+		newBlock("resource", "aws_iam_role", name).
+			attr("name", hclString(resolveExport(res, sc.Service.Name)+"-${var.stage}")).
+			attr("assume_role_policy", heredoc(assume)),
+		newBlock("resource", "aws_iam_role_policy_attachment", name+"_logs").
+			attr("role", "aws_iam_role."+name+".name").
+			attr("policy_arn", hclString("arn:aws:iam::aws:policy/service-role/AWSLambdaBasicExecutionRole")),
+	)
+
+	if len(sc.Provider.RoleStatements) == 0 {
+		return
+	}
+
+	var statements []map[string]interface{}
+	for _, s := range sc.Provider.RoleStatements {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   s.Effect,
+			"Action":   s.Actions,
+			"Resource": resolveExport(res, s.Resource),
+		})
+	}
+	e.add(newBlock("resource", "aws_iam_role_policy", name).
+		attr("role", "aws_iam_role."+name+".id").
+		attr("policy", heredoc(map[string]interface{}{"Version": "2012-10-17", "Statement": statements})))
+}
+
+// addFunction adds a function with its deployment package and events
+func (e *terraformExporter) addFunction(r, name string, sc ServerlessConfig, env map[string]string, role string, res *VariableResolver) {
+	fn := sc.Functions[name]
+	id := tfName(name)
+	handler := strings.TrimPrefix(fn.Handler, "bin/")
+	zip := "${path.module}/" + e.root + "/functions/" + r + "/bin/" + handler + ".zip"
+
+	// the functions are deployed with the zips of 'mug package'
+	runtime, arch := sc.FunctionRuntime(name)
+	handler = fn.Handler
+	if IsProvidedRuntime(runtime) {
+		handler = bootstrap
+	}
+
+	b := newBlock("resource", "aws_lambda_function", id).
+		attr("function_name", hclString(resolveExport(res, sc.Service.Name)+"-${var.stage}-"+name)).
+		attr("role", "aws_iam_role."+role+".arn").
+		attr("handler", hclString(handler)).
+		attr("runtime", hclString(runtime)).
+		attr("architectures", hclList([]string{arch})).
+		attr("filename", hclString(zip)).
+		attr("source_code_hash", "filebase64sha256("+hclString(zip)+")")
+	if fn.MemorySize > 0 {
+		b.attr("memory_size", fmt.Sprint(fn.MemorySize))
+	}
+	if fn.Timeout > 0 {
+		b.attr("timeout", fmt.Sprint(fn.Timeout))
+	} else if sc.Provider.Timeout > 0 {
+		b.attr("timeout", fmt.Sprint(sc.Provider.Timeout))
+	}
+
+	variables := map[string]string{}
+	for k, v := range env {
+		variables[k] = v
+	}
+	for k, v := range fn.Environments {
+		variables[k] = resolveExport(res, v)
+	}
+	if len(variables) > 0 {
+		b.block("environment").attr("variables", hclMap(variables))
+	}
+	e.add(b)
+
+	for i, ev := range fn.Events {
+		e.addEvent(id, i, ev, res)
+	}
+}
+
+// addEvent adds the resources connecting an event source to a function
+func (e *terraformExporter) addEvent(fnID string, i int, ev Events, res *VariableResolver) {
+	id := fmt.Sprintf("%s_%d", fnID, i+1)
+	arn := "aws_lambda_function." + fnID + ".arn"
+	permission := func(principal, source string) {
+		e.add(newBlock("resource", "aws_lambda_permission", id).
+			attr("action", hclString("lambda:InvokeFunction")).
+			attr("function_name", "aws_lambda_function."+fnID+".function_name").
+			attr("principal", hclString(principal)).
+			attr("source_arn", source))
+	}
+
+	switch {
+	case ev.HTTP != nil:
+		e.addMethod(id, fnID, ev.HTTP, res)
+		permission("apigateway.amazonaws.com", hclString("${aws_api_gateway_rest_api.api.execution_arn}/*"))
+	case ev.Schedule != nil:
+		e.add(
+			newBlock("resource", "aws_cloudwatch_event_rule", id).
+				attr("schedule_expression", hclString(ev.Schedule.Rate)),
+			newBlock("resource", "aws_cloudwatch_event_target", id).
+				attr("rule", "aws_cloudwatch_event_rule."+id+".name").
+				attr("arn", arn),
+		)
+		permission("events.amazonaws.com", "aws_cloudwatch_event_rule."+id+".arn")
+	case ev.SNS != nil:
+		topic := resolveExport(res, ev.SNS.TopicName)
+		topicARN := hclString(topic)
+		if !strings.HasPrefix(topic, "arn:") {
+			e.add(newBlock("resource", "aws_sns_topic", id).attr("name", hclString(topic)))
+			topicARN = "aws_sns_topic." + id + ".arn"
+		}
+		e.add(newBlock("resource", "aws_sns_topic_subscription", id).
+			attr("topic_arn", topicARN).
+			attr("protocol", hclString("lambda")).
+			attr("endpoint", arn))
+		permission("sns.amazonaws.com", topicARN)
+	case ev.SQS != nil:
+		b := newBlock("resource", "aws_lambda_event_source_mapping", id).
+			attr("event_source_arn", hclString(resolveExport(res, ev.SQS.ARN))).
+			attr("function_name", arn)
+		if ev.SQS.BatchSize > 0 {
+			b.attr("batch_size", fmt.Sprint(ev.SQS.BatchSize))
+		}
+		e.add(b)
+	case ev.Stream != nil:
+		position := ev.Stream.StartingPosition
+		if len(position) == 0 {
+			position = "LATEST"
+		}
+		b := newBlock("resource", "aws_lambda_event_source_mapping", id).
+			attr("event_source_arn", hclString(resolveExport(res, ev.Stream.ARN))).
+			attr("function_name", arn).
+			attr("starting_position", hclString(strings.ToUpper(position)))
+		if ev.Stream.BatchSize > 0 {
+			b.attr("batch_size", fmt.Sprint(ev.Stream.BatchSize))
+		}
+		e.add(b)
+	default:
+		log.Printf("Skipping unsupported event of %s for Terraform export", fnID)
+	}
+}
+
+// addMethod adds the API Gateway resources along the path and the method integrating the function
+func (e *terraformExporter) addMethod(id, fnID string, ev *HTTPEvent, res *VariableResolver) {
+	parent := e.pathResource(ev.Path)
+	method := strings.ToUpper(ev.Method)
+
+	authorization, authorizer := "NONE", ""
+	if ev.Authorizer != nil {
+		authorizer = e.authorizer(ev.Authorizer, res)
+		authorization = "COGNITO_USER_POOLS"
+		if strings.Contains(e.authorizers[authorizer], ":lambda:") {
+			authorization = "CUSTOM"
+		}
+	}
+
+	b := newBlock("resource", "aws_api_gateway_method", id).
+		attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+		attr("resource_id", parent).
+		attr("http_method", hclString(method)).
+		attr("authorization", hclString(authorization))
+	if len(authorizer) > 0 {
+		b.attr("authorizer_id", "aws_api_gateway_authorizer."+authorizer+".id")
+	}
+
+	e.add(b, newBlock("resource", "aws_api_gateway_integration", id).
+		attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+		attr("resource_id", parent).
+		attr("http_method", "aws_api_gateway_method."+id+".http_method").
+		attr("integration_http_method", hclString("POST")).
+		attr("type", hclString("AWS_PROXY")).
+		attr("uri", "aws_lambda_function."+fnID+".invoke_arn"))
+	e.apiIDs = append(e.apiIDs, "aws_api_gateway_integration."+id+".id")
+
+	if ev.CORS {
+		e.addCORS(ev.Path, parent)
+	}
+}
+
+// pathResource adds the API Gateway resources for every segment of the path and returns the ID expression of the last one
+func (e *terraformExporter) pathResource(path string) string {
+	parent := "aws_api_gateway_rest_api.api.root_resource_id"
+	current := ""
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if len(part) == 0 {
+			continue
+		}
+		current += "/" + part
+		id, ok := e.paths[current]
+		if !ok {
+			id = "path_" + tfName(current)
+			e.paths[current] = id
+			e.add(newBlock("resource", "aws_api_gateway_resource", id).
+				attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+				attr("parent_id", parent).
+				attr("path_part", hclString(part)))
+		}
+		parent = "aws_api_gateway_resource." + id + ".id"
+	}
+
+	return parent
+}
+
+// addCORS adds the OPTIONS method answering CORS preflight requests for a path
+func (e *terraformExporter) addCORS(path, resource string) {
+	id := "cors_" + tfName(path)
+	if Contains(e.apiIDs, "aws_api_gateway_integration."+id+".id") {
+		return
+	}
+
+	headers := map[string]string{
+		"method.response.header.Access-Control-Allow-Origin":  "'*'",
+		"method.response.header.Access-Control-Allow-Headers": "'Content-Type,X-Amz-Date,Authorization,X-Api-Key,X-Amz-Security-Token'",
+		"method.response.header.Access-Control-Allow-Methods": "'GET,PUT,POST,DELETE,PATCH,OPTIONS'",
+	}
+	allowed := map[string]string{}
+	for h := range headers {
+		allowed[h] = "true"
+	}
+
+	common := func(b *hclBlock) *hclBlock {
+		return b.attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+			attr("resource_id", resource).
+			attr("http_method", hclString("OPTIONS"))
+	}
+
+	e.add(
+		common(newBlock("resource", "aws_api_gateway_method", id)).
+			attr("authorization", hclString("NONE")),
+		common(newBlock("resource", "aws_api_gateway_integration", id)).
+			attr("type", hclString("MOCK")).
+			attr("request_templates", hclMap(map[string]string{"application/json": "{\"statusCode\": 200}"})).
+			attr("depends_on", "[aws_api_gateway_method."+id+"]"),
+		common(newBlock("resource", "aws_api_gateway_method_response", id)).
+			attr("status_code", hclString("200")).
+			attr("response_parameters", hclRawMap(allowed)).
+			attr("depends_on", "[aws_api_gateway_method."+id+"]"),
+		common(newBlock("resource", "aws_api_gateway_integration_response", id)).
+			attr("status_code", hclString("200")).
+			attr("response_parameters", hclMap(headers)).
+			attr("depends_on", "[aws_api_gateway_integration."+id+", aws_api_gateway_method_response."+id+"]"),
+	)
+	e.apiIDs = append(e.apiIDs, "aws_api_gateway_integration."+id+".id")
+}
+
+// authorizer registers the authorizer and returns its name
+func (e *terraformExporter) authorizer(a *Authorizer, res *VariableResolver) string {
+	arn := resolveExport(res, a.ARN)
+	for name, existing := range e.authorizers {
+		if existing == arn {
+			return name
+		}
+	}
+
+	name := tfName(a.Name)
+	if len(a.Name) == 0 {
+		name = fmt.Sprintf("authorizer_%d", len(e.authorizers)+1)
+	}
+	e.authorizers[name] = arn
+
+	return name
+}
+
+// addAPI adds the REST API, its authorizers, deployment and stage if any function has an http event
+func (e *terraformExporter) addAPI() {
+	if len(e.apiIDs) == 0 {
+		return
+	}
+
+	e.add(newBlock("resource", "aws_api_gateway_rest_api", "api").
+		attr("name", hclString(e.mc.ProjectName+"-${var.stage}")))
+
+	var names []string
+	for name := range e.authorizers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		arn := e.authorizers[name]
+		b := newBlock("resource", "aws_api_gateway_authorizer", name).
+			attr("name", hclString(name)).
+			attr("rest_api_id", "aws_api_gateway_rest_api.api.id")
+		if strings.Contains(arn, ":lambda:") {
+			b.attr("type", hclString("TOKEN")).
+				attr("authorizer_uri", hclString("arn:aws:apigateway:${var.region}:lambda:path/2015-03-31/functions/"+arn+"/invocations"))
+		} else {
+			b.attr("type", hclString("COGNITO_USER_POOLS")).
+				attr("provider_arns", hclList([]string{arn}))
+		}
+		e.add(b)
+	}
+
+	deployment := newBlock("resource", "aws_api_gateway_deployment", "api").
+		attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+		attr("triggers", hclRawMap(map[string]string{
+			"redeployment": "sha1(jsonencode([" + strings.Join(e.apiIDs, ", ") + "]))",
+		})).
+		attr("depends_on", "["+strings.Join(e.integrations(), ", ")+"]")
+	deployment.block("lifecycle").
+		attr("create_before_destroy", "true")
+
+	e.add(deployment,
+		newBlock("resource", "aws_api_gateway_stage", "api").
+			attr("rest_api_id", "aws_api_gateway_rest_api.api.id").
+			attr("deployment_id", "aws_api_gateway_deployment.api.id").
+			attr("stage_name", "var.stage"),
+		newBlock("output", "service_endpoint").
+			attr("value", "aws_api_gateway_stage.api.invoke_url"),
+	)
+}
+
+// integrations returns the references of all integrations for the deployment to depend on
+func (e *terraformExporter) integrations() []string {
+	var refs []string
+	for _, id := range e.apiIDs {
+		refs = append(refs, strings.TrimSuffix(id, ".id"))
+	}
+
+	return refs
+}
+
+// secretVariable registers a secret as sensitive variable referenced instead of the ssm parameter
+func (e *terraformExporter) secretVariable(path string) (string, bool) {
+	name := "secret_" + tfName(path[strings.LastIndex(path, "/")+1:])
+	if !e.secrets[name] {
+		e.secrets[name] = true
+		e.add(newBlock("variable", name).
+			attr("type", "string").
+			attr("sensitive", "true").
+			attr("description", hclString("Value of the secret "+path)))
+	}
+
+	return "${var." + name + "}", true
+}
+
+// hclString returns the quoted HCL string, interpolations like ${var.stage} are kept
+func hclString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+
+	return `"` + s + `"`
+}
+
+// hclList returns the HCL list of strings
+func hclList(vals []string) string {
+	var quoted []string
+	for _, v := range vals {
+		quoted = append(quoted, hclString(v))
+	}
+
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// hclMap returns the HCL map of strings sorted by key
+func hclMap(vals map[string]string) string {
+	quoted := map[string]string{}
+	for k, v := range vals {
+		quoted[k] = hclString(v)
+	}
+
+	return hclRawMap(quoted)
+}
+
+// hclRawMap returns the HCL map of rendered expressions sorted by key
+func hclRawMap(vals map[string]string) string {
+	var keys []string
+	for k := range vals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString("{\n")
+	for _, k := range keys {
+		sb.WriteString(fmt.Sprintf("    %s = %s\n", hclString(k), vals[k]))
+	}
+	sb.WriteString("  }")
+
+	return sb.String()
+}
+
+// heredoc returns the JSON document as HCL heredoc string
+func heredoc(doc interface{}) string {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return "<<-EOF\n" + string(data) + "\nEOF"
+}
+
+// tfName returns a valid Terraform name for the given name
+func tfName(name string) string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(flect.New(name).Underscore().String(), "_"), "_")
+}
+
+// underscore returns the attribute name used by the generated code
+func underscore(s string) string {
+	return flect.New(s).Underscore().String()
+}
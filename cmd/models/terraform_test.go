@@ -0,0 +1,117 @@
+package models
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHCLBlockWrite(t *testing.T) {
+	b := newBlock("resource", "aws_iam_role", "note").
+		attr("name", hclString("svc-note-${var.stage}")).
+		attr("tags", hclMap(map[string]string{"stage": "dev", "app": "blog"}))
+	b.block("lifecycle").attr("create_before_destroy", "true")
+
+	want := `resource "aws_iam_role" "note" {
+  name = "svc-note-${var.stage}"
+  tags = {
+    "app" = "blog"
+    "stage" = "dev"
+  }
+
+  lifecycle {
+    create_before_destroy = true
+  }
+}
+`
+	var sb strings.Builder
+	b.write(&sb, "")
+	if got := sb.String(); got != want {
+		t.Errorf("write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHCLString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"svc-${var.stage}", `"svc-${var.stage}"`},
+		{`{"statusCode": 200}`, `"{\"statusCode\": 200}"`},
+		{`C:\dir`, `"C:\\dir"`},
+		{"a\nb", `"a\nb"`},
+	}
+
+	for _, tt := range tests {
+		if got := hclString(tt.s); got != tt.want {
+			t.Errorf("hclString(%q) = %s, want %s", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTfName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"create_note", "create_note"},
+		{"NoteDynamoDbTable", "note_dynamo_db_table"},
+		{"notes/{id}", "notes_id"},
+		{"blog-post", "blog_post"},
+	}
+
+	for _, tt := range tests {
+		if got := tfName(tt.name); got != tt.want {
+			t.Errorf("tfName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportTerraform(t *testing.T) {
+	dir := writeProject(t, map[string]string{"functions/note/serverless.yml": noteService})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir, Region: "eu-central-1"}
+
+	hcl := m.ExportTerraform([]string{"note"}, "..")
+
+	for _, want := range []string{
+		`default = "eu-central-1"`,
+		`resource "aws_dynamodb_table" "note_dynamo_db_table" {
+  name           = "svc-notes-${var.stage}"`,
+		`resource "aws_iam_role" "note" {`,
+		`variable "secret_api_key" {
+  type        = string
+  sensitive   = true`,
+		`resource "aws_lambda_function" "create_note" {
+  function_name    = "svc-note-${var.stage}-create_note"
+  role             = aws_iam_role.note.arn
+  handler          = "bootstrap"
+  runtime          = "provided.al2023"
+  architectures    = ["x86_64"]
+  filename         = "${path.module}/../functions/note/bin/create.zip"`,
+		`"API_KEY" = "${var.secret_api_key}"`,
+		`"NOTE_TABLE_NAME" = "svc-notes-${var.stage}"`,
+		`resource "aws_api_gateway_resource" "path_notes_id" {
+  rest_api_id = aws_api_gateway_rest_api.api.id
+  parent_id   = aws_api_gateway_resource.path_notes.id
+  path_part   = "{id}"`,
+		`resource "aws_api_gateway_method" "create_note_1" {`,
+		`resource "aws_api_gateway_method" "cors_notes" {`,
+		`resource "aws_lambda_permission" "read_note_1" {`,
+		`depends_on  = [aws_api_gateway_integration.create_note_1, aws_api_gateway_integration.cors_notes, aws_api_gateway_integration.read_note_1]`,
+		`output "service_endpoint" {`,
+	} {
+		if !strings.Contains(hcl, want) {
+			t.Errorf("configuration doesn't contain\n%s", want)
+		}
+	}
+
+	// every path is only created once, even if several functions use it
+	if n := strings.Count(hcl, `resource "aws_api_gateway_resource" "path_notes" {`); n != 1 {
+		t.Errorf("path resource notes is defined %d times", n)
+	}
+	// the secret is passed as variable and never exported
+	if strings.Contains(hcl, "ssm:") {
+		t.Error("configuration references the secret store")
+	}
+}
@@ -0,0 +1,453 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// TestReportFormats are the formats of the reports written by 'mug test'
+var TestReportFormats = []string{"junit", "json"}
+
+// testReports is the folder of the test reports and the merged coverage profile in the project
+const testReports = ".mug/reports"
+
+// testEvent is an event of 'go test -json'
+type testEvent struct {
+	Action  string
+	Package string
+	// ImportPath is the package of build-output events, followed by the test binary in brackets
+	ImportPath string
+	Test       string
+	Elapsed    float64
+	Output     string
+}
+
+// testReport is the result of the tests of a resource/ function group
+type testReport struct {
+	Resource string           `json:"resource"`
+	Passed   int              `json:"passed"`
+	Failed   int              `json:"failed"`
+	Skipped  int              `json:"skipped"`
+	Coverage float64          `json:"coverage"`
+	Packages []*packageReport `json:"packages"`
+	// ok is false if a test or package failed, e.g. because it doesn't compile
+	ok      bool
+	profile coverProfile
+}
+
+// packageReport is the result of the tests of a package
+type packageReport struct {
+	Name    string        `json:"name"`
+	Result  string        `json:"result"`
+	Elapsed float64       `json:"elapsed"`
+	Output  string        `json:"output,omitempty"`
+	Tests   []*testResult `json:"tests"`
+}
+
+// testResult is the result of a single test
+type testResult struct {
+	Name    string  `json:"name"`
+	Result  string  `json:"result"`
+	Elapsed float64 `json:"elapsed"`
+	Output  string  `json:"output,omitempty"`
+}
+
+// coverProfile is a coverage profile, the blocks are mapped from their position to their statements and count
+type coverProfile struct {
+	mode   string
+	blocks map[string][2]int
+}
+
+// RunTests runs the tests of the resources/ function groups each with its environment, prints a coverage summary and writes
+// the reports in the format and the merged coverage profile. It fails if a test fails or the total coverage is below minCoverage.
+func (m MUGConfig) RunTests(list []string, env map[string][]string, format string, minCoverage float64, profile bool) {
+	if len(format) > 0 && !Contains(TestReportFormats, format) {
+		log.Fatalf("Unknown report format %s, choose between %s", format, strings.Join(TestReportFormats, ", "))
+	}
+	sort.Strings(list)
+	if DryRun {
+		for _, r := range list {
+			Record("%s go test -json -cover ./functions/%s/...", strings.Join(env[r], " "), r)
+		}
+		return
+	}
+	tmp, err := ioutil.TempDir("", "mug-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var reports []*testReport
+	merged := coverProfile{blocks: map[string][2]int{}}
+	for _, r := range list {
+		report, err := m.runTests(r, env[r], filepath.Join(tmp, r+".out"))
+		if err != nil {
+			log.Fatalf("Error running the tests of %s: %s", r, err)
+		}
+		merged.merge(report.profile)
+		reports = append(reports, report)
+
+		if len(format) > 0 {
+			if err := m.writeTestReport(report, format); err != nil {
+				log.Fatalf("Error writing the test report of %s: %s", r, err)
+			}
+		}
+	}
+
+	coverFile := filepath.Join(m.ProjectPath, testReports, "coverage.out")
+	if err := WriteFile(coverFile, merged.bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	// the reports and the coverage profile are kept when the tests fail, the only files mug test writes
+	Commit()
+	total := printTestSummary(reports, merged)
+	log.Printf("Coverage profile written to %s", coverFile)
+	if len(format) > 0 {
+		log.Printf("Test reports written to %s", filepath.Join(m.ProjectPath, testReports))
+	}
+
+	if profile {
+		RunCmd("go", "tool", "cover", "-html="+coverFile)
+	}
+
+	var failed []string
+	for _, r := range reports {
+		if !r.ok {
+			failed = append(failed, r.Resource)
+		}
+	}
+	if len(failed) > 0 {
+		log.Fatalf("Tests of %s failed", strings.Join(failed, ", "))
+	}
+	if total < minCoverage {
+		log.Fatalf("Total coverage of %.1f%% is below the minimum of %.1f%%", total, minCoverage)
+	}
+}
+
+// runTests runs the tests of a resource/ function group, printing their output, and collects the results and coverage
+func (m MUGConfig) runTests(r string, env []string, profile string) (*testReport, error) {
+	cmd := exec.Command("go", "test", "-json", "-cover", "-coverprofile="+profile, "./functions/"+r+"/...")
+	cmd.Dir = m.ProjectPath
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	report := &testReport{Resource: r, ok: true}
+	packages := map[string]*packageReport{}
+	tests := map[string]*testResult{}
+	builds := map[string]string{}
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			fmt.Println(scanner.Text())
+			continue
+		}
+		fmt.Print(e.Output)
+		if e.Action == "build-output" {
+			if f := strings.Fields(e.ImportPath); len(f) > 0 {
+				builds[f[0]] += e.Output
+			}
+			continue
+		}
+		if len(e.Package) == 0 {
+			continue
+		}
+
+		p := packages[e.Package]
+		if p == nil {
+			p = &packageReport{Name: e.Package}
+			packages[e.Package] = p
+			report.Packages = append(report.Packages, p)
+		}
+		if len(e.Test) == 0 {
+			p.Output += e.Output
+			switch e.Action {
+			case "pass", "fail", "skip":
+				p.Result, p.Elapsed = e.Action, e.Elapsed
+			}
+			continue
+		}
+
+		t := tests[e.Package+"/"+e.Test]
+		if t == nil {
+			t = &testResult{Name: e.Test}
+			tests[e.Package+"/"+e.Test] = t
+			p.Tests = append(p.Tests, t)
+		}
+		t.Output += e.Output
+		switch e.Action {
+		case "pass", "fail", "skip":
+			t.Result, t.Elapsed = e.Action, e.Elapsed
+		}
+	}
+	// go test fails if a test fails, which the results cover
+	waitErr := cmd.Wait()
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(report.Packages) == 0 && waitErr != nil {
+		return nil, waitErr
+	}
+
+	for _, p := range report.Packages {
+		if p.Result == "fail" {
+			report.ok = false
+			p.Output = builds[p.Name] + p.Output
+		}
+		for _, t := range p.Tests {
+			switch t.Result {
+			case "pass":
+				report.Passed++
+			case "fail":
+				report.Failed++
+				report.ok = false
+			case "skip":
+				report.Skipped++
+			}
+		}
+	}
+
+	// a package which doesn't compile has no profile
+	report.profile = coverProfile{blocks: map[string][2]int{}}
+	if data, err := ioutil.ReadFile(profile); err == nil {
+		report.profile, err = parseCoverProfile(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	report.Coverage = report.profile.percent()
+
+	return report, nil
+}
+
+// writeTestReport writes the report of a resource/ function group in the format
+func (m MUGConfig) writeTestReport(r *testReport, format string) error {
+	var data []byte
+	var err error
+	ext := format
+	if format == "junit" {
+		data, err = r.junit()
+		ext = "xml"
+	} else {
+		data, err = json.MarshalIndent(r, "", "  ")
+		data = append(data, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	return WriteFile(filepath.Join(m.ProjectPath, testReports, r.Resource+"."+ext), data, 0644)
+}
+
+// junit returns the report in the JUnit XML format with a test suite per package
+func (r *testReport) junit() ([]byte, error) {
+	type message struct {
+		Message string `xml:"message,attr,omitempty"`
+		Text    string `xml:",chardata"`
+	}
+	type testCase struct {
+		Name      string   `xml:"name,attr"`
+		Classname string   `xml:"classname,attr"`
+		Time      string   `xml:"time,attr"`
+		Failure   *message `xml:"failure,omitempty"`
+		Skipped   *message `xml:"skipped,omitempty"`
+	}
+	type testSuite struct {
+		Name      string     `xml:"name,attr"`
+		Tests     int        `xml:"tests,attr"`
+		Failures  int        `xml:"failures,attr"`
+		Skipped   int        `xml:"skipped,attr"`
+		Time      string     `xml:"time,attr"`
+		TestCases []testCase `xml:"testcase"`
+		SystemOut string     `xml:"system-out,omitempty"`
+	}
+	suites := struct {
+		XMLName  xml.Name    `xml:"testsuites"`
+		Name     string      `xml:"name,attr"`
+		Tests    int         `xml:"tests,attr"`
+		Failures int         `xml:"failures,attr"`
+		Skipped  int         `xml:"skipped,attr"`
+		Suites   []testSuite `xml:"testsuite"`
+	}{Name: r.Resource, Tests: r.Passed + r.Failed + r.Skipped, Failures: r.Failed, Skipped: r.Skipped}
+
+	for _, p := range r.Packages {
+		// skip packages without tests
+		if len(p.Tests) == 0 && p.Result != "fail" {
+			continue
+		}
+
+		s := testSuite{Name: p.Name, Time: formatSeconds(p.Elapsed)}
+		for _, t := range p.Tests {
+			c := testCase{Name: t.Name, Classname: p.Name, Time: formatSeconds(t.Elapsed)}
+			switch t.Result {
+			case "fail":
+				c.Failure = &message{Message: "Failed", Text: t.Output}
+				s.Failures++
+			case "skip":
+				c.Skipped = &message{Text: t.Output}
+				s.Skipped++
+			}
+			s.TestCases = append(s.TestCases, c)
+		}
+		// report failures outside of tests, e.g. build errors, as a failed test case of the package
+		if p.Result == "fail" && s.Failures == 0 {
+			s.TestCases = append(s.TestCases, testCase{
+				Name:      "package",
+				Classname: p.Name,
+				Time:      formatSeconds(p.Elapsed),
+				Failure:   &message{Message: "Package failed", Text: p.Output},
+			})
+			s.Failures++
+			suites.Tests++
+			suites.Failures++
+		}
+		s.Tests = len(s.TestCases)
+		s.SystemOut = p.Output
+		suites.Suites = append(suites.Suites, s)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// printTestSummary prints the test results and coverage of every resource/ function group and returns the total coverage
+func printTestSummary(reports []*testReport, merged coverProfile) float64 {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tPASSED\tFAILED\tSKIPPED\tCOVERAGE")
+	var passed, failed, skipped int
+	for _, r := range reports {
+		status := ""
+		if !r.ok && r.Failed == 0 {
+			status = " (build failed)"
+		}
+		fmt.Fprintf(w, "%s%s\t%d\t%d\t%d\t%.1f%%\n", r.Resource, status, r.Passed, r.Failed, r.Skipped, r.Coverage)
+		passed, failed, skipped = passed+r.Passed, failed+r.Failed, skipped+r.Skipped
+	}
+	total := merged.percent()
+	fmt.Fprintf(w, "total\t%d\t%d\t%d\t%.1f%%\n", passed, failed, skipped, total)
+	w.Flush()
+
+	return total
+}
+
+// parseCoverProfile parses a coverage profile written by 'go test -coverprofile'
+func parseCoverProfile(data []byte) (coverProfile, error) {
+	p := coverProfile{blocks: map[string][2]int{}}
+	for i, line := range strings.Split(string(data), "\n") {
+		if i == 0 {
+			p.mode = strings.TrimPrefix(line, "mode: ")
+			continue
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		// e.g. github.com/user/project/functions/user/create/main.go:12.40,14.2 1 1
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return p, fmt.Errorf("invalid line in coverage profile: %s", line)
+		}
+		stmts, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return p, err
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return p, err
+		}
+		p.add(fields[0], stmts, count)
+	}
+
+	return p, nil
+}
+
+// add adds the count of a block, blocks of packages tested more than once are merged
+func (p *coverProfile) add(block string, stmts, count int) {
+	b, ok := p.blocks[block]
+	switch {
+	case !ok:
+		p.blocks[block] = [2]int{stmts, count}
+	case p.mode == "set":
+		if count > b[1] {
+			p.blocks[block] = [2]int{stmts, count}
+		}
+	default:
+		p.blocks[block] = [2]int{stmts, b[1] + count}
+	}
+}
+
+// merge adds the blocks of the other profile
+func (p *coverProfile) merge(o coverProfile) {
+	if len(p.mode) == 0 {
+		p.mode = o.mode
+	}
+	for block, b := range o.blocks {
+		p.add(block, b[0], b[1])
+	}
+}
+
+// percent returns the percentage of covered statements
+func (p coverProfile) percent() float64 {
+	var total, covered int
+	for _, b := range p.blocks {
+		total += b[0]
+		if b[1] > 0 {
+			covered += b[0]
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+
+	return float64(covered) / float64(total) * 100
+}
+
+// bytes returns the profile in the format of 'go test -coverprofile' sorted by block
+func (p coverProfile) bytes() []byte {
+	mode := p.mode
+	if len(mode) == 0 {
+		mode = "set"
+	}
+
+	var blocks []string
+	for block := range p.blocks {
+		blocks = append(blocks, block)
+	}
+	sort.Strings(blocks)
+
+	var b strings.Builder
+	b.WriteString("mode: " + mode + "\n")
+	for _, block := range blocks {
+		fmt.Fprintf(&b, "%s %d %d\n", block, p.blocks[block][0], p.blocks[block][1])
+	}
+
+	return []byte(b.String())
+}
+
+// formatSeconds returns the elapsed seconds as a JUnit time
+func formatSeconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCoverProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    coverProfile
+		wantErr bool
+	}{
+		{
+			name: "set",
+			data: "mode: set\nproject/functions/user/create/main.go:12.40,14.2 1 1\nproject/functions/user/create/main.go:16.2,18.3 2 0\n",
+			want: coverProfile{mode: "set", blocks: map[string][2]int{
+				"project/functions/user/create/main.go:12.40,14.2": {1, 1},
+				"project/functions/user/create/main.go:16.2,18.3":  {2, 0},
+			}},
+		},
+		{
+			name: "repeated blocks are merged",
+			data: "mode: count\nmain.go:1.1,2.2 1 2\nmain.go:1.1,2.2 1 3\n",
+			want: coverProfile{mode: "count", blocks: map[string][2]int{"main.go:1.1,2.2": {1, 5}}},
+		},
+		{
+			name: "empty",
+			data: "mode: atomic\n",
+			want: coverProfile{mode: "atomic", blocks: map[string][2]int{}},
+		},
+		{name: "missing field", data: "mode: set\nmain.go:1.1,2.2 1\n", wantErr: true},
+		{name: "invalid statements", data: "mode: set\nmain.go:1.1,2.2 x 1\n", wantErr: true},
+		{name: "invalid count", data: "mode: set\nmain.go:1.1,2.2 1 x\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCoverProfile([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCoverProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCoverProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoverProfileMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile coverProfile
+		other   coverProfile
+		want    coverProfile
+		percent float64
+	}{
+		{
+			name:    "set keeps the covered block",
+			profile: coverProfile{mode: "set", blocks: map[string][2]int{"a": {2, 0}, "b": {2, 1}}},
+			other:   coverProfile{mode: "set", blocks: map[string][2]int{"a": {2, 1}, "b": {2, 0}}},
+			want:    coverProfile{mode: "set", blocks: map[string][2]int{"a": {2, 1}, "b": {2, 1}}},
+			percent: 100,
+		},
+		{
+			name:    "count adds the counts",
+			profile: coverProfile{mode: "count", blocks: map[string][2]int{"a": {1, 2}}},
+			other:   coverProfile{mode: "count", blocks: map[string][2]int{"a": {1, 3}, "b": {3, 0}}},
+			want:    coverProfile{mode: "count", blocks: map[string][2]int{"a": {1, 5}, "b": {3, 0}}},
+			percent: 25,
+		},
+		{
+			name:    "empty profile takes the mode",
+			profile: coverProfile{blocks: map[string][2]int{}},
+			other:   coverProfile{mode: "set", blocks: map[string][2]int{"a": {1, 1}}},
+			want:    coverProfile{mode: "set", blocks: map[string][2]int{"a": {1, 1}}},
+			percent: 100,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.profile.merge(tt.other)
+			if !reflect.DeepEqual(tt.profile, tt.want) {
+				t.Errorf("merge() = %v, want %v", tt.profile, tt.want)
+			}
+			if p := tt.profile.percent(); p != tt.percent {
+				t.Errorf("percent() = %v, want %v", p, tt.percent)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	"gopkg.in/yaml.v2"
+)
+
+// maxVariableDepth limits the nesting of variables referencing other variables
+const maxVariableDepth = 10
+
+// variableSources are the sources of variables mug resolves locally, others like ${cf:...} are passed through
+var variableSources = []string{"self:", "opt:", "env:", "file(", "ssm:"}
+
+// VariableResolver resolves the serverless variable syntax (${self:...}, ${opt:...},
+// ${env:...}, ${file(...):...} and ${ssm:...} including defaults and nesting)
+type VariableResolver struct {
+	// Options are the CLI options referenced by ${opt:...} e.g. stage
+	Options map[string]string
+	// Secrets looks up the value of an SSM parameter referenced by ${ssm:...}
+	Secrets func(path string) (string, bool)
+
+	self   map[interface{}]interface{}
+	path   string
+	warned map[string]bool
+}
+
+// NewVariableResolver returns a VariableResolver for the ServerlessConfig of the given resource/ function group
+func (m MUGConfig) NewVariableResolver(sc ServerlessConfig, r string, options map[string]string) (*VariableResolver, error) {
+	// marshal the config to have self references match the serverless.yml
+	yml, err := yaml.Marshal(sc)
+	if err != nil {
+		return nil, err
+	}
+	self := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal(yml, &self); err != nil {
+		return nil, err
+	}
+
+	if options == nil {
+		options = map[string]string{}
+	}
+	if _, ok := options["region"]; !ok && len(m.Region) > 0 {
+		options["region"] = m.Region
+	}
+
+	store := m.ReadSecretStore()
+
+	return &VariableResolver{
+		Options: options,
+		Secrets: store.Get,
+		self:    self,
+		path:    filepath.Join(m.ProjectPath, "functions", r),
+	}, nil
+}
+
+// Resolve replaces all variables in the given string with their values
+func (v *VariableResolver) Resolve(s string) (string, error) {
+	return v.resolve(s, 0)
+}
+
+// Validate resolves every string in the serverless.yml and returns all errors found
+func (v *VariableResolver) Validate() []error {
+	var errs []error
+	walkStrings(v.self, "", func(key, val string) {
+		if _, err := v.Resolve(val); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s", key, err))
+		}
+	})
+
+	return errs
+}
+
+// resolve replaces the variables of a string, depth tracks references resolving to further variables
+func (v *VariableResolver) resolve(s string, depth int) (string, error) {
+	if depth > maxVariableDepth {
+		return "", fmt.Errorf("variable reference cycle in %s", s)
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), nil
+		}
+		j := closingBrace(s, i+2)
+		if j < 0 {
+			return "", fmt.Errorf("unterminated variable in %s", s)
+		}
+
+		val, err := v.resolveVariable(s[i+2:j], depth)
+		if err != nil {
+			return "", err
+		}
+
+		sb.WriteString(s[:i])
+		sb.WriteString(val)
+		s = s[j+1:]
+	}
+}
+
+// resolveVariable resolves the content of a variable trying all comma separated fallbacks. Nested variables of a
+// fallback are only resolved when it is reached, like the serverless framework does.
+func (v *VariableResolver) resolveVariable(content string, depth int) (string, error) {
+	var nestedErr error
+	for _, part := range splitFallbacks(content) {
+		part = strings.TrimSpace(part)
+
+		// a fallback to another variable, e.g. ${opt:stage, ${env:STAGE}}
+		if strings.HasPrefix(part, "${") && closingBrace(part, 2) == len(part)-1 {
+			val, err := v.resolve(part, depth+1)
+			if err != nil {
+				nestedErr = err
+				continue
+			}
+			return val, nil
+		}
+
+		// nested variables of the reference, e.g. ${self:custom.${opt:stage}}
+		part, err := v.resolve(part, depth+1)
+		if err != nil {
+			nestedErr = err
+			continue
+		}
+
+		// literal default values
+		if len(part) >= 2 && (part[0] == '\'' || part[0] == '"') && part[len(part)-1] == part[0] {
+			return part[1 : len(part)-1], nil
+		}
+		if _, err := strconv.ParseFloat(part, 64); err == nil {
+			return part, nil
+		}
+
+		if !supportedVariable(part) {
+			if !v.warned[content] {
+				log.Printf("Warning: ${%s} can't be resolved locally, it is passed through verbatim", content)
+				if v.warned == nil {
+					v.warned = map[string]bool{}
+				}
+				v.warned[content] = true
+			}
+			return "${" + content + "}", nil
+		}
+
+		val, found, err := v.lookup(part)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			// values of the serverless.yml or files may contain variables themselves
+			if strings.HasPrefix(part, "self:") || strings.HasPrefix(part, "file(") {
+				return v.resolve(val, depth+1)
+			}
+			return val, nil
+		}
+	}
+
+	if nestedErr != nil {
+		return "", nestedErr
+	}
+
+	return "", fmt.Errorf("could not resolve ${%s}", content)
+}
+
+// supportedVariable returns whether the reference has a source mug resolves locally
+func supportedVariable(ref string) bool {
+	for _, source := range variableSources {
+		if strings.HasPrefix(ref, source) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// lookup returns the value of a single reference
+func (v *VariableResolver) lookup(ref string) (string, bool, error) {
+	switch {
+	case strings.HasPrefix(ref, "self:"):
+		return lookupKey(v.self, strings.TrimPrefix(ref, "self:"))
+	case strings.HasPrefix(ref, "opt:"):
+		val, ok := v.Options[strings.TrimPrefix(ref, "opt:")]
+		return val, ok && len(val) > 0, nil
+	case strings.HasPrefix(ref, "env:"):
+		val, ok := os.LookupEnv(strings.TrimPrefix(ref, "env:"))
+		return val, ok && len(val) > 0, nil
+	case strings.HasPrefix(ref, "file("):
+		return v.lookupFile(ref)
+	case strings.HasPrefix(ref, "ssm:"):
+		path := strings.TrimSuffix(strings.TrimPrefix(ref, "ssm:"), "~true")
+		if v.Secrets == nil {
+			return "", false, nil
+		}
+		val, ok := v.Secrets(path)
+		return val, ok, nil
+	}
+
+	return "", false, fmt.Errorf("unsupported variable %s", ref)
+}
+
+// lookupFile returns the value of a ${file(path):key} reference relative to the resource/ function group
+func (v *VariableResolver) lookupFile(ref string) (string, bool, error) {
+	end := strings.Index(ref, ")")
+	if end < 0 {
+		return "", false, fmt.Errorf("invalid file reference %s", ref)
+	}
+	path := strings.Trim(ref[len("file("):end], `'" `)
+	key := strings.TrimPrefix(ref[end+1:], ":")
+
+	data, err := readDataFromFile(filepath.Join(v.path, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+
+	var content interface{}
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return "", false, fmt.Errorf("invalid file %s: %s", path, err)
+	}
+	if len(key) == 0 {
+		return stringify(content)
+	}
+
+	return lookupKey(content, key)
+}
+
+// lookupKey walks the dot separated key through the yaml content
+func lookupKey(content interface{}, key string) (string, bool, error) {
+	for _, k := range strings.Split(key, ".") {
+		m, ok := content.(map[interface{}]interface{})
+		if !ok {
+			return "", false, nil
+		}
+		if content, ok = m[k]; !ok {
+			return "", false, nil
+		}
+	}
+
+	return stringify(content)
+}
+
+// stringify returns the string representation of a scalar yaml value
+func stringify(val interface{}) (string, bool, error) {
+	switch t := val.(type) {
+	case nil:
+		return "", false, nil
+	case string:
+		return t, len(t) > 0, nil
+	case int, int64, float64, bool:
+		return fmt.Sprint(t), true, nil
+	}
+
+	return "", false, fmt.Errorf("variable resolves to %T, only scalar values are supported", val)
+}
+
+// closingBrace returns the index of the brace closing the variable starting before start
+func closingBrace(s string, start int) int {
+	open := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			open++
+		case '}':
+			open--
+			if open == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// splitFallbacks splits the variable content at commas outside of quotes, parentheses and nested variables
+func splitFallbacks(content string) []string {
+	var (
+		parts []string
+		quote byte
+		paren int
+		brace int
+		last  int
+	)
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			paren++
+		case c == ')':
+			paren--
+		case c == '{':
+			brace++
+		case c == '}':
+			brace--
+		case c == ',' && paren == 0 && brace == 0:
+			parts = append(parts, content[last:i])
+			last = i + 1
+		}
+	}
+
+	return append(parts, content[last:])
+}
+
+// walkStrings calls fn for every string value in the yaml content
+func walkStrings(content interface{}, key string, fn func(key, val string)) {
+	switch t := content.(type) {
+	case map[interface{}]interface{}:
+		for k, val := range t {
+			walkStrings(val, strings.TrimPrefix(fmt.Sprintf("%s.%v", key, k), "."), fn)
+		}
+	case []interface{}:
+		for i, val := range t {
+			walkStrings(val, fmt.Sprintf("%s[%d]", key, i), fn)
+		}
+	case string:
+		fn(key, t)
+	}
+}
+
+// ResolveEnvironment returns the provider environment of a resource/ function group resolved for the given stage.
+// Table names are resolved for the mode to reference the local tables.
+func (m MUGConfig) ResolveEnvironment(sc ServerlessConfig, r, stage, mode string) map[string]string {
+	res, err := m.NewVariableResolver(sc, r, map[string]string{"stage": stage})
+	if err != nil {
+		log.Fatal(err)
+	}
+	local, err := m.NewVariableResolver(sc, r, map[string]string{"stage": mode})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tables := map[string]bool{}
+	for _, rd := range sc.Resources.Resources {
+		if rd.Type == "AWS::DynamoDB::Table" {
+			tables[rd.Properties.TableName] = true
+		}
+	}
+
+	env := map[string]string{}
+	for key, val := range sc.Provider.Environments {
+		v, err := res.Resolve(val)
+		if tables[val] {
+			v, err = local.Resolve(val)
+		}
+		if err != nil {
+			log.Fatalf("Error resolving environment %s of %s: %s", key, r, err)
+		}
+		env[key] = v
+	}
+
+	return env
+}
+
+// LocalTableEnvironment returns the environment variables of the resources in the list naming their tables, e.g.
+// USER_TABLE_NAME, resolved to the local tables of the mode. The fallback of the generated models names the table
+// differently than serverless.yml for resources like BlogPost, so mug always passes the resolved names.
+// The table definitions including the secondary indexes are passed in e.g. USER_TABLE_DEFINITION,
+// the generated TestMain creates the tables of the test packages from them.
+func (m MUGConfig) LocalTableEnvironment(list []string, mode string) []string {
+	var env []string
+	for _, r := range list {
+		sc := m.ReadServerlessConfig(r)
+		tables := map[string]Properties{}
+		for _, rd := range sc.Resources.Resources {
+			if rd.Type == "AWS::DynamoDB::Table" {
+				tables[rd.Properties.TableName] = rd.Properties
+			}
+		}
+		for key, val := range sc.Provider.Environments {
+			props, ok := tables[val]
+			if !ok {
+				continue
+			}
+			tableName := m.LocalTableName(sc, r, props, mode)
+			env = append(env, key+"="+tableName)
+			if strings.HasSuffix(key, "_NAME") {
+				def, err := json.Marshal(tableInput(tableName, props))
+				if err != nil {
+					log.Fatal(err)
+				}
+				env = append(env, strings.TrimSuffix(key, "_NAME")+"_DEFINITION="+string(def))
+			}
+		}
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// LocalTableName returns the name of the local table of a resource/ function group for the given mode
+func (m MUGConfig) LocalTableName(sc ServerlessConfig, r string, props Properties, mode string) string {
+	if len(props.TableName) == 0 {
+		// function groups may declare tables as well, they are named after the group
+		ident := flect.New(r)
+		if res, ok := m.Resources[r]; ok && res != nil {
+			ident = res.Ident
+		}
+		return m.ProjectName + "-" + ident.Pluralize().Camelize().String() + "-" + mode
+	}
+
+	res, err := m.NewVariableResolver(sc, r, map[string]string{"stage": mode})
+	if err != nil {
+		log.Fatal(err)
+	}
+	tableName, err := res.Resolve(props.TableName)
+	if err != nil {
+		log.Fatalf("Error resolving table name of %s: %s", r, err)
+	}
+
+	return tableName
+}
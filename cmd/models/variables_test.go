@@ -0,0 +1,160 @@
+package models
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gobuffalo/flect"
+	"gopkg.in/yaml.v2"
+)
+
+func TestVariableResolverResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mug-variables")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := "table: ${self:service}-${opt:stage}\nstages:\n  dev:\n    memory: 128\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.yml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	self := map[interface{}]interface{}{}
+	sls := `
+service: user
+custom:
+  stage: ${opt:stage, 'dev'}
+  dev:
+    domain: dev.example.com
+  prod:
+    domain: example.com
+  table: ${self:service}-table
+  raw: ${env:MUG_TEST_RAW}
+`
+	if err := yaml.Unmarshal([]byte(sls), &self); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("MUG_TEST_ENV", "from-env")
+	os.Setenv("MUG_TEST_RAW", "${self:service}")
+	defer os.Unsetenv("MUG_TEST_ENV")
+	defer os.Unsetenv("MUG_TEST_RAW")
+
+	tests := []struct {
+		name    string
+		options map[string]string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"plain string", nil, "no variables", "no variables", false},
+		{"self", nil, "${self:service}", "user", false},
+		{"self with text around", nil, "arn:${self:service}:table", "arn:user:table", false},
+		{"self resolving a variable", nil, "${self:custom.table}", "user-table", false},
+		{"opt", map[string]string{"stage": "prod"}, "${opt:stage}", "prod", false},
+		{"env", nil, "${env:MUG_TEST_ENV}", "from-env", false},
+		{"env value is not resolved again", nil, "${env:MUG_TEST_RAW}", "${self:service}", false},
+		{"quoted default", nil, "${opt:stage, 'dev'}", "dev", false},
+		{"double quoted default", nil, `${env:MUG_TEST_MISSING, "fallback"}`, "fallback", false},
+		{"numeric default", nil, "${opt:memory, 256}", "256", false},
+		{"variable default", nil, "${opt:stage, self:service}", "user", false},
+		{"default not used", map[string]string{"stage": "prod"}, "${opt:stage, 'dev'}", "prod", false},
+		{"nested", map[string]string{"stage": "prod"}, "${self:custom.${opt:stage}.domain}", "example.com", false},
+		{"nested with default", nil, "${self:custom.${opt:stage, 'dev'}.domain}", "dev.example.com", false},
+		{"self default through variable", nil, "${self:custom.stage}", "dev", false},
+		{"file key", map[string]string{"stage": "dev"}, "${file(./config.yml):table}", "user-dev", false},
+		{"file nested key", nil, "${file(config.yml):stages.dev.memory}", "128", false},
+		{"missing file with default", nil, "${file(./missing.yml):table, 'none'}", "none", false},
+		{"missing self", nil, "${self:custom.missing}", "", true},
+		{"missing opt", nil, "${opt:stage}", "", true},
+		{"fallback variable not resolved if unused", map[string]string{"stage": "prod"}, "${opt:stage, ${env:MUG_TEST_MISSING}}", "prod", false},
+		{"fallback variable", nil, "${opt:stage, ${env:MUG_TEST_ENV}}", "from-env", false},
+		{"fallback variable with nested default", nil, "${opt:missing, ${self:custom.${opt:stage, 'dev'}.domain}}", "dev.example.com", false},
+		{"missing fallback variable", nil, "${opt:stage, ${env:MUG_TEST_MISSING}}", "", true},
+		{"unsupported source passed through", nil, "${cf:stack.output}", "${cf:stack.output}", false},
+		{"unsupported source in text", nil, "arn:${sls:instanceId}", "arn:${sls:instanceId}", false},
+		{"unterminated", nil, "${self:service", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if options == nil {
+				options = map[string]string{}
+			}
+			v := &VariableResolver{Options: options, self: self, path: dir}
+
+			got, err := v.Resolve(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVariableResolverCycle(t *testing.T) {
+	self := map[interface{}]interface{}{}
+	if err := yaml.Unmarshal([]byte("a: ${self:b}\nb: ${self:a}\n"), &self); err != nil {
+		t.Fatal(err)
+	}
+	v := &VariableResolver{Options: map[string]string{}, self: self}
+
+	if _, err := v.Resolve("${self:a}"); err == nil {
+		t.Error("Resolve of a reference cycle returned no error")
+	}
+}
+
+func TestLocalTableName(t *testing.T) {
+	m := MUGConfig{
+		ProjectName: "blog",
+		Resources:   map[string]*NewResource{"blogPost": {Ident: flect.New("blogPost")}},
+	}
+
+	tests := []struct {
+		name  string
+		r     string
+		props Properties
+		want  string
+	}{
+		{"resource", "blogPost", Properties{}, "blog-blogPosts-test"},
+		{"function group", "report", Properties{}, "blog-reports-test"},
+		{"table name", "report", Properties{TableName: "${opt:stage}-reports"}, "test-reports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := ServerlessConfig{Service: Service{Name: tt.r}}
+			if got := m.LocalTableName(sc, tt.r, tt.props, "test"); got != tt.want {
+				t.Errorf("LocalTableName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalTableEnvironment(t *testing.T) {
+	dir := writeProject(t, map[string]string{"functions/note/serverless.yml": noteService})
+	defer os.RemoveAll(dir)
+	m := MUGConfig{ProjectName: "blog", ProjectPath: dir}
+
+	env := m.LocalTableEnvironment([]string{"note"}, "test")
+	if len(env) != 2 || !strings.HasPrefix(env[0], "NOTE_TABLE_DEFINITION=") || env[1] != "NOTE_TABLE_NAME=svc-notes-test" {
+		t.Fatalf("LocalTableEnvironment() = %v", env)
+	}
+
+	var def dynamodb.CreateTableInput
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(env[0], "NOTE_TABLE_DEFINITION=")), &def); err != nil {
+		t.Fatal(err)
+	}
+	if got := def.String(); got != tableInput("svc-notes-test", m.ReadServerlessConfig("note").Resources.Resources["NoteDynamoDbTable"].Properties).String() {
+		t.Errorf("NOTE_TABLE_DEFINITION = %s", got)
+	}
+}
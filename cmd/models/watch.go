@@ -0,0 +1,149 @@
+package models
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// watchedFiles are the files besides go sources whose changes affect the build or the configuration
+var watchedFiles = map[string]bool{
+	"serverless.yml": true,
+	"secrets.yml":    true,
+	"go.mod":         true,
+	"go.sum":         true,
+	"Gopkg.lock":     true,
+}
+
+// ignoredDirs are the folders containing build outputs or dependencies, which aren't watched
+var ignoredDirs = map[string]bool{
+	".git":         true,
+	".mug":         true,
+	".serverless":  true,
+	"bin":          true,
+	"debug":        true,
+	"local":        true,
+	"dlv":          true,
+	"vendor":       true,
+	"node_modules": true,
+}
+
+// Watcher polls the sources and configuration of a project for changes
+type Watcher struct {
+	root     string
+	interval time.Duration
+	files    map[string]fileState
+}
+
+// fileState is the state of a watched file used to detect changes
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher returns a watcher of the project polling with the interval
+func NewWatcher(root string, interval time.Duration) *Watcher {
+	w := &Watcher{root: root, interval: interval}
+	w.files = w.scan()
+
+	return w
+}
+
+// Watch calls fn with the changed files relative to the project until mug is interrupted.
+// Changes are collected until the files didn't change for one interval, so saving several files triggers a single call.
+func (w *Watcher) Watch(fn func(changed []string)) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	changed := map[string]bool{}
+	for {
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+
+		files := w.scan()
+		diff := w.diff(files)
+		w.files = files
+		for _, f := range diff {
+			changed[f] = true
+		}
+		if len(diff) > 0 || len(changed) == 0 {
+			continue
+		}
+
+		var list []string
+		for f := range changed {
+			list = append(list, f)
+		}
+		sort.Strings(list)
+		changed = map[string]bool{}
+		fn(list)
+	}
+}
+
+// scan returns the state of all watched files of the project
+func (w *Watcher) scan() map[string]fileState {
+	files := map[string]fileState{}
+	filepath.Walk(w.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// files may be removed while walking
+			return nil
+		}
+		if info.IsDir() {
+			if path != w.root && ignoredDirs[info.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".go") && !watchedFiles[info.Name()] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(w.root, path)
+		if err != nil {
+			return nil
+		}
+		files[filepath.ToSlash(rel)] = fileState{modTime: info.ModTime(), size: info.Size()}
+		return nil
+	})
+
+	return files
+}
+
+// diff returns the files added, changed or removed compared to the last scan
+func (w *Watcher) diff(files map[string]fileState) []string {
+	var changed []string
+	for f, s := range files {
+		if old, ok := w.files[f]; !ok || old != s {
+			changed = append(changed, f)
+		}
+	}
+	for f := range w.files {
+		if _, ok := files[f]; !ok {
+			changed = append(changed, f)
+		}
+	}
+
+	return changed
+}
+
+// ConfigChanged checks whether a serverless.yml or secrets.yml is among the changed files
+func ConfigChanged(changed []string) bool {
+	for _, f := range changed {
+		if base := filepath.Base(f); base == "serverless.yml" || base == "secrets.yml" {
+			return true
+		}
+	}
+
+	return false
+}
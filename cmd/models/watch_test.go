@@ -0,0 +1,78 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWatcherDiff(t *testing.T) {
+	dir := writeProject(t, map[string]string{
+		"functions/note/note.go":             "package note",
+		"functions/note/create/main.go":      "package main",
+		"functions/note/serverless.yml":      "service: note",
+		"functions/note/README.md":           "not watched",
+		"functions/note/bin/create/main.go":  "build output",
+		"functions/note/vendor/lib/lib.go":   "dependency",
+		"functions/note/.mug/templates/a.go": "template",
+		"go.mod":                             "module blog",
+	})
+	defer os.RemoveAll(dir)
+
+	w := NewWatcher(dir, time.Second)
+	var watched []string
+	for f := range w.files {
+		watched = append(watched, f)
+	}
+	sort.Strings(watched)
+	want := []string{"functions/note/create/main.go", "functions/note/note.go", "functions/note/serverless.yml", "go.mod"}
+	if !reflect.DeepEqual(watched, want) {
+		t.Errorf("watched files = %v, want %v", watched, want)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "functions", "note", "note.go"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions", "note", "create", "main.go"), []byte("package main // changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "go.mod")); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions", "note", "secrets.yml"), []byte("dev: {}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "functions", "note", "README.md"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := w.diff(w.scan())
+	sort.Strings(changed)
+	want = []string{"functions/note/create/main.go", "functions/note/note.go", "functions/note/secrets.yml", "go.mod"}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("diff() = %v, want %v", changed, want)
+	}
+}
+
+func TestConfigChanged(t *testing.T) {
+	tests := []struct {
+		changed []string
+		want    bool
+	}{
+		{[]string{"functions/note/note.go", "functions/note/serverless.yml"}, true},
+		{[]string{"functions/note/secrets.yml"}, true},
+		{[]string{"functions/note/note.go", "go.mod"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := ConfigChanged(tt.changed); got != tt.want {
+			t.Errorf("ConfigChanged(%v) = %v, want %v", tt.changed, got, tt.want)
+		}
+	}
+}
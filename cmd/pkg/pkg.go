@@ -0,0 +1,55 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pkg
+
+import (
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// PackageCmd represents the package command
+	PackageCmd = &cobra.Command{
+		Use:         "package",
+		Short:       "Builds and packages the functions as reproducible zip files",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Builds the functions and packages each of them as zip file in the bin folder of its
+resource/ function group. Besides the binary the files matching the package.include patterns
+of the function are added. The zips are reproducible, entries are sorted and have a fixed
+timestamp, so unchanged functions result in identical zips.
+
+The package.artifact of every function in the serverless.yml is pointed at its zip and the sizes
+are reported together with a warning for functions close to the Lambda limits.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+			list := models.GetList(mc.ProjectPath, buildList)
+
+			mc.Build(list, false)
+			mc.Package(list)
+		},
+	}
+
+	buildList string
+)
+
+func init() {
+	PackageCmd.Flags().StringVarP(&buildList, "list", "l", "all", "comma separated list of resources/ function groups to package")
+}
@@ -27,9 +27,10 @@ import (
 
 // rmauthCmd represents the rmauth command
 var rmauthCmd = &cobra.Command{
-	Use:   "auth [resourceName]",
-	Short: "Remove authentication from the given resource or function group",
-	Args:  cobra.ExactArgs(1),
+	Use:         "auth [resourceName]",
+	Short:       "Remove authentication from the given resource or function group",
+	Annotations: map[string]string{models.LockAnnotation: "true"},
+	Args:        cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		rName := args[0]
 
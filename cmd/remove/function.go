@@ -28,9 +28,10 @@ import (
 // rmfunctionCmd represents the rmfunction command
 var (
 	rmfunctionCmd = &cobra.Command{
-		Use:   "function functionName",
-		Short: "Removes a function from a resource",
-		Args:  cobra.ExactArgs(1),
+		Use:         "function functionName",
+		Short:       "Removes a function from a resource",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			fName := models.GetFuncName(assigned, args[0])
 
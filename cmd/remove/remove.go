@@ -30,9 +30,10 @@ import (
 
 // RemoveCmd represents the remove command
 var RemoveCmd = &cobra.Command{
-	Use:   "remove name",
-	Short: "Remove resource or function group from your project",
-	Args:  cobra.ExactArgs(1),
+	Use:         "remove name",
+	Short:       "Remove resource or function group from your project",
+	Annotations: map[string]string{models.LockAnnotation: "true"},
+	Args:        cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		rName := args[0]
 
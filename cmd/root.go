@@ -21,18 +21,31 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/crolly/mug/cmd/test"
 
+	"github.com/crolly/mug/cmd/pkg"
 	"github.com/crolly/mug/cmd/remove"
+	"github.com/crolly/mug/cmd/runtime"
+	"github.com/crolly/mug/cmd/secret"
+	"github.com/crolly/mug/cmd/seed"
+	"github.com/crolly/mug/cmd/serve"
+	"github.com/crolly/mug/cmd/templates"
+	"github.com/crolly/mug/cmd/validate"
 
 	"github.com/crolly/mug/cmd/deploy"
+	"github.com/crolly/mug/cmd/dump"
 
 	"github.com/crolly/mug/cmd/add"
 	"github.com/crolly/mug/cmd/create"
 	"github.com/crolly/mug/cmd/debug"
+	"github.com/crolly/mug/cmd/event"
+	"github.com/crolly/mug/cmd/export"
+	"github.com/crolly/mug/cmd/invoke"
+	"github.com/crolly/mug/cmd/local"
+	"github.com/crolly/mug/cmd/migrate"
+	"github.com/crolly/mug/cmd/models"
 
 	"github.com/spf13/cobra"
 )
@@ -47,24 +60,65 @@ var RootCmd = &cobra.Command{
 	Short: "microservices understand golang - easily creating serverless AWS Lambda CRUDL apps",
 	Long: `
 mug lets you create AWS Lambda for golang projects and boilerplates
-the project structure with serverless configuration and a required Makefile
-to build the functions. You can easily add CRUDL functions as resources.`,
+the project structure with serverless configuration and builds
+the functions. You can easily add CRUDL functions as resources.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// read-only commands and create, which writes outside of a project, run without the lock
+		if !models.DryRun && cmd.Annotations[models.LockAnnotation] == "true" {
+			models.Lock()
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		finish(cmd)
+	},
+}
+
+// finish writes the staged files of the command and releases the project lock
+func finish(cmd *cobra.Command) {
+	if models.DryRun {
+		models.PrintDryRun()
+		return
+	}
+	models.Commit()
+	if cmd.Annotations[models.LockAnnotation] == "true" {
+		models.Unlock()
+	}
 }
 
 func init() {
+	RootCmd.PersistentFlags().BoolVar(&models.DryRun, "dry-run", false, "Print the changes and commands instead of writing files and running them")
+
 	RootCmd.AddCommand(create.CreateCmd)
 	RootCmd.AddCommand(add.AddCmd)
 	RootCmd.AddCommand(debug.DebugCmd)
 	RootCmd.AddCommand(deploy.DeployCmd)
 	RootCmd.AddCommand(test.TestCmd)
 	RootCmd.AddCommand(remove.RemoveCmd)
+	RootCmd.AddCommand(secret.SecretCmd)
+	RootCmd.AddCommand(validate.ValidateCmd)
+	RootCmd.AddCommand(export.ExportCmd)
+	RootCmd.AddCommand(templates.TemplatesCmd)
+	RootCmd.AddCommand(migrate.MigrateModulesCmd)
+	RootCmd.AddCommand(runtime.RuntimeCmd)
+	RootCmd.AddCommand(pkg.PackageCmd)
+	RootCmd.AddCommand(serve.ServeCmd)
+	RootCmd.AddCommand(invoke.InvokeCmd)
+	RootCmd.AddCommand(event.EventCmd)
+	RootCmd.AddCommand(seed.SeedCmd)
+	RootCmd.AddCommand(dump.DumpCmd)
+	RootCmd.AddCommand(local.LocalCmd)
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
-		fmt.Println(err)
+	cmd, err := RootCmd.ExecuteC()
+	if err != nil {
+		// commands return errors of the user's code, e.g. of an invoked function, after their own work
+		// succeeded, so the post run is finished nevertheless
+		if cmd != nil {
+			finish(cmd)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,86 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package runtime
+
+import (
+	"log"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// RuntimeCmd represents the runtime command
+	RuntimeCmd = &cobra.Command{
+		Use:   "runtime RUNTIME",
+		Short: "Sets the Lambda runtime and architecture of the project, resources/ function groups or a single function",
+		Long: `Sets the Lambda runtime (provided.al2023, provided.al2 or the deprecated go1.x) and architecture
+(x86_64 or arm64) for the resources/ function groups in the list. Setting it for all of them also
+changes the default of the project for new resources/ function groups. With --name and --assign
+only the given function is changed.
+
+The provided runtimes execute a bootstrap binary, which is built per function and packaged as zip.`,
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Args:        cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runtime := args[0]
+			if err := models.ValidateRuntime(runtime, arch); err != nil {
+				log.Fatal(err)
+			}
+
+			mc := models.ReadMUGConfig()
+
+			if len(fnName) > 0 {
+				sc := mc.ReadServerlessConfig(assigned)
+				if err := sc.SetFunctionRuntime(models.GetFuncName(assigned, fnName), runtime, arch); err != nil {
+					log.Fatal(err)
+				}
+				sc.Write(mc.ProjectPath, assigned)
+				log.Printf("Function %s of %s uses %s on %s", fnName, assigned, runtime, arch)
+				return
+			}
+
+			for _, r := range models.GetList(mc.ProjectPath, list) {
+				sc := mc.ReadServerlessConfig(r)
+				sc.SetRuntime(runtime, arch)
+				sc.Write(mc.ProjectPath, r)
+				log.Printf("%s uses %s on %s", r, runtime, arch)
+			}
+			if list == "all" {
+				mc.Runtime, mc.Architecture = runtime, arch
+				mc.Write()
+			}
+		},
+	}
+
+	arch, list, fnName, assigned string
+)
+
+func init() {
+	RuntimeCmd.SetHelpCommand(&cobra.Command{
+		Use:    "no-help",
+		Hidden: true,
+	})
+	RuntimeCmd.Flags().StringVar(&arch, "arch", models.X86Architecture, "Lambda architecture (x86_64 or arm64)")
+	RuntimeCmd.Flags().StringVarP(&list, "list", "l", "all", "comma separated list of resources/ function groups")
+	RuntimeCmd.Flags().StringVarP(&fnName, "name", "n", "", "Name of a single function to set the runtime for")
+	RuntimeCmd.Flags().StringVarP(&assigned, "assign", "a", "generic", "Name of the resource or function group the function is assigned to")
+}
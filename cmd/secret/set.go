@@ -0,0 +1,96 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package secret
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setCmd = &cobra.Command{
+		Use:         "set KEY [value]",
+		Short:       "Stores a secret in SSM Parameter Store or the local secret store and references it in serverless.yml",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Stores a secret for the given stage and references it in the environment of the serverless.yml.
+If the value is omitted, it is read from stdin so it doesn't end up in your shell history.
+
+The ssm backend writes the secret as SecureString to the AWS SSM Parameter Store.
+The local backend writes it to the encrypted .mug/secrets.enc which is used by 'mug debug' and 'mug test'.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			key := args[0]
+			value := ""
+			if len(args) > 1 {
+				value = args[1]
+			} else {
+				value = readValue(key)
+			}
+
+			mc := models.ReadMUGConfig()
+			sc := mc.ReadServerlessConfig(assigned)
+
+			switch backend {
+			case "ssm":
+				mc.PutSSMSecret(stage, profile, key, value)
+			case "local":
+				store := mc.ReadSecretStore()
+				store.Set(mc.SecretPath(stage, key), value)
+				store.Write()
+			default:
+				log.Fatalf("Unknown backend %s, choose between 'ssm' and 'local'", backend)
+			}
+			log.Printf("Secret %s stored for stage %s in %s backend", key, stage, backend)
+
+			// reference secret in serverless.yml
+			sc.SetSecret(mc, assigned, key)
+			sc.Write(mc.ProjectPath, assigned)
+		},
+	}
+
+	assigned, stage, backend, profile string
+)
+
+func init() {
+	SecretCmd.AddCommand(setCmd)
+
+	setCmd.Flags().StringVarP(&assigned, "assign", "a", "generic", "Name of the resource or function group the secret should be assigned to")
+	setCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the secret is stored for")
+	setCmd.Flags().StringVarP(&backend, "backend", "b", "ssm", "Choose between 'ssm' for the AWS SSM Parameter Store or 'local' for the encrypted local secret store")
+	setCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile used for the ssm backend")
+}
+
+// readValue reads the secret value from stdin
+func readValue(key string) string {
+	fmt.Printf("Value for %s: ", key)
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && len(value) == 0 {
+		log.Fatal(err)
+	}
+
+	return strings.TrimRight(value, "\r\n")
+}
@@ -0,0 +1,73 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+package seed
+
+import (
+	"log"
+
+	"github.com/crolly/mug/cmd/models"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// SeedCmd represents the seed command
+	SeedCmd = &cobra.Command{
+		Use:   "seed",
+		Short: "Seed the local DynamoDB tables with fixtures",
+		Long: `Writes the fixtures in functions/<resource>/fixtures into the local DynamoDB tables of the resources.
+Fixtures are JSON or YAML files containing a list of items, as written by 'mug dump'.
+With --generate the given number of items is generated from the model of each resource instead,
+the generated data is the same on every run.
+
+The tables of the mode (debug or test) are created if they don't exist yet, use --force to recreate
+them empty before seeding.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if mode != "debug" && mode != "test" {
+				log.Fatalf("Unknown mode %s, choose between 'debug' and 'test'", mode)
+			}
+
+			// get the config
+			mc := models.ReadMUGConfig()
+
+			list := models.GetList(mc.ProjectPath, seedList)
+
+			// create lambda-local network if it doesn't exist already
+			mc.CreateLambdaNetwork()
+			// start dynamodb-local
+			mc.StartLocalDynamoDB()
+			// create tables for resources
+			mc.CreateResourceTables(list, mode, force)
+
+			mc.Seed(list, mode, generate)
+		},
+	}
+
+	seedList, mode string
+	generate       int
+	force          bool
+)
+
+func init() {
+	SeedCmd.Flags().StringVarP(&seedList, "list", "l", "all", "comma separated list of resources to seed")
+	SeedCmd.Flags().StringVarP(&mode, "mode", "m", "debug", "Choose between the 'debug' and 'test' tables")
+	SeedCmd.Flags().IntVarP(&generate, "generate", "g", 0, "number of items to generate from the model instead of reading the fixtures")
+	SeedCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "recreate the tables empty before seeding")
+}
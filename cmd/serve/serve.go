@@ -0,0 +1,77 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package serve
+
+import (
+	"log"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// ServeCmd represents the serve command
+	ServeCmd = &cobra.Command{
+		Use:         "serve",
+		Short:       "Start a local API without Docker and aws-sam-cli",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Builds the functions for the local machine and starts an API emulating API Gateway. The http
+events of the functions are routed to the binaries including path parameters like {id} or {proxy+}.
+Functions of the provided runtimes are invoked through an emulated Lambda runtime API, go1.x
+functions through their RPC interface.
+
+Authorizers are mocked: the identity source header (Authorization by default) is required and the
+claims of a JWT are passed to the function without verifying its signature. Disable them with
+--authorizer off.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if authorizer != models.AuthorizerMock && authorizer != models.AuthorizerOff {
+				log.Fatalf("Unknown authorizer mode %s, choose between %s and %s", authorizer, models.AuthorizerMock, models.AuthorizerOff)
+			}
+
+			mc := models.ReadMUGConfig()
+			list := models.GetList(mc.ProjectPath, serveList)
+
+			// build binaries for the local machine
+			mc.BuildNative(list)
+			if models.DryRun {
+				return
+			}
+
+			api := mc.NewLocalAPI(list, stage, "debug", authorizer)
+			// release the project lock while serving
+			models.Commit()
+			models.Unlock()
+			if err := api.ListenAndServe(host + ":" + port); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	serveList, stage, host, port, authorizer string
+)
+
+func init() {
+	ServeCmd.Flags().StringVarP(&serveList, "list", "l", "all", "comma separated list of resources/ function groups to serve")
+	ServeCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the serverless variables and secrets are resolved for")
+	ServeCmd.Flags().StringVar(&host, "host", "127.0.0.1", "host the API listens on")
+	ServeCmd.Flags().StringVarP(&port, "port", "p", "3000", "port the API listens on")
+	ServeCmd.Flags().StringVar(&authorizer, "authorizer", models.AuthorizerMock, "mode of the authorizers (mock or off)")
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package templates
+
+import (
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ejectCmd = &cobra.Command{
+		Use:         "eject [resource|function|e2e]...",
+		Short:       "Copies the built-in templates to .mug/templates to customize them",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
+		Long: `Copies the built-in templates to .mug/templates in your project.
+The templates in .mug/templates take precedence over the built-in ones, so you can change
+e.g. the CORS headers or the logging of the generated functions without forking mug.
+Delete a template to use the built-in one again. Run 'mug templates data' to see the data available.`,
+		Args:      cobra.OnlyValidArgs,
+		ValidArgs: []string{"resource", "function", "e2e"},
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+			models.EjectTemplates(mc.ProjectPath, args, force)
+		},
+	}
+
+	force bool
+)
+
+func init() {
+	TemplatesCmd.AddCommand(ejectCmd)
+
+	ejectCmd.Flags().BoolVarP(&force, "force", "f", false, "Overwrite templates ejected before")
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package test
+
+import (
+	"log"
+	"strings"
+
+	"github.com/crolly/mug/cmd/models"
+)
+
+// runE2E generates the end-to-end tests of the resources and runs them against the local API using the test tables
+func runE2E(mc models.MUGConfig, list, env []string) {
+	tested := mc.WriteE2ETests(list)
+	if len(tested) == 0 {
+		log.Println("No resources with routes to test end-to-end, function groups don't have a model")
+		return
+	}
+	log.Printf("Generated end-to-end tests of %s", strings.Join(tested, ", "))
+
+	if api == models.E2ESAM {
+		// the functions run in the docker network of the local DynamoDB
+		mc.WriteLocalTemplate(list, stage, "test")
+	}
+	// go test and sam read the generated tests and the template.yml from disk
+	models.Commit()
+
+	var url string
+	stop := func() {}
+	switch {
+	case api == models.E2ESAM:
+		mc.BuildDebug(list)
+		port := models.FreePort()
+		cmd := models.StartCmd("sam", "local", "start-api", "-p", port, "--docker-network", mc.DynamoDB().Network)
+		url, stop = "http://127.0.0.1:"+port, func() { models.StopCmd(cmd) }
+		if !models.DryRun {
+			if err := models.WaitForAPI("127.0.0.1:" + port); err != nil {
+				stop()
+				log.Fatal(err)
+			}
+		}
+	case models.DryRun:
+		mc.BuildNative(list)
+		models.Record("serve the local API of %s in the background", strings.Join(list, ", "))
+		url = "http://127.0.0.1"
+	default:
+		mc.BuildNative(list)
+		// authorizers are off, the generated tests don't send tokens
+		url, stop = mc.NewLocalAPI(list, stage, "test", models.AuthorizerOff).Start()
+	}
+	log.Printf("Running end-to-end tests against %s", url)
+
+	err := mc.RunE2ETests(url, env)
+	stop()
+	if err != nil {
+		log.Fatalf("End-to-end tests failed: %s", err)
+	}
+}
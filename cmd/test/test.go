@@ -21,6 +21,9 @@
 package test
 
 import (
+	"log"
+	"sort"
+
 	"github.com/crolly/mug/cmd/models"
 
 	"github.com/spf13/cobra"
@@ -29,40 +32,63 @@ import (
 var (
 	// TestCmd represents the test command
 	TestCmd = &cobra.Command{
-		Use:   "test",
-		Short: "Run go tests for your project",
+		Use:         "test",
+		Short:       "Run go tests for your project",
+		Annotations: map[string]string{models.LockAnnotation: "true"},
 		Run: func(cmd *cobra.Command, args []string) {
+			if e2e && api != models.E2EServe && api != models.E2ESAM {
+				log.Fatalf("Unknown local API %s, choose between %s and %s", api, models.E2EServe, models.E2ESAM)
+			}
+
 			// get the config
 			mc := models.ReadMUGConfig()
 
 			list := models.GetList(mc.ProjectPath, list)
 
 			// create lambda-local network if it doesn't exist already
-			models.CreateLambdaNetwork()
+			mc.CreateLambdaNetwork()
 			// start dynamodb-local
-			models.StartLocalDynamoDB()
-			// create tables for resources
-			mc.CreateResourceTables(list, "test", force)
+			mc.StartLocalDynamoDB()
+			if e2e {
+				// the functions of the local API use the shared tables, the go tests create their own
+				mc.CreateResourceTables(list, "test", force)
+			}
 
-			env := []string{"MODE=test"}
-			t := "go test -cover -p 1"
-			if profile {
-				models.RunCmdWithEnv(env, "/bin/sh", "-c", t+" -coverprofile=cover.out ./functions/...")
-				models.RunCmdWithEnv(env, "/bin/sh", "-c", "go tool cover -html=cover.out")
-			} else {
-				for _, r := range list {
-					models.RunCmdWithEnv(env, "/bin/sh", "-c", t+" ./functions/"+r+"/...")
+			// the generated TestMain replaces the table names with the tables of the packages
+			env := append([]string{"MODE=test", "DYNAMODB_ENDPOINT=" + mc.DynamoDB().Endpoint()}, mc.LocalTableEnvironment(list, "test")...)
+			if e2e {
+				runE2E(mc, list, env)
+				return
+			}
+
+			// each resource/ function group only sees its own resolved secrets
+			envs := map[string][]string{}
+			for _, r := range list {
+				secrets := []string{}
+				for k, v := range mc.ResolveSecrets(mc.ReadServerlessConfig(r), r, stage) {
+					secrets = append(secrets, k+"="+v)
 				}
+				sort.Strings(secrets)
+				envs[r] = append(append([]string{}, env...), secrets...)
 			}
+
+			// the packages run in parallel, each against its own table created by the generated TestMain
+			mc.RunTests(list, envs, report, minCoverage, profile)
 		},
 	}
 
-	list           string
-	force, profile bool
+	list, stage, report, api string
+	force, profile, e2e      bool
+	minCoverage              float64
 )
 
 func init() {
 	TestCmd.Flags().StringVarP(&list, "list", "l", "all", "comma separated list of resources/ function groups to debug")
-	TestCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "force overwrite existing tables (might be necessary if you changed you table definition - e.g. new index)")
-	TestCmd.Flags().BoolVarP(&profile, "profile coverage", "p", false, "show the code coverage profile (not compatibale with list flag)")
+	TestCmd.Flags().BoolVarP(&force, "force overwrite", "f", false, "recreate the existing tables of the end-to-end tests empty instead of migrating them to the changed table definition")
+	TestCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the secrets are resolved for from the local secret store")
+	TestCmd.Flags().BoolVarP(&profile, "profile coverage", "p", false, "show the merged code coverage profile in the browser")
+	TestCmd.Flags().StringVarP(&report, "report", "r", "", "write a test report per resource/ function group to .mug/reports (junit or json)")
+	TestCmd.Flags().Float64Var(&minCoverage, "min-coverage", 0, "fail if the total coverage in percent is below the threshold")
+	TestCmd.Flags().BoolVar(&e2e, "e2e", false, "run generated end-to-end tests of the resources' routes against the local API instead of the go tests")
+	TestCmd.Flags().StringVar(&api, "api", models.E2EServe, "local API of the end-to-end tests (serve or sam)")
 }
@@ -0,0 +1,84 @@
+// Copyright © 2019 Christian Rolly <mail@chromium-solutions.de>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package validate
+
+import (
+	"log"
+
+	"github.com/crolly/mug/cmd/models"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// ValidateCmd represents the validate command
+	ValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validates the serverless variables of your resources and function groups",
+		Long: `This command resolves every serverless variable (self, opt, env, file and ssm references including defaults and nesting)
+of the serverless.yml files for the given stage and reports the ones that cannot be resolved.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			mc := models.ReadMUGConfig()
+
+			list := models.GetList(mc.ProjectPath, validateList)
+
+			var lookup func(path string) (string, bool)
+			if remote {
+				lookup = mc.GetSSMSecret(profile)
+			}
+
+			invalid := 0
+			for _, r := range list {
+				sc := mc.ReadServerlessConfig(r)
+				res, err := mc.NewVariableResolver(sc, r, map[string]string{"stage": stage})
+				if err != nil {
+					log.Fatal(err)
+				}
+				if lookup != nil {
+					res.Secrets = lookup
+				}
+
+				errs := res.Validate()
+				for _, err := range errs {
+					log.Printf("%s: %s", r, err)
+				}
+				if len(errs) > 0 {
+					invalid++
+				} else {
+					log.Printf("%s: valid", r)
+				}
+			}
+
+			if invalid > 0 {
+				log.Fatalf("%d of %d resources/ function groups are invalid for stage %s", invalid, len(list), stage)
+			}
+		},
+	}
+
+	validateList, stage, profile string
+	remote                       bool
+)
+
+func init() {
+	ValidateCmd.Flags().StringVarP(&validateList, "list", "l", "all", "comma separated list of resources/ function groups to validate")
+	ValidateCmd.Flags().StringVarP(&stage, "stage", "s", "dev", "stage the serverless variables are resolved for")
+	ValidateCmd.Flags().BoolVarP(&remote, "ssm", "r", false, "resolve ssm references against the AWS SSM Parameter Store instead of the local secret store")
+	ValidateCmd.Flags().StringVarP(&profile, "profile", "p", "", "AWS profile used to resolve ssm references")
+}
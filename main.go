@@ -25,5 +25,5 @@ import (
 )
 
 func main() {
-	cmd.RootCmd.Execute()
+	cmd.Execute()
 }